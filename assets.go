@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"braces.dev/errtrace"
+)
+
+// _extraStaticDir is the directory inside html.StaticDir
+// that holds the files added with -static-dir, -extra-css, and -extra-js.
+const _extraStaticDir = "extra"
+
+// extraAssets are user-provided files added to a website.
+type extraAssets struct {
+	// Files maps /-separated paths relative to html.StaticDir
+	// to their contents.
+	Files map[string][]byte
+
+	// CSS and JS are /-separated paths relative to html.StaticDir
+	// of stylesheets and scripts to include in every page, in order.
+	CSS, JS []string
+}
+
+// loadExtraAssets reads the files for -static-dir, -extra-css, and -extra-js.
+//
+// The contents of staticDir are placed inside _extraStaticDir
+// with the same layout,
+// and stylesheets and scripts are placed there by their base name.
+// Stylesheets and scripts inside staticDir are referenced in place.
+func loadExtraAssets(staticDir string, css, js []filePath) (*extraAssets, error) {
+	assets := extraAssets{
+		Files: make(map[string][]byte),
+	}
+
+	if staticDir != "" {
+		err := fs.WalkDir(os.DirFS(staticDir), ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return errtrace.Wrap(err)
+			}
+
+			bs, err := os.ReadFile(filepath.Join(staticDir, filepath.FromSlash(name)))
+			if err != nil {
+				return errtrace.Wrap(err)
+			}
+			assets.Files[path.Join(_extraStaticDir, name)] = bs
+			return nil
+		})
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("-static-dir: %w", err))
+		}
+	}
+
+	// add adds a stylesheet or script to the assets,
+	// and returns its path relative to html.StaticDir.
+	add := func(file string) (string, error) {
+		if staticDir != "" {
+			rel, err := filepath.Rel(staticDir, file)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				name := path.Join(_extraStaticDir, filepath.ToSlash(rel))
+				if _, ok := assets.Files[name]; ok {
+					return name, nil
+				}
+			}
+		}
+
+		bs, err := os.ReadFile(file)
+		if err != nil {
+			return "", errtrace.Wrap(err)
+		}
+
+		name := path.Join(_extraStaticDir, filepath.Base(file))
+		if _, ok := assets.Files[name]; ok {
+			return "", errtrace.Wrap(fmt.Errorf("%v: another file is already named %v", file, path.Base(name)))
+		}
+		assets.Files[name] = bs
+		return name, nil
+	}
+
+	for _, f := range css {
+		name, err := add(string(f))
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("-extra-css: %w", err))
+		}
+		assets.CSS = append(assets.CSS, name)
+	}
+	for _, f := range js {
+		name, err := add(string(f))
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("-extra-js: %w", err))
+		}
+		assets.JS = append(assets.JS, name)
+	}
+
+	return &assets, nil
+}
@@ -0,0 +1,380 @@
+// Package docgen generates API reference websites for Go packages.
+//
+// It provides the functionality of the doc2go command as a library.
+// For example, the following is roughly equivalent to
+// running 'doc2go ./...':
+//
+//	res, err := docgen.Generate(ctx, &docgen.Config{
+//		OutDir: "_site",
+//	}, "./...")
+package docgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/doc/comment"
+	"io"
+	"io/fs"
+	"log"
+	"strings"
+	"text/template"
+
+	"braces.dev/errtrace"
+	chroma "github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/gomod"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/highlight"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/pathx"
+	"go.abhg.dev/doc2go/internal/sitegen"
+	"golang.org/x/tools/go/packages"
+)
+
+// Config specifies how documentation should be generated.
+type Config struct {
+	// PackagesConfig configures how packages are loaded.
+	// Its Mode and Tests fields are ignored.
+	//
+	// Defaults to loading packages from the current directory.
+	PackagesConfig *packages.Config
+
+	// Tags are build tags to enable when loading packages.
+	Tags []string
+
+	// OutDir is the directory to write the website to.
+	// It will be created if it doesn't exist.
+	//
+	// Files are written to a temporary directory first,
+	// and moved into OutDir only if generation succeeds.
+	OutDir string
+
+	// Output, if set, receives generated files instead of OutDir.
+	// Use [MemoryOutput] or [ArchiveOutput],
+	// or provide your own implementation.
+	Output Output
+
+	// SubDir is an optional subdirectory inside the output.
+	// If specified, pages are generated inside it,
+	// and the root of the output gets an index of its siblings.
+	//
+	// SubDir must not contain '/'.
+	SubDir string
+
+	// Siblings lists subdirectories written to Output
+	// by earlier calls to Generate.
+	// They're listed in the index of siblings alongside SubDir.
+	//
+	// To link pages to their copies in sibling sites,
+	// Output must be able to read back files written to it,
+	// like [MemoryOutput] does.
+	//
+	// Siblings is ignored unless Output is set:
+	// siblings in OutDir are found on disk.
+	Siblings []string
+
+	// Basename of generated pages.
+	//
+	// Defaults to index.html.
+	Basename string
+
+	// Home is the import path for the home page of the documentation.
+	// Packages that aren't descendants of this path are omitted.
+	Home string
+
+	// PkgVersion is a version string included in generated pages.
+	PkgVersion string
+
+	// Embedded generates partial HTML pages
+	// fit for embedding into another website.
+	Embedded bool
+
+	// Internal includes internal packages in package listings.
+	Internal bool
+
+	// FrontMatter, if set, is executed to generate front matter
+	// at the top of each page.
+	// See 'doc2go -help=frontmatter' for the data it receives.
+	FrontMatter *template.Template
+
+	// Linker, if set, generates links to the documentation of packages.
+	//
+	// By default, links to packages being documented are relative,
+	// links matching PackageDocs use those templates,
+	// and other links point to pkg.go.dev.
+	Linker Linker
+
+	// PackageDocs maps import paths to templates
+	// that generate links to the documentation
+	// of those packages and their descendants.
+	// See 'doc2go -help=pkg-doc' for the data they receive.
+	//
+	// PackageDocs is ignored if Linker is set.
+	PackageDocs map[string]*template.Template
+
+	// Highlighter specifies how code is highlighted.
+	//
+	// Defaults to the "github" style with classes,
+	// or inline styles if Embedded is set.
+	Highlighter *Highlighter
+
+	// Templates, if set, holds replacements
+	// for the built-in HTML templates.
+	// Templates missing from it use the built-in versions.
+	// It works like the -template-dir flag of the doc2go command.
+	Templates fs.FS
+
+	// Jobs is the maximum number of packages
+	// that are processed concurrently.
+	//
+	// Defaults to GOMAXPROCS.
+	Jobs int
+
+	// KeepGoing continues generating documentation for other packages
+	// if a package fails.
+	// Failed packages are left out of package listings.
+	//
+	// Generate still returns an error if any packages failed,
+	// but the rest of the documentation is written.
+	KeepGoing bool
+
+	// Log receives warnings about packages that couldn't be loaded.
+	//
+	// Defaults to discarding them.
+	Log *log.Logger
+
+	// DebugLog receives debug messages.
+	//
+	// Defaults to discarding them.
+	DebugLog *log.Logger
+}
+
+// Linker generates links to the documentation of packages.
+type Linker interface {
+	// DocLinkURL returns the URL for a link
+	// found in the documentation of the package at fromPkg.
+	DocLinkURL(fromPkg string, link *comment.DocLink) string
+}
+
+var _ Linker = (*sitegen.DocLinker)(nil)
+
+// Highlighter specifies how code is highlighted.
+type Highlighter struct {
+	// Style used to highlight code.
+	//
+	// Defaults to the "github" style.
+	Style *chroma.Style
+
+	// UseClasses highlights code with CSS classes
+	// instead of inline styles.
+	// The style sheet is written alongside the generated pages.
+	UseClasses bool
+
+	// DarkStyle, if set, is used instead of Style
+	// for readers that prefer a dark color scheme.
+	// This requires UseClasses.
+	DarkStyle *chroma.Style
+
+	// Options configure the HTML formatter used to render code,
+	// e.g. to change the tab width or the prefix of CSS classes.
+	// UseClasses takes precedence over these.
+	Options []chromahtml.Option
+}
+
+// Result describes the outcome of [Generate].
+type Result struct {
+	// Files written, sorted.
+	// These are /-separated paths relative to OutDir,
+	// or to the root of Output if that was used.
+	Files []string
+
+	// Packages that documentation was generated for,
+	// sorted by import path.
+	Packages []PackageResult
+
+	// Pages for packages and directories in the website,
+	// sorted by import path.
+	Pages []PageResult
+}
+
+// PackageResult is the outcome of documenting a single package.
+type PackageResult struct {
+	ImportPath string
+
+	// Err is non-nil if documentation for the package
+	// could not be generated.
+	Err error
+}
+
+// PageKind specifies what a page documents.
+type PageKind string
+
+// Kinds of pages generated for packages and directories.
+const (
+	PackagePage   = PageKind(sitegen.PackagePage)
+	CommandPage   = PageKind(sitegen.CommandPage)   // package main
+	DirectoryPage = PageKind(sitegen.DirectoryPage) // no package
+)
+
+// PageResult describes a page generated for a package or directory.
+type PageResult struct {
+	// ImportPath of the package or directory.
+	ImportPath string
+
+	// File that the page was written to,
+	// in the same format as [Result.Files].
+	File string
+
+	Kind PageKind
+
+	// Synopsis of the package.
+	// Empty for directories.
+	Synopsis string
+}
+
+// Generate generates documentation
+// for packages matching the given import path patterns.
+// Use ./... to match the package in the current directory
+// and all its descendants.
+//
+// If documentation for some packages could not be generated,
+// Generate returns an error alongside a Result
+// that reports the error for each package.
+// Unless KeepGoing is set, Generate stops at the first such package.
+func Generate(ctx context.Context, cfg *Config, patterns ...string) (*Result, error) {
+	if cfg.OutDir == "" && cfg.Output == nil {
+		return nil, errtrace.Wrap(errors.New("either OutDir or Output must be set"))
+	}
+
+	highlighter := highlight.Highlighter{
+		Style:      styles.Get("github"),
+		UseClasses: !cfg.Embedded,
+	}
+	if h := cfg.Highlighter; h != nil {
+		if h.Style != nil {
+			highlighter.Style = h.Style
+		}
+		if h.DarkStyle != nil && !h.UseClasses {
+			return nil, errtrace.Wrap(errors.New("a dark highlighting style requires UseClasses"))
+		}
+		highlighter.UseClasses = h.UseClasses
+		highlighter.DarkStyle = h.DarkStyle
+		highlighter.Options = h.Options
+	}
+
+	var templates *html.Templates
+	if cfg.Templates != nil {
+		var err error
+		templates, err = html.ParseTemplates(cfg.Templates)
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("bad templates: %w", err))
+		}
+	}
+
+	logger := cfg.Log
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+
+	var packagesConfig packages.Config
+	if cfg.PackagesConfig != nil {
+		packagesConfig = *cfg.PackagesConfig
+	}
+	packagesConfig.Context = ctx
+
+	finder := gosrc.Finder{
+		PackagesConfig: &packagesConfig,
+		Tags:           cfg.Tags,
+		Log:            logger,
+		DebugLog:       cfg.DebugLog,
+	}
+	pkgRefs, err := finder.FindPackages(patterns...)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	if home := cfg.Home; home != "" {
+		refs := pkgRefs[:0]
+		for _, r := range pkgRefs {
+			if pathx.Descends(home, r.ImportPath) {
+				refs = append(refs, r)
+			}
+		}
+		pkgRefs = refs
+	}
+
+	linker := cfg.Linker
+	if linker == nil {
+		docLinker := new(sitegen.DocLinker)
+		if moduleTree := (&gomod.Builder{Logger: logger}).Build(pkgRefs); moduleTree != nil {
+			docLinker.ModuleTree = moduleTree
+		}
+		for path, tmpl := range cfg.PackageDocs {
+			docLinker.Template(path, tmpl)
+		}
+		for _, ref := range pkgRefs {
+			docLinker.LocalPackage(ref.ImportPath)
+		}
+		linker = docLinker
+	}
+
+	g := sitegen.Generator{
+		Log:      logger,
+		DebugLog: cfg.DebugLog,
+		Parser:   &gosrc.Parser{Logger: logger},
+		Assembler: &godoc.Assembler{
+			Linker: linker,
+			Lexer:  highlight.GoLexer,
+			Logger: logger,
+		},
+		Renderer: &html.Renderer{
+			Home:        cfg.Home,
+			Embedded:    cfg.Embedded,
+			Internal:    cfg.Internal,
+			FrontMatter: cfg.FrontMatter,
+			Highlighter: &highlighter,
+			DarkMode:    highlighter.DarkStyle != nil,
+			Templates:   templates,
+		},
+		DocLinker:  linker,
+		OutDir:     cfg.OutDir,
+		Output:     cfg.Output,
+		SubDir:     strings.Trim(cfg.SubDir, "/"),
+		Siblings:   cfg.Siblings,
+		PkgVersion: cfg.PkgVersion,
+		Basename:   cfg.Basename,
+		Home:       cfg.Home,
+		Jobs:       cfg.Jobs,
+		KeepGoing:  cfg.KeepGoing,
+	}
+
+	res, err := g.Generate(ctx, pkgRefs)
+	return newResult(res), errtrace.Wrap(err)
+}
+
+func newResult(res *sitegen.Result) *Result {
+	pkgs := make([]PackageResult, len(res.Packages))
+	for i, pkg := range res.Packages {
+		pkgs[i] = PackageResult{
+			ImportPath: pkg.ImportPath,
+			Err:        pkg.Err,
+		}
+	}
+	pages := make([]PageResult, len(res.Pages))
+	for i, page := range res.Pages {
+		pages[i] = PageResult{
+			ImportPath: page.ImportPath,
+			File:       page.File,
+			Kind:       PageKind(page.Kind),
+			Synopsis:   page.Synopsis,
+		}
+	}
+	return &Result{
+		Files:    res.Files,
+		Packages: pkgs,
+		Pages:    pages,
+	}
+}
@@ -0,0 +1,241 @@
+package docgen
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"go/doc/comment"
+	"log"
+	"testing"
+	"testing/fstest"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/iotest"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "example.com/foo",
+			Files: map[string]any{
+				"foo.go": "// Package foo does things.\n" +
+					"// See [bytes.Buffer].\n" +
+					"package foo\n",
+				"bar/bar.go": "// Package bar does other things.\npackage bar\n",
+			},
+		},
+	})
+
+	var out MemoryOutput
+	res, err := Generate(context.Background(), &Config{
+		PackagesConfig: exported.Config,
+		Output:         &out,
+		Linker:         stubLinker{},
+		DebugLog:       log.New(iotest.Writer(t), "", 0),
+	}, "./...")
+	require.NoError(t, err)
+
+	assert.Equal(t, []PackageResult{
+		{ImportPath: "example.com/foo"},
+		{ImportPath: "example.com/foo/bar"},
+	}, res.Packages)
+
+	assert.Contains(t, res.Files, "index.html")
+	assert.Contains(t, res.Files, "example.com/foo/index.html")
+	assert.Contains(t, res.Files, "example.com/foo/bar/index.html")
+
+	assert.Contains(t, res.Pages, PageResult{
+		ImportPath: "example.com/foo",
+		File:       "example.com/foo/index.html",
+		Kind:       PackagePage,
+		Synopsis:   "Package foo does things.",
+	})
+	assert.Contains(t, res.Pages, PageResult{
+		ImportPath: "example.com/foo/bar",
+		File:       "example.com/foo/bar/index.html",
+		Kind:       PackagePage,
+		Synopsis:   "Package bar does other things.",
+	})
+
+	files := out.Files()
+	assert.Len(t, files, len(res.Files))
+	assert.Contains(t, string(files["example.com/foo/index.html"]), "https://example.com/bytes#Buffer")
+}
+
+func TestGenerate_siblings(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "example.com/foo",
+			Files: map[string]any{
+				"foo.go": "// Package foo does things.\npackage foo\n",
+			},
+		},
+	})
+
+	var out MemoryOutput
+	generate := func(subDir string, siblings ...string) *Result {
+		res, err := Generate(context.Background(), &Config{
+			PackagesConfig: exported.Config,
+			Output:         &out,
+			SubDir:         subDir,
+			Siblings:       siblings,
+			Linker:         stubLinker{},
+			DebugLog:       log.New(iotest.Writer(t), "", 0),
+		}, "./...")
+		require.NoError(t, err)
+		return res
+	}
+
+	generate("v2.0.0")
+	res := generate("v1.0.0", "v2.0.0")
+
+	// The latest alias redirects to every page of v2.0.0,
+	// which is only possible if its list of pages was read back.
+	assert.Contains(t, res.Files, "latest/example.com/foo/index.html")
+}
+
+func TestGenerate_archive(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "example.com/foo",
+			Files: map[string]any{
+				"foo.go": "// Package foo does things.\npackage foo\n",
+			},
+		},
+	})
+
+	var buf bytes.Buffer
+	out := NewArchiveOutput(&buf, Zip)
+	res, err := Generate(context.Background(), &Config{
+		PackagesConfig: exported.Config,
+		Output:         out,
+		Embedded:       true,
+		Highlighter:    &Highlighter{UseClasses: false},
+	}, "./...")
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	var names []string
+	for _, f := range zr.File {
+		if !f.FileInfo().IsDir() {
+			names = append(names, f.Name)
+		}
+	}
+	assert.ElementsMatch(t, res.Files, names)
+}
+
+func TestGenerate_templates(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "example.com/foo",
+			Files: map[string]any{
+				"foo.go": "// Package foo does things.\npackage foo\n\nfunc Foo() {}\n",
+			},
+		},
+	})
+
+	layout := `{{ define "Page" -}}
+<header>ACME Corp</header>
+{{ template "Body" $ }}
+{{- end }}
+{{ define "Embedded" }}{{ template "Body" $ }}{{ end }}`
+
+	var out MemoryOutput
+	_, err := Generate(context.Background(), &Config{
+		PackagesConfig: exported.Config,
+		Output:         &out,
+		Templates: fstest.MapFS{
+			"layout.html": {Data: []byte(layout)},
+		},
+	}, "./...")
+	require.NoError(t, err)
+
+	got := string(out.Files()["example.com/foo/index.html"])
+	assert.Contains(t, got, "<header>ACME Corp</header>")
+	assert.Contains(t, got, `id="Foo"`, "built-in package template must be used")
+	assert.NotContains(t, got, "<!DOCTYPE html>")
+}
+
+func TestGenerate_badTemplates(t *testing.T) {
+	t.Parallel()
+
+	_, err := Generate(context.Background(), &Config{
+		Output: new(MemoryOutput),
+		Templates: fstest.MapFS{
+			"layout.html": {Data: []byte(`{{ define "Page" }}{{ if }}{{ end }}`)},
+		},
+	}, "./...")
+	assert.ErrorContains(t, err, "bad templates")
+}
+
+func TestGenerate_highlighter(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "example.com/foo",
+			Files: map[string]any{
+				"foo.go": "// Package foo does things.\npackage foo\n\nfunc Foo() {}\n",
+			},
+		},
+	})
+
+	var out MemoryOutput
+	_, err := Generate(context.Background(), &Config{
+		PackagesConfig: exported.Config,
+		Output:         &out,
+		Highlighter: &Highlighter{
+			Style:      styles.Get("github"),
+			DarkStyle:  styles.Get("github-dark"),
+			UseClasses: true,
+			Options:    []chromahtml.Option{chromahtml.ClassPrefix("acme-")},
+		},
+	}, "./...")
+	require.NoError(t, err)
+
+	files := out.Files()
+	css := string(files["_/css/highlight.css"])
+	assert.Contains(t, css, "@media (prefers-color-scheme: dark)")
+	assert.Contains(t, css, ".acme-")
+	assert.Contains(t, string(files["example.com/foo/index.html"]), `class="acme-`)
+}
+
+func TestGenerate_darkStyleNeedsClasses(t *testing.T) {
+	t.Parallel()
+
+	_, err := Generate(context.Background(), &Config{
+		Output: new(MemoryOutput),
+		Highlighter: &Highlighter{
+			DarkStyle: styles.Get("github-dark"),
+		},
+	}, "./...")
+	assert.ErrorContains(t, err, "requires UseClasses")
+}
+
+func TestGenerate_noOutput(t *testing.T) {
+	t.Parallel()
+
+	_, err := Generate(context.Background(), &Config{}, "./...")
+	assert.ErrorContains(t, err, "either OutDir or Output must be set")
+}
+
+type stubLinker struct{}
+
+func (stubLinker) DocLinkURL(_ string, link *comment.DocLink) string {
+	return "https://example.com/" + link.ImportPath + "#" + link.Name
+}
@@ -0,0 +1,95 @@
+package docgen
+
+import (
+	"io"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/output"
+)
+
+// Output receives files generated by [Generate].
+type Output interface {
+	// Create creates a file at the given /-separated path
+	// and returns a writer for its contents.
+	// The file is complete when the writer is closed.
+	//
+	// Create may be called concurrently.
+	Create(name string) (io.WriteCloser, error)
+}
+
+var (
+	_ Output = (*MemoryOutput)(nil)
+	_ Output = (*ArchiveOutput)(nil)
+
+	// Reading back pages of sibling sites requires ReadFile.
+	_ output.ReadFS = (*MemoryOutput)(nil)
+)
+
+// MemoryOutput holds generated files in memory.
+//
+// The zero value is ready to use.
+type MemoryOutput struct {
+	mem output.Memory
+}
+
+// Create creates a file in memory.
+func (m *MemoryOutput) Create(name string) (io.WriteCloser, error) {
+	return errtrace.Wrap2(m.mem.Create(name))
+}
+
+// Files returns the contents of files written so far,
+// keyed by their /-separated paths.
+func (m *MemoryOutput) Files() map[string][]byte {
+	return m.mem.Files()
+}
+
+// ReadFile returns the contents of a file written so far.
+// It returns an error matching [io/fs.ErrNotExist]
+// if the file hasn't been written.
+func (m *MemoryOutput) ReadFile(name string) ([]byte, error) {
+	return errtrace.Wrap2(m.mem.ReadFile(name))
+}
+
+// ArchiveFormat specifies the format of an [ArchiveOutput].
+type ArchiveFormat int
+
+const (
+	// TarGz is a gzip-compressed tar archive.
+	TarGz = ArchiveFormat(output.TarGz)
+
+	// Zip is a zip archive.
+	Zip = ArchiveFormat(output.Zip)
+)
+
+// String returns the conventional file extension for the format,
+// without a leading '.'.
+func (f ArchiveFormat) String() string {
+	return output.ArchiveFormat(f).String()
+}
+
+// ArchiveOutput writes generated files into an archive.
+//
+// Files are buffered in memory,
+// and the archive is written when the output is closed.
+type ArchiveOutput struct {
+	archive *output.Archive
+}
+
+// NewArchiveOutput builds an ArchiveOutput
+// that writes an archive in the given format to w.
+func NewArchiveOutput(w io.Writer, format ArchiveFormat) *ArchiveOutput {
+	return &ArchiveOutput{
+		archive: output.NewArchive(w, output.ArchiveFormat(format)),
+	}
+}
+
+// Create creates a file in the archive.
+func (a *ArchiveOutput) Create(name string) (io.WriteCloser, error) {
+	return errtrace.Wrap2(a.archive.Create(name))
+}
+
+// Close writes the archive.
+// It does not close the underlying writer.
+func (a *ArchiveOutput) Close() error {
+	return errtrace.Wrap(a.archive.Close())
+}
@@ -5,7 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"braces.dev/errtrace"
@@ -13,6 +16,8 @@ import (
 	"github.com/alecthomas/chroma/v2/styles"
 	ff "github.com/peterbourgon/ff/v3"
 	"go.abhg.dev/doc2go/internal/flagvalue"
+	"go.abhg.dev/doc2go/internal/output"
+	"go.abhg.dev/doc2go/internal/sitegen"
 )
 
 var (
@@ -35,18 +40,43 @@ type params struct {
 	Debug  flagvalue.FileSwitch
 	Config string
 	Dir    string
+	Jobs   int
 
+	Incremental bool
+	Watch       bool
+
+	// Serve is set if doc2go was invoked as 'doc2go serve'.
+	Serve bool
+	HTTP  string
+
+	Format     outputFormat
 	Basename   string
 	OutputDir  string
 	SubDir     string
 	PkgVersion string
+	Refs       string
 	Home       string
+	Clean      cleanMode
+	KeepGoing  bool
+	Report     string
+	Man        string
+	SinglePage string
 	Pagefind   pagefindFlag
 
+	SymbolSearch bool
+
+	BaseURL       baseURL
+	SitemapLatest bool
+
 	Embed            bool
 	Internal         bool
 	PkgDocs          []pathTemplate
+	GoImports        []goImport
 	FrontMatter      string
+	TemplateDir      string
+	StaticDir        string
+	ExtraCSS         []filePath
+	ExtraJS          []filePath
 	RelLinkStyle     relLinkStyle
 	NoModuleVersions bool
 
@@ -83,16 +113,31 @@ func (cmd *cliParser) newFlagSet(cfg *configFileParser) (*params, *flag.FlagSet)
 	flag.StringVar(&p.OutputDir, "out", "_site", "")
 	flag.StringVar(&p.SubDir, "subdir", "", "")
 	flag.StringVar(&p.PkgVersion, "pkg-version", "", "")
+	flag.StringVar(&p.Refs, "refs", "", "")
 	flag.StringVar(&p.Basename, "basename", "", "")
+	flag.Var(&p.Format, "format", "")
 	flag.StringVar(&p.Home, "home", "", "")
+	flag.Var(&p.Clean, "clean", "")
+	flag.BoolVar(&p.KeepGoing, "keep-going", false, "")
+	flag.StringVar(&p.Report, "report", "", "")
+	flag.StringVar(&p.Man, "man", "", "")
+	flag.StringVar(&p.SinglePage, "single-page", "", "")
+	flag.Var(&p.BaseURL, "base-url", "")
+	flag.BoolVar(&p.SitemapLatest, "sitemap-latest", false, "")
 
 	// HTML output:
 	flag.BoolVar(&p.Internal, "internal", false, "")
 	flag.BoolVar(&p.Embed, "embed", false, "")
 	flag.StringVar(&p.FrontMatter, "frontmatter", "", "")
+	flag.StringVar(&p.TemplateDir, "template-dir", "", "")
+	flag.StringVar(&p.StaticDir, "static-dir", "", "")
+	flag.Var(flagvalue.ListOf(&p.ExtraCSS), "extra-css", "")
+	flag.Var(flagvalue.ListOf(&p.ExtraJS), "extra-js", "")
 	flag.Var(flagvalue.ListOf(&p.PkgDocs), "pkg-doc", "")
+	flag.Var(flagvalue.ListOf(&p.GoImports), "go-import", "")
 	flag.Var(&p.RelLinkStyle, "rel-link-style", "")
 	flag.Var(&p.Pagefind, "pagefind", "")
+	flag.BoolVar(&p.SymbolSearch, "symbol-search", false, "")
 	flag.BoolVar(&p.NoModuleVersions, "no-mod-versions", false, "")
 
 	// Highlighting:
@@ -101,9 +146,17 @@ func (cmd *cliParser) newFlagSet(cfg *configFileParser) (*params, *flag.FlagSet)
 	flag.BoolVar(&p.HighlightListThemes, "highlight-list-themes", false, "")
 	cfg.Reject("highlight-print-css", "highlight-list-themes")
 
+	// Server:
+	flag.StringVar(&p.HTTP, "http", "", "")
+
 	// Go build system:
 	flag.StringVar(&p.Tags, "tags", "", "")
 
+	// Performance:
+	flag.IntVar(&p.Jobs, "jobs", 0, "")
+	flag.BoolVar(&p.Incremental, "incremental", false, "")
+	flag.BoolVar(&p.Watch, "watch", false, "")
+
 	// Program-level:
 	flag.Var(&p.Debug, "debug", "")
 	flag.StringVar(&p.Config, "config", "doc2go.rc", "")
@@ -137,6 +190,14 @@ func (cmd *cliParser) Parse(args []string) (*params, error) {
 	flag.Var(&help, "h", "")
 	cfgParser.Reject("version", "print-config-keys", "help", "h")
 
+	// 'doc2go serve ...' runs a documentation server
+	// instead of generating a website.
+	// The remaining arguments are parsed as usual.
+	if len(args) > 0 && args[0] == "serve" {
+		p.Serve = true
+		args = args[1:]
+	}
+
 	err := ff.Parse(flag, args,
 		ff.WithAllowMissingConfigFile(true),
 		ff.WithConfigFileVia(&p.Config),
@@ -183,9 +244,131 @@ func (cmd *cliParser) Parse(args []string) (*params, error) {
 		return nil, errtrace.Wrap(errHelp)
 	}
 
-	// pagefind cannot be used in embedded mode.
-	if p.Embed && p.Pagefind.Mode == pagefindEnabled {
-		fmt.Fprintln(cmd.Stderr, "pagefind cannot be used in embedded mode")
+	var (
+		_, archive = output.ArchiveFormatOf(p.OutputDir)
+
+		pagefind    = p.Pagefind.Mode == pagefindEnabled
+		clean       = p.Clean != cleanDisabled
+		baseURL     = p.BaseURL.URL != nil
+		goImport    = len(p.GoImports) > 0
+		staticDir   = p.StaticDir != ""
+		extraCSS    = len(p.ExtraCSS) > 0
+		extraJS     = len(p.ExtraJS) > 0
+		subdir      = p.SubDir != ""
+		refs        = p.Refs != ""
+		report      = p.Report != ""
+		man         = p.Man != ""
+		frontMatter = p.FrontMatter != ""
+		singlePage  = p.SinglePage != ""
+		notHTML     = p.Format != formatHTML
+		manOutput   = p.Format == formatMan
+		jsonOutput  = p.Format == formatJSON
+		withFormat  = "with " + p.Format.String() + " output"
+	)
+
+	// Flags that can't be used together, in the order they're reported.
+	// Each entry reports "<flag> cannot be used <with>".
+	for _, c := range []struct {
+		flag     string
+		with     string
+		conflict bool
+	}{
+		// Embedded pages don't have a <head> to put tags in,
+		// and are styled by the site they're embedded into.
+		{"pagefind", "in embedded mode", p.Embed && pagefind},
+		{"go-import", "in embedded mode", p.Embed && goImport},
+		{"static-dir", "in embedded mode", p.Embed && staticDir},
+		{"extra-css", "in embedded mode", p.Embed && extraCSS},
+		{"extra-js", "in embedded mode", p.Embed && extraJS},
+		{"serve", "in embedded mode", p.Embed && p.Serve},
+
+		// Only one search box fits on a page.
+		{"symbol-search", "with pagefind", p.SymbolSearch && pagefind},
+
+		// serve renders each package on demand when it's requested.
+		// It doesn't write any files, and it picks up changes on its own.
+		{"watch", "with serve", p.Serve && p.Watch},
+		{"clean", "with serve", p.Serve && clean},
+		{"keep-going", "with serve", p.Serve && p.KeepGoing},
+		{"incremental", "with serve", p.Serve && p.Incremental},
+		{"jobs", "with serve", p.Serve && p.Jobs != 0},
+		{"report", "with serve", p.Serve && report},
+		{"man", "with serve", p.Serve && man},
+		{"symbol-search", "with serve", p.Serve && p.SymbolSearch},
+		{"base-url", "with serve", p.Serve && baseURL},
+
+		// Each ref is generated into its own subdirectory
+		// with its own version.
+		{"refs", "with serve", refs && p.Serve},
+		{"refs", "with watch", refs && p.Watch},
+		{"refs", "with subdir", refs && subdir},
+		{"refs", "with pkg-version", refs && p.PkgVersion != ""},
+		{"refs", "with report", refs && report},
+		{"refs", "with man", refs && man},
+
+		// Archives are written from scratch on every run,
+		// and can't be indexed by pagefind.
+		{"pagefind", "with archive output", archive && !p.Serve && pagefind},
+		{"incremental", "with archive output", archive && !p.Serve && p.Incremental},
+		{"watch", "with archive output", archive && !p.Serve && p.Watch},
+		{"clean", "with archive output", archive && !p.Serve && clean},
+
+		// Features that need a browser or an HTTP server
+		// are only available for HTML output.
+		{"serve", withFormat, notHTML && p.Serve},
+		{"embed", withFormat, notHTML && p.Embed},
+		{"pagefind", withFormat, notHTML && pagefind},
+		{"symbol-search", withFormat, notHTML && p.SymbolSearch},
+		{"base-url", withFormat, notHTML && baseURL},
+		{"go-import", withFormat, notHTML && goImport},
+		{"template-dir", withFormat, notHTML && p.TemplateDir != ""},
+		{"static-dir", withFormat, notHTML && staticDir},
+		{"extra-css", withFormat, notHTML && extraCSS},
+		{"extra-js", withFormat, notHTML && extraJS},
+
+		// Front matter would make the files invalid JSON or roff.
+		{"frontmatter", withFormat, (jsonOutput || manOutput) && frontMatter},
+
+		// Man pages are written for commands only,
+		// into a flat directory with no site around them.
+		{"subdir", withFormat, manOutput && subdir},
+		{"refs", withFormat, manOutput && refs},
+		{"report", withFormat, manOutput && report},
+		{"man", withFormat, manOutput && man},
+		{"incremental", withFormat, manOutput && p.Incremental},
+		{"watch", withFormat, manOutput && p.Watch},
+		{"clean", withFormat, manOutput && clean},
+		{"keep-going", withFormat, manOutput && p.KeepGoing},
+
+		// The single page is written instead of a website,
+		// from scratch on every run.
+		{"single-page", "with serve", singlePage && p.Serve},
+		{"single-page", withFormat, singlePage && notHTML},
+		{"single-page", "with embed", singlePage && p.Embed},
+		{"single-page", "with pagefind", singlePage && pagefind},
+		{"single-page", "with symbol-search", singlePage && p.SymbolSearch},
+		{"single-page", "with frontmatter", singlePage && frontMatter},
+		{"single-page", "with subdir", singlePage && subdir},
+		{"single-page", "with refs", singlePage && refs},
+		{"single-page", "with report", singlePage && report},
+		{"single-page", "with incremental", singlePage && p.Incremental},
+		{"single-page", "with watch", singlePage && p.Watch},
+		{"single-page", "with clean", singlePage && clean},
+		{"single-page", "with keep-going", singlePage && p.KeepGoing},
+		{"single-page", "with base-url", singlePage && baseURL},
+		{"single-page", "with go-import", singlePage && goImport},
+		{"single-page", "with static-dir", singlePage && staticDir},
+		{"single-page", "with extra-css", singlePage && extraCSS},
+		{"single-page", "with extra-js", singlePage && extraJS},
+	} {
+		if c.conflict {
+			fmt.Fprintf(cmd.Stderr, "%v cannot be used %v\n", c.flag, c.with)
+			return nil, errtrace.Wrap(errInvalidArguments)
+		}
+	}
+
+	if p.SitemapLatest && !baseURL {
+		fmt.Fprintln(cmd.Stderr, "sitemap-latest requires base-url")
 		return nil, errtrace.Wrap(errInvalidArguments)
 	}
 
@@ -195,6 +378,11 @@ func (cmd *cliParser) Parse(args []string) (*params, error) {
 		return nil, errtrace.Wrap(errInvalidArguments)
 	}
 
+	if p.Jobs < 0 {
+		fmt.Fprintf(cmd.Stderr, "jobs must not be negative: %d\n", p.Jobs)
+		return nil, errtrace.Wrap(errInvalidArguments)
+	}
+
 	p.Patterns = args
 	if len(p.Patterns) == 0 && !p.HighlightPrintCSS && !p.HighlightListThemes {
 		fmt.Fprintln(cmd.Stderr, "Please provide at least one pattern.")
@@ -258,6 +446,10 @@ func (m *highlightMode) Set(s string) error {
 type highlightParams struct {
 	Mode  highlightMode
 	Theme string
+
+	// DarkTheme is used instead of Theme
+	// when the reader prefers a dark color scheme.
+	DarkTheme string
 }
 
 var _ flag.Getter = (*highlightParams)(nil)
@@ -265,6 +457,9 @@ var _ flag.Getter = (*highlightParams)(nil)
 func (p *highlightParams) Get() any { return p }
 
 func (p *highlightParams) String() string {
+	if p.DarkTheme != "" {
+		return fmt.Sprintf("%v:%v,%v", p.Mode, p.Theme, p.DarkTheme)
+	}
 	return fmt.Sprintf("%v:%v", p.Mode, p.Theme)
 }
 
@@ -275,7 +470,12 @@ func (p *highlightParams) Set(s string) error {
 		}
 		s = s[idx+1:]
 	}
-	p.Theme = s
+	p.Theme, p.DarkTheme, _ = strings.Cut(s, ",")
+
+	// Inline styles can't change with the color scheme.
+	if p.DarkTheme != "" && p.Mode == highlightModeInline {
+		return errtrace.Wrap(fmt.Errorf("inline highlighting does not support a dark theme"))
+	}
 	return nil
 }
 
@@ -303,6 +503,81 @@ func (pt *pathTemplate) Set(s string) error {
 	return nil
 }
 
+// filePath is the path to a file
+// for flags that may be specified multiple times.
+type filePath string
+
+var _ flag.Getter = (*filePath)(nil)
+
+func (fp *filePath) Get() any { return string(*fp) }
+
+func (fp *filePath) String() string { return string(*fp) }
+
+func (fp *filePath) Set(s string) error {
+	if s == "" {
+		return errtrace.Wrap(errors.New("path must not be empty"))
+	}
+	*fp = filePath(s)
+	return nil
+}
+
+// goImport maps a module path to the repository hosting it.
+type goImport struct {
+	Module  string
+	VCS     string
+	RepoURL string
+	Subdir  string // optional
+}
+
+var _ flag.Getter = (*goImport)(nil)
+
+// _goImportVCS lists version control systems
+// supported by the go command.
+var _goImportVCS = []string{"bzr", "fossil", "git", "hg", "svn"}
+
+func (gi *goImport) Get() any { return gi }
+
+func (gi *goImport) String() string {
+	s := fmt.Sprintf("%s=%s:%s", gi.Module, gi.VCS, gi.RepoURL)
+	if gi.Subdir != "" {
+		s += "#" + gi.Subdir
+	}
+	return s
+}
+
+func (gi *goImport) Set(s string) error {
+	module, repo, ok := strings.Cut(s, "=")
+	if !ok || module == "" {
+		return errtrace.Wrap(fmt.Errorf("expected form 'module=vcs:url[#subdir]'"))
+	}
+
+	vcs, repoURL, ok := strings.Cut(repo, ":")
+	if !ok {
+		return errtrace.Wrap(fmt.Errorf("expected form 'module=vcs:url[#subdir]'"))
+	}
+	if !slices.Contains(_goImportVCS, vcs) {
+		return errtrace.Wrap(fmt.Errorf("unsupported version control system %q: expected one of %v",
+			vcs, strings.Join(_goImportVCS, ", ")))
+	}
+
+	repoURL, subdir, hasSubdir := strings.Cut(repoURL, "#")
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return errtrace.Wrap(fmt.Errorf("%q is not an absolute URL", repoURL))
+	}
+
+	subdir = strings.TrimSuffix(subdir, "/")
+	if hasSubdir && (!fs.ValidPath(subdir) || subdir == ".") {
+		return errtrace.Wrap(fmt.Errorf("%q is not a subdirectory of the repository", subdir))
+	}
+
+	gi.Module = strings.TrimSuffix(module, "/")
+	gi.VCS = vcs
+	gi.RepoURL = repoURL
+	gi.Subdir = subdir
+	return nil
+}
+
 type configFileParser struct {
 	disallowed map[string]struct{}
 }
@@ -338,6 +613,70 @@ func (f *configFileParser) Parse(r io.Reader, set func(string, string) error) er
 	}))
 }
 
+// outputFormat specifies the format of generated pages.
+type outputFormat int
+
+const (
+	// formatHTML generates a website.
+	formatHTML outputFormat = iota
+
+	// formatMarkdown generates GitHub-flavored Markdown files.
+	formatMarkdown
+
+	// formatJSON generates JSON files
+	// for use with other documentation frontends.
+	formatJSON
+
+	// formatMan generates only man pages for commands.
+	formatMan
+)
+
+func (f outputFormat) String() string {
+	switch f {
+	case formatHTML:
+		return "html"
+	case formatMarkdown:
+		return "markdown"
+	case formatJSON:
+		return "json"
+	case formatMan:
+		return "man"
+	default:
+		return fmt.Sprintf("outputFormat(%d)", int(f))
+	}
+}
+
+// DefaultBasename is the base name of generated files
+// if -basename is not specified.
+func (f outputFormat) DefaultBasename() string {
+	switch f {
+	case formatMarkdown:
+		return "README.md"
+	case formatJSON:
+		return "index.json"
+	default:
+		return "index.html"
+	}
+}
+
+func (f *outputFormat) Get() any { return *f }
+
+func (f *outputFormat) Set(s string) error {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "html":
+		*f = formatHTML
+	case "markdown", "md":
+		*f = formatMarkdown
+	case "json":
+		*f = formatJSON
+	case "man":
+		*f = formatMan
+	default:
+		return errtrace.Wrap(fmt.Errorf("unrecognized output format %q", s))
+	}
+	return nil
+}
+
 // relLinkStyle specifies how we relative links to directories.
 type relLinkStyle int
 
@@ -412,6 +751,81 @@ func (ls relLinkStyle) Normalize(s string, basename string) string {
 	}
 }
 
+// cleanMode specifies whether stale files
+// in the output directory should be deleted.
+//
+// Examples usages:
+//
+//	--clean          // delete stale files
+//	--clean=false    // don't delete stale files
+//	--clean=dry-run  // print stale files without deleting them
+type cleanMode sitegen.CleanMode
+
+const (
+	cleanDisabled = cleanMode(sitegen.CleanDisabled)
+	cleanEnabled  = cleanMode(sitegen.CleanEnabled)
+	cleanDryRun   = cleanMode(sitegen.CleanDryRun)
+)
+
+var _ flag.Getter = (*cleanMode)(nil)
+
+func (*cleanMode) IsBoolFlag() bool { return true }
+
+func (m *cleanMode) Get() any { return *m }
+
+func (m cleanMode) String() string {
+	switch m {
+	case cleanDisabled:
+		return "false"
+	case cleanEnabled:
+		return "true"
+	case cleanDryRun:
+		return "dry-run"
+	default:
+		return fmt.Sprintf("cleanMode(%d)", int(m))
+	}
+}
+
+func (m *cleanMode) Set(v string) error {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true", "t", "yes", "y", "on":
+		*m = cleanEnabled
+	case "false", "f", "no", "n", "off":
+		*m = cleanDisabled
+	case "dry-run", "dryrun":
+		*m = cleanDryRun
+	default:
+		return errtrace.Wrap(fmt.Errorf("unrecognized clean mode %q", v))
+	}
+	return nil
+}
+
+// baseURL is the absolute URL that a website is served from.
+type baseURL struct{ *url.URL }
+
+var _ flag.Getter = (*baseURL)(nil)
+
+func (u *baseURL) Get() any { return u.URL }
+
+func (u *baseURL) String() string {
+	if u.URL == nil {
+		return ""
+	}
+	return u.URL.String()
+}
+
+func (u *baseURL) Set(s string) error {
+	parsed, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errtrace.Wrap(fmt.Errorf("%q is not an absolute http or https URL", s))
+	}
+	u.URL = parsed
+	return nil
+}
+
 // pagefindFlag indicates whether to include client-side search
 // using pagefind.
 //
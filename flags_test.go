@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -151,6 +152,34 @@ func TestCLIParser(t *testing.T) {
 				OutputDir:   "_site",
 			},
 		},
+		{
+			desc: "template dir",
+			give: []string{"-template-dir", "templates", "./..."},
+			want: params{
+				Config:      "doc2go.rc",
+				TemplateDir: "templates",
+				Patterns:    []string{"./..."},
+				OutputDir:   "_site",
+			},
+		},
+		{
+			desc: "extra assets",
+			give: []string{
+				"-static-dir", "assets",
+				"-extra-css", "assets/brand.css",
+				"-extra-css=fonts.css",
+				"-extra-js", "analytics.js",
+				"./...",
+			},
+			want: params{
+				Config:    "doc2go.rc",
+				StaticDir: "assets",
+				ExtraCSS:  []filePath{"assets/brand.css", "fonts.css"},
+				ExtraJS:   []filePath{"analytics.js"},
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
 		{
 			desc: "home",
 			give: []string{"-home", "go.abhg.dev/doc2go", "./..."},
@@ -171,6 +200,171 @@ func TestCLIParser(t *testing.T) {
 				OutputDir: "_site",
 			},
 		},
+		{
+			desc: "jobs",
+			give: []string{"-jobs", "4", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				Jobs:      4,
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "clean",
+			give: []string{"-clean", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				Clean:     cleanEnabled,
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "clean dry run",
+			give: []string{"-clean=dry-run", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				Clean:     cleanDryRun,
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "report",
+			give: []string{"-report", "report.json", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				Report:    "report.json",
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "man",
+			give: []string{"-man", "man1", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				Man:       "man1",
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "single page",
+			give: []string{"-single-page", "api.html", "./..."},
+			want: params{
+				Config:     "doc2go.rc",
+				SinglePage: "api.html",
+				Patterns:   []string{"./..."},
+				OutputDir:  "_site",
+			},
+		},
+		{
+			desc: "symbol search with embed",
+			give: []string{"-symbol-search", "-embed", "./..."},
+			want: params{
+				Config:       "doc2go.rc",
+				SymbolSearch: true,
+				Embed:        true,
+				Patterns:     []string{"./..."},
+				OutputDir:    "_site",
+			},
+		},
+		{
+			desc: "go import",
+			give: []string{
+				"-go-import", "go.abhg.dev/doc2go=git:https://github.com/abhinav/doc2go",
+				"-go-import=example.com/foo/=hg:https://hg.example.com/foo",
+				"-go-import=example.com/bar=git:https://github.com/example/mono#go/bar/",
+				"./...",
+			},
+			want: params{
+				Config: "doc2go.rc",
+				GoImports: []goImport{
+					{
+						Module:  "go.abhg.dev/doc2go",
+						VCS:     "git",
+						RepoURL: "https://github.com/abhinav/doc2go",
+					},
+					{
+						Module:  "example.com/foo",
+						VCS:     "hg",
+						RepoURL: "https://hg.example.com/foo",
+					},
+					{
+						Module:  "example.com/bar",
+						VCS:     "git",
+						RepoURL: "https://github.com/example/mono",
+						Subdir:  "go/bar",
+					},
+				},
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "base url",
+			give: []string{"-base-url", "https://example.com/docs/", "-sitemap-latest", "./..."},
+			want: params{
+				Config:        "doc2go.rc",
+				BaseURL:       baseURL{&url.URL{Scheme: "https", Host: "example.com", Path: "/docs/"}},
+				SitemapLatest: true,
+				Patterns:      []string{"./..."},
+				OutputDir:     "_site",
+			},
+		},
+		{
+			desc: "format man",
+			give: []string{"-format", "man", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				Format:    formatMan,
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "markdown",
+			give: []string{"-format", "markdown", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				Format:    formatMarkdown,
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "keep going",
+			give: []string{"-keep-going", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				KeepGoing: true,
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "refs",
+			give: []string{"-refs", "v1.*,main", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				Refs:      "v1.*,main",
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
+		{
+			desc: "serve",
+			give: []string{"serve", "-http", ":8080", "./..."},
+			want: params{
+				Config:    "doc2go.rc",
+				Serve:     true,
+				HTTP:      ":8080",
+				Patterns:  []string{"./..."},
+				OutputDir: "_site",
+			},
+		},
 		{
 			desc: "list themes",
 			give: []string{"-highlight-list-themes"},
@@ -328,16 +522,266 @@ func TestCLIParser_Errors(t *testing.T) {
 			give: []string{"-highlight", "foo:bar"},
 			want: `unrecognized highlight mode "foo"`,
 		},
+		{
+			desc: "inline highlight with dark theme",
+			give: []string{"-highlight", "inline:github,github-dark"},
+			want: "inline highlighting does not support a dark theme",
+		},
 		{
 			desc: "subdir with /",
 			give: []string{"-subdir", "foo/bar", "./..."},
 			want: "must not contain path separators",
 		},
+		{
+			desc: "negative jobs",
+			give: []string{"-jobs", "-1", "./..."},
+			want: "jobs must not be negative",
+		},
+		{
+			desc: "serve with embed",
+			give: []string{"serve", "-embed", "./..."},
+			want: "serve cannot be used in embedded mode",
+		},
+		{
+			desc: "bad clean mode",
+			give: []string{"-clean=sometimes", "./..."},
+			want: `unrecognized clean mode "sometimes"`,
+		},
+		{
+			desc: "serve with clean",
+			give: []string{"serve", "-clean", "./..."},
+			want: "clean cannot be used with serve",
+		},
+		{
+			desc: "serve with keep-going",
+			give: []string{"serve", "-keep-going", "./..."},
+			want: "keep-going cannot be used with serve",
+		},
+		{
+			desc: "serve with incremental",
+			give: []string{"serve", "-incremental", "./..."},
+			want: "incremental cannot be used with serve",
+		},
+		{
+			desc: "serve with jobs",
+			give: []string{"serve", "-jobs", "4", "./..."},
+			want: "jobs cannot be used with serve",
+		},
+		{
+			desc: "incremental with archive",
+			give: []string{"-incremental", "-out", "site.tar.gz", "./..."},
+			want: "incremental cannot be used with archive output",
+		},
+		{
+			desc: "pagefind with archive",
+			give: []string{"-pagefind", "-out", "site.zip", "./..."},
+			want: "pagefind cannot be used with archive output",
+		},
+		{
+			desc: "refs with subdir",
+			give: []string{"-refs", "v1.0.0", "-subdir", "v1", "./..."},
+			want: "refs cannot be used with subdir",
+		},
+		{
+			desc: "refs with pkg-version",
+			give: []string{"-refs", "v1.0.0", "-pkg-version", "v1", "./..."},
+			want: "refs cannot be used with pkg-version",
+		},
+		{
+			desc: "refs with report",
+			give: []string{"-refs", "v1.0.0", "-report", "report.json", "./..."},
+			want: "refs cannot be used with report",
+		},
+		{
+			desc: "markdown with serve",
+			give: []string{"serve", "-format", "markdown", "./..."},
+			want: "serve cannot be used with markdown output",
+		},
+		{
+			desc: "markdown with embed",
+			give: []string{"-format", "markdown", "-embed", "./..."},
+			want: "embed cannot be used with markdown output",
+		},
+		{
+			desc: "markdown with pagefind",
+			give: []string{"-format", "markdown", "-pagefind", "./..."},
+			want: "pagefind cannot be used with markdown output",
+		},
+		{
+			desc: "json with serve",
+			give: []string{"serve", "-format", "json", "./..."},
+			want: "serve cannot be used with json output",
+		},
+		{
+			desc: "json with frontmatter",
+			give: []string{"-format", "json", "-frontmatter", "fm.tmpl", "./..."},
+			want: "frontmatter cannot be used with json output",
+		},
+		{
+			desc: "man format with frontmatter",
+			give: []string{"-format", "man", "-frontmatter", "fm.tmpl", "./..."},
+			want: "frontmatter cannot be used with man output",
+		},
+		{
+			desc: "man format with subdir",
+			give: []string{"-format", "man", "-subdir", "v1", "./..."},
+			want: "subdir cannot be used with man output",
+		},
+		{
+			desc: "man format with man",
+			give: []string{"-format", "man", "-man", "man1", "./..."},
+			want: "man cannot be used with man output",
+		},
+		{
+			desc: "single page with serve",
+			give: []string{"serve", "-single-page", "api.html", "./..."},
+			want: "single-page cannot be used with serve",
+		},
+		{
+			desc: "single page with markdown",
+			give: []string{"-format", "markdown", "-single-page", "api.html", "./..."},
+			want: "single-page cannot be used with markdown output",
+		},
+		{
+			desc: "single page with subdir",
+			give: []string{"-subdir", "v1", "-single-page", "api.html", "./..."},
+			want: "single-page cannot be used with subdir",
+		},
+		{
+			desc: "single page with watch",
+			give: []string{"-watch", "-single-page", "api.html", "./..."},
+			want: "single-page cannot be used with watch",
+		},
+		{
+			desc: "serve with man",
+			give: []string{"serve", "-man", "man1", "./..."},
+			want: "man cannot be used with serve",
+		},
+		{
+			desc: "refs with man",
+			give: []string{"-refs", "v1.0.0", "-man", "man1", "./..."},
+			want: "refs cannot be used with man",
+		},
+		{
+			desc: "serve with report",
+			give: []string{"serve", "-report", "report.json", "./..."},
+			want: "report cannot be used with serve",
+		},
+		{
+			desc: "refs with serve",
+			give: []string{"serve", "-refs", "v1.0.0", "./..."},
+			want: "refs cannot be used with serve",
+		},
+		{
+			desc: "serve with watch",
+			give: []string{"serve", "-watch", "./..."},
+			want: "watch cannot be used with serve",
+		},
 		{
 			desc: "pagefind with embed",
 			give: []string{"-embed", "-pagefind", "./..."},
 			want: "pagefind cannot be used in embedded mode",
 		},
+		{
+			desc: "relative base url",
+			give: []string{"-base-url", "/docs", "./..."},
+			want: `"/docs" is not an absolute http or https URL`,
+		},
+		{
+			desc: "base url with serve",
+			give: []string{"serve", "-base-url", "https://example.com", "./..."},
+			want: "base-url cannot be used with serve",
+		},
+		{
+			desc: "base url with json",
+			give: []string{"-format", "json", "-base-url", "https://example.com", "./..."},
+			want: "base-url cannot be used with json output",
+		},
+		{
+			desc: "go import without vcs",
+			give: []string{"-go-import", "example.com/foo=https://example.com/foo", "./..."},
+			want: `unsupported version control system "https"`,
+		},
+		{
+			desc: "go import without module",
+			give: []string{"-go-import", "git:https://example.com/foo", "./..."},
+			want: "expected form 'module=vcs:url[#subdir]'",
+		},
+		{
+			desc: "go import relative url",
+			give: []string{"-go-import", "example.com/foo=git:foo", "./..."},
+			want: `"foo" is not an absolute URL`,
+		},
+		{
+			desc: "go import subdir outside repo",
+			give: []string{"-go-import", "example.com/foo=git:https://example.com/mono#../foo", "./..."},
+			want: `"../foo" is not a subdirectory of the repository`,
+		},
+		{
+			desc: "go import empty subdir",
+			give: []string{"-go-import", "example.com/foo=git:https://example.com/mono#", "./..."},
+			want: `"" is not a subdirectory of the repository`,
+		},
+		{
+			desc: "go import with embed",
+			give: []string{"-embed", "-go-import", "example.com/foo=git:https://example.com/foo", "./..."},
+			want: "go-import cannot be used in embedded mode",
+		},
+		{
+			desc: "go import with markdown",
+			give: []string{"-format", "markdown", "-go-import", "example.com/foo=git:https://example.com/foo", "./..."},
+			want: "go-import cannot be used with markdown output",
+		},
+		{
+			desc: "symbol search with pagefind",
+			give: []string{"-symbol-search", "-pagefind", "./..."},
+			want: "symbol-search cannot be used with pagefind",
+		},
+		{
+			desc: "symbol search with serve",
+			give: []string{"serve", "-symbol-search", "./..."},
+			want: "symbol-search cannot be used with serve",
+		},
+		{
+			desc: "symbol search with json",
+			give: []string{"-format", "json", "-symbol-search", "./..."},
+			want: "symbol-search cannot be used with json output",
+		},
+		{
+			desc: "symbol search with single page",
+			give: []string{"-single-page", "api.html", "-symbol-search", "./..."},
+			want: "single-page cannot be used with symbol-search",
+		},
+		{
+			desc: "template dir with markdown",
+			give: []string{"-format", "markdown", "-template-dir", "templates", "./..."},
+			want: "template-dir cannot be used with markdown output",
+		},
+		{
+			desc: "extra css with embed",
+			give: []string{"-embed", "-extra-css", "brand.css", "./..."},
+			want: "extra-css cannot be used in embedded mode",
+		},
+		{
+			desc: "static dir with json",
+			give: []string{"-format", "json", "-static-dir", "assets", "./..."},
+			want: "static-dir cannot be used with json output",
+		},
+		{
+			desc: "extra js with single page",
+			give: []string{"-single-page", "api.html", "-extra-js", "analytics.js", "./..."},
+			want: "single-page cannot be used with extra-js",
+		},
+		{
+			desc: "empty extra css",
+			give: []string{"-extra-css", "", "./..."},
+			want: "path must not be empty",
+		},
+		{
+			desc: "sitemap latest without base url",
+			give: []string{"-sitemap-latest", "./..."},
+			want: "sitemap-latest requires base-url",
+		},
 	}
 
 	for _, tt := range tests {
@@ -391,6 +835,24 @@ func TestHighlightParams_Parse(t *testing.T) {
 			want:       highlightParams{Theme: "foo", Mode: highlightModeInline},
 			wantString: "inline:foo",
 		},
+		{
+			desc:       "dark theme",
+			give:       []string{"-x", "foo,bar"},
+			want:       highlightParams{Theme: "foo", DarkTheme: "bar", Mode: highlightModeAuto},
+			wantString: "auto:foo,bar",
+		},
+		{
+			desc:       "mode and dark theme",
+			give:       []string{"-x", "classes:foo,bar"},
+			want:       highlightParams{Theme: "foo", DarkTheme: "bar", Mode: highlightModeClasses},
+			wantString: "classes:foo,bar",
+		},
+		{
+			desc:       "dark theme only",
+			give:       []string{"-x", "classes:,bar"},
+			want:       highlightParams{DarkTheme: "bar", Mode: highlightModeClasses},
+			wantString: "classes:,bar",
+		},
 	}
 
 	for _, tt := range tests {
@@ -494,6 +956,92 @@ func TestConfigFileParser(t *testing.T) {
 	})
 }
 
+func TestOutputFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc         string
+		give         []string
+		want         outputFormat
+		wantString   string
+		wantBasename string
+	}{
+		{
+			desc:         "default",
+			want:         formatHTML,
+			wantString:   "html",
+			wantBasename: "index.html",
+		},
+		{
+			desc:         "html",
+			give:         []string{"-x", "html"},
+			want:         formatHTML,
+			wantString:   "html",
+			wantBasename: "index.html",
+		},
+		{
+			desc:         "markdown",
+			give:         []string{"-x", "markdown"},
+			want:         formatMarkdown,
+			wantString:   "markdown",
+			wantBasename: "README.md",
+		},
+		{
+			desc:         "md",
+			give:         []string{"-x", "MD"},
+			want:         formatMarkdown,
+			wantString:   "markdown",
+			wantBasename: "README.md",
+		},
+		{
+			desc:         "json",
+			give:         []string{"-x", "json"},
+			want:         formatJSON,
+			wantString:   "json",
+			wantBasename: "index.json",
+		},
+		{
+			desc:         "man",
+			give:         []string{"-x", "man"},
+			want:         formatMan,
+			wantString:   "man",
+			wantBasename: "index.html",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			fset := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+			fset.SetOutput(iotest.Writer(t))
+
+			var got outputFormat
+			fset.Var(&got, "x", "")
+
+			require.NoError(t, fset.Parse(tt.give))
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want, got.Get())
+			assert.Equal(t, tt.wantString, got.String())
+			assert.Equal(t, tt.wantBasename, got.DefaultBasename())
+		})
+	}
+}
+
+func TestOutputFormat_unrecognized(t *testing.T) {
+	t.Parallel()
+
+	fset := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fset.SetOutput(iotest.Writer(t))
+
+	var got outputFormat
+	fset.Var(&got, "x", "")
+
+	err := fset.Parse([]string{"-x", "pdf"})
+	assert.ErrorContains(t, err, "unrecognized output format")
+	assert.Equal(t, "outputFormat(42)", outputFormat(42).String())
+}
+
 func TestRelLinkStyle(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,147 @@
+// Package buildcache records information about packages
+// rendered by a previous run of doc2go
+// so that unchanged packages may be skipped in subsequent runs.
+package buildcache
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"braces.dev/errtrace"
+)
+
+// Cache tracks hashes of packages rendered by a previous run,
+// and those rendered by the current run.
+//
+// Only entries that were looked up or stored during the current run
+// are retained when the cache is saved.
+// Packages that are no longer part of the documentation
+// will therefore drop out of the cache.
+//
+// Cache is safe for concurrent use.
+type Cache struct {
+	salt string
+
+	mu   sync.Mutex
+	prev map[string]entry // import path => entry
+	next map[string]entry // import path => entry
+}
+
+type entry struct {
+	Hash string `json:"hash"`
+	Package
+}
+
+// Package is the information recorded about a rendered package
+// that later runs need without rendering it again.
+type Package struct {
+	Synopsis string `json:"synopsis,omitempty"`
+
+	// Exported is the number of exported symbols in the package.
+	Exported int `json:"exported,omitempty"`
+
+	// Documented is the number of exported symbols
+	// that have a doc comment.
+	Documented int `json:"documented,omitempty"`
+}
+
+// fileFormat is the on-disk representation of the cache.
+type fileFormat struct {
+	// Salt identifies the global configuration
+	// that the cache was built with.
+	// If it changes, all entries are invalidated.
+	Salt string `json:"salt"`
+
+	Packages map[string]entry `json:"packages"`
+}
+
+// New builds an empty cache with the given salt.
+//
+// The salt should capture all global inputs
+// that affect the output of every package:
+// the doc2go version, command line flags, etc.
+func New(salt string) *Cache {
+	return &Cache{
+		salt: salt,
+		prev: make(map[string]entry),
+		next: make(map[string]entry),
+	}
+}
+
+// Load reads a cache from the given file.
+//
+// If the file does not exist,
+// or was written with a different salt,
+// an empty cache is returned.
+func Load(path, salt string) (*Cache, error) {
+	c := New(salt)
+
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return nil, errtrace.Wrap(err)
+	}
+
+	var f fileFormat
+	if err := json.Unmarshal(bs, &f); err != nil {
+		// A corrupt cache is not fatal.
+		// We'll just rebuild everything.
+		return c, nil
+	}
+
+	if f.Salt == salt && f.Packages != nil {
+		c.prev = f.Packages
+	}
+	return c, nil
+}
+
+// Lookup reports whether the package at the given import path
+// was previously rendered with the given hash.
+// If so, it returns the information recorded for it.
+//
+// A successful lookup keeps the entry alive for the next Save.
+func (c *Cache) Lookup(importPath, hash string) (pkg Package, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.prev[importPath]
+	if !ok || e.Hash != hash {
+		return Package{}, false
+	}
+	c.next[importPath] = e
+	return e.Package, true
+}
+
+// Store records that the package at the given import path
+// was rendered with the given hash.
+func (c *Cache) Store(importPath, hash string, pkg Package) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.next[importPath] = entry{Hash: hash, Package: pkg}
+}
+
+// Save writes the entries looked up or stored during this run
+// to the given file, creating parent directories if necessary.
+func (c *Cache) Save(path string) error {
+	c.mu.Lock()
+	bs, err := json.MarshalIndent(fileFormat{
+		Salt:     c.salt,
+		Packages: c.next,
+	}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o1755); err != nil {
+		return errtrace.Wrap(err)
+	}
+	return errtrace.Wrap(os.WriteFile(path, bs, 0o644))
+}
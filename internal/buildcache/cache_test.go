@@ -0,0 +1,103 @@
+package buildcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_roundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cache", "cache.json")
+
+	c, err := Load(path, "salt")
+	require.NoError(t, err)
+
+	_, ok := c.Lookup("foo", "hash1")
+	assert.False(t, ok, "empty cache must not have entries")
+
+	c.Store("foo", "hash1", Package{
+		Synopsis:   "Package foo does things.",
+		Exported:   3,
+		Documented: 2,
+	})
+	c.Store("bar", "hash2", Package{})
+	require.NoError(t, c.Save(path))
+
+	t.Run("hit", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := Load(path, "salt")
+		require.NoError(t, err)
+
+		pkg, ok := c.Lookup("foo", "hash1")
+		assert.True(t, ok)
+		assert.Equal(t, Package{
+			Synopsis:   "Package foo does things.",
+			Exported:   3,
+			Documented: 2,
+		}, pkg)
+	})
+
+	t.Run("hash mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := Load(path, "salt")
+		require.NoError(t, err)
+
+		_, ok := c.Lookup("foo", "hash2")
+		assert.False(t, ok)
+	})
+
+	t.Run("salt mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		c, err := Load(path, "different salt")
+		require.NoError(t, err)
+
+		_, ok := c.Lookup("foo", "hash1")
+		assert.False(t, ok)
+	})
+}
+
+func TestCache_dropsUnusedEntries(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c := New("salt")
+	c.Store("foo", "hash1", Package{})
+	c.Store("bar", "hash2", Package{})
+	require.NoError(t, c.Save(path))
+
+	// Second run only sees foo.
+	c, err := Load(path, "salt")
+	require.NoError(t, err)
+	_, ok := c.Lookup("foo", "hash1")
+	require.True(t, ok)
+	require.NoError(t, c.Save(path))
+
+	c, err = Load(path, "salt")
+	require.NoError(t, err)
+	_, ok = c.Lookup("foo", "hash1")
+	assert.True(t, ok, "foo must be retained")
+	_, ok = c.Lookup("bar", "hash2")
+	assert.False(t, ok, "bar must be dropped")
+}
+
+func TestLoad_corrupt(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+
+	c, err := Load(path, "salt")
+	require.NoError(t, err)
+
+	_, ok := c.Lookup("foo", "hash")
+	assert.False(t, ok)
+}
@@ -0,0 +1,63 @@
+// Package frontmatter generates front matter for pages
+// from a user-provided template.
+package frontmatter
+
+import (
+	"bytes"
+	"io"
+	"text/template"
+
+	"braces.dev/errtrace"
+)
+
+// Data is the context that front matter templates are executed with.
+// See 'doc2go -help=frontmatter'.
+type Data struct {
+	Path        string
+	Basename    string
+	NumChildren int
+	Package     PackageData
+}
+
+// PackageData is information about the package in [Data].
+// It's empty for directories.
+type PackageData struct {
+	Name     string
+	Synopsis string
+}
+
+// Name returns the name of the package or directory.
+func (d Data) Name() string {
+	if n := d.Package.Name; n != "" && n != "main" {
+		return n
+	}
+	if d.Basename != "" {
+		return d.Basename
+	}
+	return ""
+}
+
+// Render executes the front matter template with the given data,
+// and writes the result to w followed by an empty line.
+//
+// Nothing is written if tmpl is nil
+// or if the template generates only whitespace.
+func Render(w io.Writer, tmpl *template.Template, d Data) error {
+	if tmpl == nil {
+		return nil
+	}
+
+	var buff bytes.Buffer
+	if err := tmpl.Execute(&buff, d); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	bs := bytes.TrimSpace(buff.Bytes())
+	if len(bs) == 0 {
+		return nil
+	}
+	bs = append(bs, '\n', '\n')
+
+	_, err := w.Write(bs)
+	return errtrace.Wrap(err)
+}
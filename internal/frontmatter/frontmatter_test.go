@@ -0,0 +1,94 @@
+package frontmatter
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestData_Name(t *testing.T) {
+	tests := []struct {
+		desc string
+		data Data
+		want string
+	}{
+		{desc: "empty"},
+		{
+			desc: "package",
+			data: Data{
+				Package: PackageData{
+					Name: "foo",
+				},
+			},
+			want: "foo",
+		},
+		{
+			desc: "main package",
+			data: Data{
+				Package: PackageData{
+					Name: "main",
+				},
+				Basename: "bar",
+			},
+			want: "bar",
+		},
+		{
+			desc: "dir",
+			data: Data{
+				Basename: "baz",
+			},
+			want: "baz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.data.Name())
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		tmpl string // no template if empty
+		want string
+	}{
+		{desc: "no template"},
+		{
+			desc: "blank",
+			tmpl: "  \n{{ .Package.Synopsis }}\n",
+		},
+		{
+			desc: "trimmed",
+			tmpl: "\n---\ntitle: {{ .Name }}\n---\n\n",
+			want: "---\ntitle: foo\n---\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var tmpl *template.Template
+			if tt.tmpl != "" {
+				var err error
+				tmpl, err = template.New(t.Name()).Parse(tt.tmpl)
+				require.NoError(t, err)
+			}
+
+			var buff bytes.Buffer
+			require.NoError(t, Render(&buff, tmpl, Data{
+				Path:     "example.com/foo",
+				Basename: "foo",
+				Package:  PackageData{Name: "foo"},
+			}))
+			assert.Equal(t, tt.want, buff.String())
+		})
+	}
+}
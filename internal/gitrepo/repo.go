@@ -0,0 +1,133 @@
+// Package gitrepo checks out revisions of a Git repository
+// into temporary worktrees.
+package gitrepo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"braces.dev/errtrace"
+)
+
+// Repo is a Git repository on disk.
+type Repo struct {
+	// Dir is a directory inside the repository.
+	//
+	// Defaults to the current directory.
+	Dir string
+
+	// Git is the path to the git executable.
+	//
+	// Defaults to searching $PATH for git.
+	Git string
+
+	// DebugLog receives the commands that are run.
+	//
+	// Use nil to disable debug logging.
+	DebugLog *log.Logger
+}
+
+// Prefix reports the path of Dir relative to the root of the repository,
+// or an empty string if Dir is the root.
+// The path uses '/' as the separator.
+func (r *Repo) Prefix(ctx context.Context) (string, error) {
+	out, err := r.git(ctx, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", errtrace.Wrap(err)
+	}
+	return strings.TrimSuffix(strings.TrimSpace(out), "/"), nil
+}
+
+// ResolveRefs expands the given list of refs into refs
+// that can be checked out.
+//
+// Refs containing glob characters ('*', '?', or '[')
+// are treated as patterns, and expand into the tags that match them,
+// sorted by version.
+// It is an error for a pattern to not match any tags.
+// Other refs must name a commit in the repository,
+// and are returned as-is.
+//
+// Duplicate refs are dropped.
+func (r *Repo) ResolveRefs(ctx context.Context, refs []string) ([]string, error) {
+	var resolved []string
+	for _, ref := range refs {
+		if !strings.ContainsAny(ref, "*?[") {
+			if _, err := r.git(ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+				return nil, errtrace.Wrap(fmt.Errorf("unknown ref %q", ref))
+			}
+			resolved = append(resolved, ref)
+			continue
+		}
+
+		out, err := r.git(ctx, "tag", "--list", "--sort=version:refname", ref)
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		tags := strings.Fields(out)
+		if len(tags) == 0 {
+			return nil, errtrace.Wrap(fmt.Errorf("no tags match %q", ref))
+		}
+		resolved = append(resolved, tags...)
+	}
+
+	// Drop duplicates, keeping the first occurrence.
+	seen := make(map[string]struct{}, len(resolved))
+	return slices.DeleteFunc(resolved, func(ref string) bool {
+		if _, ok := seen[ref]; ok {
+			return true
+		}
+		seen[ref] = struct{}{}
+		return false
+	}), nil
+}
+
+// AddWorktree checks out the given ref into a new worktree at dir.
+// dir must not exist, or must be empty.
+//
+// The worktree is detached from any branch.
+// Use RemoveWorktree to delete it when done.
+func (r *Repo) AddWorktree(ctx context.Context, ref, dir string) error {
+	_, err := r.git(ctx, "worktree", "add", "--detach", dir, ref)
+	return errtrace.Wrap(err)
+}
+
+// RemoveWorktree deletes a worktree created by AddWorktree,
+// discarding any changes made to it.
+func (r *Repo) RemoveWorktree(ctx context.Context, dir string) error {
+	_, err := r.git(ctx, "worktree", "remove", "--force", dir)
+	return errtrace.Wrap(err)
+}
+
+// git runs a git command inside the repository
+// and returns its standard output.
+func (r *Repo) git(ctx context.Context, args ...string) (string, error) {
+	git := r.Git
+	if git == "" {
+		git = "git"
+	}
+
+	cmd := exec.CommandContext(ctx, git, args...)
+	cmd.Dir = r.Dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if r.DebugLog != nil {
+		r.DebugLog.Printf("git %v", strings.Join(args, " "))
+	}
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = errors.Join(err, errors.New(msg))
+		}
+		return "", errtrace.Wrap(fmt.Errorf("git %v: %w", args[0], err))
+	}
+	return stdout.String(), nil
+}
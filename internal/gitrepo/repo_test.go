@@ -0,0 +1,116 @@
+package gitrepo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepo(t *testing.T) {
+	t.Parallel()
+
+	dir := newTestRepo(t)
+	ctx := context.Background()
+
+	gitRun(t, dir, "tag", "v1.10.0")
+	gitRun(t, dir, "tag", "v1.2.0")
+	gitRun(t, dir, "tag", "v2.0.0")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b"), 0o644))
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "-m", "second")
+
+	repo := Repo{Dir: filepath.Join(dir, "sub")}
+
+	t.Run("Prefix", func(t *testing.T) {
+		prefix, err := repo.Prefix(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "sub", prefix)
+
+		prefix, err = (&Repo{Dir: dir}).Prefix(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, prefix)
+	})
+
+	t.Run("ResolveRefs", func(t *testing.T) {
+		refs, err := repo.ResolveRefs(ctx, []string{"HEAD", "v1.*", "v1.2.0"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"HEAD", "v1.2.0", "v1.10.0"}, refs)
+	})
+
+	t.Run("ResolveRefs/unknown ref", func(t *testing.T) {
+		_, err := repo.ResolveRefs(ctx, []string{"v3.0.0"})
+		assert.ErrorContains(t, err, `unknown ref "v3.0.0"`)
+	})
+
+	t.Run("ResolveRefs/no matching tags", func(t *testing.T) {
+		_, err := repo.ResolveRefs(ctx, []string{"v3.*"})
+		assert.ErrorContains(t, err, `no tags match "v3.*"`)
+	})
+
+	t.Run("Worktree", func(t *testing.T) {
+		wt := filepath.Join(t.TempDir(), "wt")
+		require.NoError(t, repo.AddWorktree(ctx, "v1.2.0", wt))
+
+		assert.FileExists(t, filepath.Join(wt, "sub", "a.txt"))
+		assert.NoFileExists(t, filepath.Join(wt, "sub", "b.txt"),
+			"file from a later commit")
+
+		require.NoError(t, repo.RemoveWorktree(ctx, wt))
+		assert.NoDirExists(t, wt)
+	})
+}
+
+func TestRepo_notARepository(t *testing.T) {
+	t.Parallel()
+
+	requireGit(t)
+
+	repo := Repo{Dir: t.TempDir()}
+	_, err := repo.Prefix(context.Background())
+	assert.ErrorContains(t, err, "git rev-parse")
+}
+
+// newTestRepo creates a Git repository with a single commit
+// containing sub/a.txt.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+
+	requireGit(t)
+
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a"), 0o644))
+
+	gitRun(t, dir, "init", "--quiet")
+	gitRun(t, dir, "add", ".")
+	gitRun(t, dir, "commit", "--quiet", "-m", "initial")
+	return dir
+}
+
+func requireGit(t *testing.T) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found:", err)
+	}
+}
+
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test",
+		"GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test",
+		"GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v:\n%s", args, out)
+}
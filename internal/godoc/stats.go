@@ -0,0 +1,49 @@
+package godoc
+
+// Stats counts the exported symbols in a package.
+type Stats struct {
+	// Exported is the number of exported constants, variables,
+	// types, functions, and methods.
+	Exported int
+
+	// Documented is the number of exported symbols
+	// that have a doc comment.
+	// Constants and variables declared in a group
+	// are documented by the group's comment.
+	Documented int
+}
+
+// Stats counts the exported symbols in the package.
+func (p *Package) Stats() Stats {
+	var s Stats
+	s.values(p.Constants)
+	s.values(p.Variables)
+	for _, t := range p.Types {
+		s.add(1, t.Doc != nil)
+		s.values(t.Constants)
+		s.values(t.Variables)
+		s.funcs(t.Functions)
+		s.funcs(t.Methods)
+	}
+	s.funcs(p.Functions)
+	return s
+}
+
+func (s *Stats) values(vals []*Value) {
+	for _, v := range vals {
+		s.add(len(v.Names), v.Doc != nil)
+	}
+}
+
+func (s *Stats) funcs(funcs []*Function) {
+	for _, f := range funcs {
+		s.add(1, f.Doc != nil)
+	}
+}
+
+func (s *Stats) add(n int, documented bool) {
+	s.Exported += n
+	if documented {
+		s.Documented += n
+	}
+}
@@ -0,0 +1,60 @@
+package godoc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackage_Stats(t *testing.T) {
+	t.Parallel()
+
+	pkg := srcPackage{
+		Name:       "foo",
+		ImportPath: "example.com/foo",
+		Lines: []string{
+			"// Package foo does stuff.",
+			"package foo",
+			"",
+			"// Group of constants.",
+			"const (",
+			"	A = 1",
+			"	B = 2",
+			")",
+			"",
+			"var X, Y int",
+			"",
+			"var unexported int",
+			"",
+			"// Bar is a type.",
+			"type Bar struct{}",
+			"",
+			"// NewBar builds a Bar.",
+			"func NewBar() *Bar { return nil }",
+			"",
+			"func (*Bar) Method() {}",
+			"",
+			"func (*Bar) unexported() {}",
+			"",
+			"// Baz does things.",
+			"func Baz() {}",
+			"",
+			"func Qux() {}",
+		},
+	}
+
+	dpkg, err := (&Assembler{
+		Linker:           &exampleLinker{},
+		Lexer:            new(stubLexer),
+		newDeclFormatter: newPlainDeclFormatter,
+	}).Assemble(pkg.Build(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, Stats{
+		// A, B, X, Y, Bar, NewBar, Bar.Method, Baz, Qux
+		Exported: 9,
+		// A, B, Bar, NewBar, Baz
+		Documented: 5,
+	}, dpkg.Stats())
+}
@@ -0,0 +1,113 @@
+package gosrc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"braces.dev/errtrace"
+	"golang.org/x/mod/module"
+	"golang.org/x/tools/go/packages"
+)
+
+// downloadedModule is a module downloaded into the module cache.
+type downloadedModule struct {
+	Path    string
+	Version string
+	Dir     string // extracted module in the module cache
+	GoMod   string
+	Error   string
+}
+
+// isLocalPattern reports whether pattern refers to a directory
+// instead of an import path.
+func isLocalPattern(pattern string) bool {
+	return build.IsLocalImport(pattern) || filepath.IsAbs(pattern)
+}
+
+// downloadPattern resolves a path@version pattern
+// and downloads the module providing it into the module cache.
+//
+// path is the module path to document the whole module,
+// or the import path of a package inside it,
+// optionally followed by "/..." to include its descendants.
+// The returned pattern selects these packages
+// relative to the root of the downloaded module.
+func downloadPattern(cfg *packages.Config, pattern string) (mod *downloadedModule, modPattern string, err error) {
+	path, version, _ := strings.Cut(pattern, "@")
+	pkgPath, recursive := strings.CutSuffix(path, "/...")
+	if err := module.CheckImportPath(pkgPath); err != nil {
+		return nil, "", errtrace.Wrap(err)
+	}
+	if version == "" {
+		return nil, "", errtrace.Wrap(errors.New("empty version"))
+	}
+
+	// The package may be inside a module with a shorter path.
+	// Try the longest path first, like 'go get' does.
+	var firstErr error
+	for modPath := pkgPath; ; {
+		mod, err := downloadModule(cfg, modPath, version)
+		if err == nil {
+			rel := "." + strings.TrimPrefix(pkgPath, mod.Path)
+			if rel == "." || recursive {
+				rel += "/..."
+			}
+			return mod, rel, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		idx := strings.LastIndexByte(modPath, '/')
+		if idx < 0 {
+			return nil, "", errtrace.Wrap(firstErr)
+		}
+		modPath = modPath[:idx]
+	}
+}
+
+// downloadModule downloads a version of a module into the module cache
+// with 'go mod download'.
+//
+// Version may be a semantic version or a query like "latest".
+// The download respects GOPROXY, GOFLAGS, and other environment variables
+// in the configuration.
+func downloadModule(cfg *packages.Config, path, version string) (*downloadedModule, error) {
+	cmd := exec.Command("go", "mod", "download", "-json", path+"@"+version)
+	if ctx := cfg.Context; ctx != nil {
+		cmd = exec.CommandContext(ctx, "go", cmd.Args[1:]...)
+	}
+	cmd.Dir = cfg.Dir
+	cmd.Env = cfg.Env
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	// 'go mod download -json' reports errors in its output
+	// alongside a non-zero exit code.
+	var mod downloadedModule
+	if err := json.Unmarshal(stdout.Bytes(), &mod); err != nil {
+		if runErr != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("go mod download: %w\n%s", runErr, stderr.Bytes()))
+		}
+		return nil, errtrace.Wrap(fmt.Errorf("go mod download: bad output: %w", err))
+	}
+	if mod.Error != "" {
+		return nil, errtrace.Wrap(errors.New(mod.Error))
+	}
+	if runErr != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("go mod download: %w\n%s", runErr, stderr.Bytes()))
+	}
+	if mod.Dir == "" {
+		return nil, errtrace.Wrap(errors.New("go mod download: module was not extracted"))
+	}
+	return &mod, nil
+}
@@ -3,6 +3,7 @@ package gosrc
 import (
 	"cmp"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -46,6 +47,11 @@ type ModuleRef struct {
 
 	// GoMod is the path to the module's go.mod file.
 	GoMod string
+
+	// Version of the module if it was loaded from the module cache
+	// with a path@version pattern.
+	// Empty for modules loaded from the local filesystem.
+	Version string
 }
 
 // ImportedPackage is a package imported by another package.
@@ -71,12 +77,57 @@ type Finder struct {
 	//
 	// Use nil to disable debug logging.
 	DebugLog *log.Logger
+
+	// OnSkip, if set, is called for each package
+	// matching the patterns that will not be documented.
+	OnSkip func(SkippedPackage)
+
+	// OnWarning, if set, is called for each problem
+	// found with a package, in addition to it being logged.
+	OnWarning func(Warning)
+}
+
+// SkipReason explains why a package was not documented.
+type SkipReason string
+
+// Reasons that Finder may skip a package for.
+const (
+	// SkipVendored marks packages inside a vendor directory.
+	SkipVendored SkipReason = "vendor"
+
+	// SkipNoGoFiles marks packages that have only test files.
+	SkipNoGoFiles SkipReason = "no non-test files"
+
+	// SkipLoadErrors marks packages that could not be loaded.
+	// The errors are reported as warnings.
+	SkipLoadErrors SkipReason = "load errors"
+)
+
+// SkippedPackage is a package that will not be documented.
+type SkippedPackage struct {
+	ImportPath string
+	Reason     SkipReason
+}
+
+// Warning is a problem found with a package.
+type Warning struct {
+	ImportPath string
+	Message    string
 }
 
 const _finderLoadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedModule
 
 // FindPackages searches for packages matching the given import path patterns,
 // and returns references to them.
+//
+// Patterns in the form module@version refer to all packages
+// in that version of the module.
+// Patterns in the form package@version and package/...@version
+// refer to only that package or its descendants, respectively.
+// The module is downloaded into the module cache if necessary,
+// respecting the GOPROXY and GOFLAGS environment variables.
+// Use GOPROXY=off to document only modules already in the cache,
+// or GOPROXY=file:///path/to/mirror to use a local mirror.
 func (f *Finder) FindPackages(patterns ...string) ([]*PackageRef, error) {
 	var cfg packages.Config
 	if f.PackagesConfig != nil {
@@ -95,29 +146,90 @@ func (f *Finder) FindPackages(patterns ...string) ([]*PackageRef, error) {
 		cfg.Logf = f.DebugLog.Printf
 	}
 
-	pkgs, err := packages.Load(&cfg, patterns...)
-	if err != nil {
-		return nil, errtrace.Wrap(err)
+	var (
+		localPatterns []string
+		modPatterns   []string // path@version
+	)
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, "@") && !isLocalPattern(pattern) {
+			modPatterns = append(modPatterns, pattern)
+		} else {
+			localPatterns = append(localPatterns, pattern)
+		}
 	}
 
-	if len(pkgs) == 0 {
+	var (
+		infos []*PackageRef
+		found int // number of packages returned by go/packages
+	)
+	mods := make(map[string]*ModuleRef) // module path@version -> ModuleRef
+	if len(localPatterns) > 0 || len(modPatterns) == 0 {
+		pkgs, err := packages.Load(&cfg, localPatterns...)
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		found += len(pkgs)
+		infos = f.collect(infos, mods, pkgs, "")
+	}
+
+	for _, pattern := range modPatterns {
+		mod, modPattern, err := downloadPattern(&cfg, pattern)
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("%v: %w", pattern, err))
+		}
+		if f.DebugLog != nil {
+			f.DebugLog.Printf("Loading %v from %v@%v in %v", modPattern, mod.Path, mod.Version, mod.Dir)
+		}
+
+		modCfg := cfg
+		modCfg.Dir = mod.Dir
+		env := cfg.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		// Ignore any go.work file in the working directory.
+		modCfg.Env = append(slices.Clip(env), "GOWORK=off")
+		// The module cache is read-only.
+		// Don't let 'go list' try to update go.mod or go.sum.
+		modCfg.BuildFlags = append(slices.Clip(cfg.BuildFlags), "-mod=readonly")
+
+		pkgs, err := packages.Load(&modCfg, modPattern)
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("%v: %w", pattern, err))
+		}
+		found += len(pkgs)
+		infos = f.collect(infos, mods, pkgs, mod.Version)
+	}
+
+	if found == 0 {
 		return nil, errtrace.Wrap(errors.New("no packages found"))
 	}
+	return infos, nil
+}
 
-	infos := make([]*PackageRef, 0, len(pkgs))
-	mods := make(map[string]*ModuleRef) // module path -> ModuleRef
+// collect appends references to the given packages to infos.
+// version is the version of the module the packages were loaded from,
+// or empty if they were loaded from the local filesystem.
+func (f *Finder) collect(
+	infos []*PackageRef,
+	mods map[string]*ModuleRef,
+	pkgs []*packages.Package,
+	version string,
+) []*PackageRef {
 	for _, pkg := range pkgs {
 		if strings.HasPrefix(pkg.PkgPath, "vendor/") {
 			f.Log.Printf("[%v] Skipping.", pkg.PkgPath)
+			f.skip(pkg.PkgPath, SkipVendored)
 			continue
 		}
 
 		var pkgFailed bool
 		for _, err := range pkg.Errors {
 			pkgFailed = true
-			f.Log.Printf("[%v] %+v", pkg.PkgPath, err)
+			f.warnf(pkg.PkgPath, "%+v", err)
 		}
 		if pkgFailed {
+			f.skip(pkg.PkgPath, SkipLoadErrors)
 			continue
 		}
 
@@ -128,13 +240,14 @@ func (f *Finder) FindPackages(patterns ...string) ([]*PackageRef, error) {
 
 		if len(goFiles) == 0 {
 			f.Log.Printf("[%v] No non-test Go files. Skipping.", pkg.PkgPath)
+			f.skip(pkg.PkgPath, SkipNoGoFiles)
 			continue
 		}
 
 		pkgDir := filepath.Dir(goFiles[0])
 		var testFiles []string
 		if ents, err := os.ReadDir(pkgDir); err != nil {
-			f.Log.Printf("[%v] Skipping tests: unable to read directory: %+v", pkg.PkgPath, err)
+			f.warnf(pkg.PkgPath, "Skipping tests: unable to read directory: %+v", err)
 		} else {
 			// FIXME: This ignores build tags in test files.
 			// Maybe, it should be two load calls:
@@ -163,14 +276,16 @@ func (f *Finder) FindPackages(patterns ...string) ([]*PackageRef, error) {
 
 		var mod *ModuleRef
 		if pkg.Module != nil {
+			key := pkg.Module.Path + "@" + version
 			var ok bool
-			mod, ok = mods[pkg.Module.Path]
+			mod, ok = mods[key]
 			if !ok {
 				mod = &ModuleRef{
-					Path:  pkg.Module.Path,
-					GoMod: pkg.Module.GoMod,
+					Path:    pkg.Module.Path,
+					GoMod:   pkg.Module.GoMod,
+					Version: version,
 				}
-				mods[pkg.Module.Path] = mod
+				mods[key] = mod
 			}
 		}
 
@@ -183,5 +298,21 @@ func (f *Finder) FindPackages(patterns ...string) ([]*PackageRef, error) {
 			Module:     mod,
 		})
 	}
-	return infos, nil
+	return infos
+}
+
+func (f *Finder) skip(importPath string, reason SkipReason) {
+	if f.OnSkip != nil {
+		f.OnSkip(SkippedPackage{ImportPath: importPath, Reason: reason})
+	}
+}
+
+// warnf logs a problem with the package at importPath
+// and reports it to OnWarning.
+func (f *Finder) warnf(importPath, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	f.Log.Printf("[%v] %v", importPath, msg)
+	if f.OnWarning != nil {
+		f.OnWarning(Warning{ImportPath: importPath, Message: msg})
+	}
 }
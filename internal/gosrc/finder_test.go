@@ -1,15 +1,20 @@
 package gosrc
 
 import (
+	"archive/zip"
 	"bytes"
+	"fmt"
 	"io"
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.abhg.dev/doc2go/internal/iotest"
+	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/packages/packagestest"
 )
 
@@ -43,6 +48,9 @@ func testFinder(t *testing.T, exporter packagestest.Exporter) {
 		tags     []string
 		want     func(*packagestest.Exported, packagestest.Exporter) []*PackageRef
 		wantMsgs []string // messages printed to stderr
+
+		wantSkipped  []SkippedPackage
+		wantWarnings []string // import paths with warnings
 	}{
 		{
 			desc: "file and its test",
@@ -166,6 +174,10 @@ func testFinder(t *testing.T, exporter packagestest.Exporter) {
 				}
 			},
 			wantMsgs: []string{"[example.com/foo/bar]", "b.go:1"},
+			wantSkipped: []SkippedPackage{
+				{ImportPath: "example.com/foo/bar", Reason: SkipLoadErrors},
+			},
+			wantWarnings: []string{"example.com/foo/bar"},
 		},
 		{
 			desc: "skip only test files",
@@ -187,6 +199,9 @@ func testFinder(t *testing.T, exporter packagestest.Exporter) {
 				}
 			},
 			wantMsgs: []string{"[example.com/bar/baz] No non-test Go files. Skipping."},
+			wantSkipped: []SkippedPackage{
+				{ImportPath: "example.com/bar/baz", Reason: SkipNoGoFiles},
+			},
 		},
 	}
 
@@ -202,12 +217,22 @@ func testFinder(t *testing.T, exporter packagestest.Exporter) {
 			})
 			t.Cleanup(exported.Cleanup)
 
-			var buff bytes.Buffer
+			var (
+				buff     bytes.Buffer
+				skipped  []SkippedPackage
+				warnings []string
+			)
 			f := Finder{
 				Tags:           tt.tags,
 				Log:            log.New(io.MultiWriter(&buff, iotest.Writer(t)), "", 0),
 				DebugLog:       log.New(iotest.Writer(t), "", 0),
 				PackagesConfig: exported.Config,
+				OnSkip: func(pkg SkippedPackage) {
+					skipped = append(skipped, pkg)
+				},
+				OnWarning: func(w Warning) {
+					warnings = append(warnings, w.ImportPath)
+				},
 			}
 
 			got, err := f.FindPackages("./...")
@@ -217,6 +242,8 @@ func testFinder(t *testing.T, exporter packagestest.Exporter) {
 			for _, msg := range tt.wantMsgs {
 				assert.Contains(t, buff.String(), msg)
 			}
+			assert.Equal(t, tt.wantSkipped, skipped)
+			assert.Equal(t, tt.wantWarnings, warnings)
 		})
 	}
 }
@@ -305,3 +332,173 @@ func testFinderImportedPackage(t *testing.T, exporter packagestest.Exporter) {
 			},
 		}, refs)
 }
+
+func TestFinder_moduleVersion(t *testing.T) {
+	t.Parallel()
+
+	proxyDir := t.TempDir()
+	writeModuleProxy(t, proxyDir, "example.com/foo", "v1.2.0", map[string]string{
+		"go.mod":         "module example.com/foo\n\ngo 1.21\n",
+		"foo.go":         "// Package foo does things.\npackage foo\n",
+		"bar/bar.go":     "package bar\n",
+		"bar/x_test.go":  "package bar\n",
+		"bar/baz/baz.go": "package baz\n",
+	})
+	writeModuleProxy(t, proxyDir, "example.com/foo", "v1.1.0", map[string]string{
+		"go.mod": "module example.com/foo\n\ngo 1.21\n",
+		"foo.go": "package foo\n",
+	})
+
+	modCache := t.TempDir()
+	cfg := packages.Config{
+		Dir: t.TempDir(),
+		Env: append(os.Environ(),
+			"GOPROXY=file://"+filepath.ToSlash(proxyDir),
+			"GOMODCACHE="+modCache,
+			"GOFLAGS=-modcacherw", // so that t.TempDir can clean up
+			"GOSUMDB=off",
+			"GOTOOLCHAIN=local",
+		),
+	}
+
+	tests := []struct {
+		desc    string
+		pattern string
+		want    []string // import paths
+		version string
+	}{
+		{
+			desc:    "exact version",
+			pattern: "example.com/foo@v1.1.0",
+			want:    []string{"example.com/foo"},
+			version: "v1.1.0",
+		},
+		{
+			desc:    "latest",
+			pattern: "example.com/foo@latest",
+			want:    []string{"example.com/foo", "example.com/foo/bar", "example.com/foo/bar/baz"},
+			version: "v1.2.0",
+		},
+		{
+			desc:    "package",
+			pattern: "example.com/foo/bar@v1.2.0",
+			want:    []string{"example.com/foo/bar"},
+			version: "v1.2.0",
+		},
+		{
+			desc:    "descendants",
+			pattern: "example.com/foo/bar/...@v1.2.0",
+			want:    []string{"example.com/foo/bar", "example.com/foo/bar/baz"},
+			version: "v1.2.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			f := Finder{
+				Log:            log.New(iotest.Writer(t), "", 0),
+				DebugLog:       log.New(iotest.Writer(t), "", 0),
+				PackagesConfig: &cfg,
+			}
+			refs, err := f.FindPackages(tt.pattern)
+			require.NoError(t, err)
+
+			var got []string
+			for _, ref := range refs {
+				got = append(got, ref.ImportPath)
+
+				require.NotNil(t, ref.Module, "%v: module", ref.ImportPath)
+				assert.Equal(t, "example.com/foo", ref.Module.Path)
+				assert.Equal(t, tt.version, ref.Module.Version)
+				assert.FileExists(t, ref.Module.GoMod)
+				for _, file := range ref.Files {
+					assert.True(t, strings.HasPrefix(file, modCache), "file %v outside module cache", file)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("test files", func(t *testing.T) {
+		f := Finder{
+			Log:            log.New(iotest.Writer(t), "", 0),
+			PackagesConfig: &cfg,
+		}
+		refs, err := f.FindPackages("example.com/foo@v1.2.0")
+		require.NoError(t, err)
+		require.Len(t, refs, 3)
+		assert.Len(t, refs[1].TestFiles, 1)
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		f := Finder{
+			Log:            log.New(iotest.Writer(t), "", 0),
+			PackagesConfig: &cfg,
+		}
+		_, err := f.FindPackages("example.com/foo@v2.0.0")
+		assert.ErrorContains(t, err, "example.com/foo@v2.0.0")
+	})
+
+	t.Run("bad module path", func(t *testing.T) {
+		f := Finder{
+			Log:            log.New(iotest.Writer(t), "", 0),
+			PackagesConfig: &cfg,
+		}
+		_, err := f.FindPackages("example.com/foo bar@v1.0.0")
+		assert.ErrorContains(t, err, "example.com/foo bar@v1.0.0")
+	})
+
+	t.Run("local directory", func(t *testing.T) {
+		// Directories may contain '@'
+		// without being mistaken for a module version.
+		dir := filepath.Join(t.TempDir(), "foo@v1.0.0")
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"),
+			[]byte("module example.com/local\n\ngo 1.21\n"), 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "local.go"),
+			[]byte("package local\n"), 0o644))
+
+		localCfg := cfg
+		localCfg.Dir = dir
+		f := Finder{
+			Log:            log.New(iotest.Writer(t), "", 0),
+			PackagesConfig: &localCfg,
+		}
+		refs, err := f.FindPackages(dir + "/...")
+		require.NoError(t, err)
+		require.Len(t, refs, 1)
+		assert.Equal(t, "example.com/local", refs[0].ImportPath)
+		assert.Empty(t, refs[0].Module.Version)
+	})
+}
+
+// writeModuleProxy adds a module version to a GOPROXY=file:// mirror at dir.
+func writeModuleProxy(t *testing.T, dir, path, version string, files map[string]string) {
+	t.Helper()
+
+	vdir := filepath.Join(dir, filepath.FromSlash(path), "@v")
+	require.NoError(t, os.MkdirAll(vdir, 0o755))
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		w, err := zw.Create(path + "@" + version + "/" + name)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, body)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	write := func(name, body string) {
+		require.NoError(t, os.WriteFile(filepath.Join(vdir, name), []byte(body), 0o644))
+	}
+	write(version+".zip", buf.String())
+	write(version+".mod", files["go.mod"])
+	write(version+".info", fmt.Sprintf(`{"Version":%q}`, version))
+
+	list, err := os.OpenFile(filepath.Join(vdir, "list"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = fmt.Fprintln(list, version)
+	require.NoError(t, err)
+	require.NoError(t, list.Close())
+}
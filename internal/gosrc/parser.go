@@ -3,6 +3,7 @@ package gosrc
 import (
 	"fmt"
 	"go/ast"
+	"go/doc"
 	"go/parser"
 	"go/token"
 	"go/types"
@@ -119,6 +120,36 @@ func (p *Parser) ParsePackage(ref *PackageRef) (*Package, error) {
 	}, nil
 }
 
+// ParseSynopsis reads the synopsis of the package at the given path
+// from its package comment,
+// without parsing the rest of the package.
+//
+// The synopsis matches the one reported for the package
+// after it has been fully parsed and assembled.
+func (p *Parser) ParseSynopsis(ref *PackageRef) (string, error) {
+	fset := token.NewFileSet()
+
+	// go/doc concatenates the package comments of all files
+	// in order of their names.
+	// Test files are used only for examples.
+	var text string
+	for _, file := range slices.Sorted(slices.Values(ref.Files)) {
+		f, err := parser.ParseFile(fset, file, nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			return "", errtrace.Wrap(fmt.Errorf("parse file %q: %w", file, err))
+		}
+		if f.Doc == nil {
+			continue
+		}
+
+		if text != "" {
+			text += "\n"
+		}
+		text += f.Doc.Text()
+	}
+	return new(doc.Package).Synopsis(text), nil
+}
+
 // parseFiles parses the given list of files,
 // and returns ASTs for them in the same order.
 // If fmap is non-nil, this will also populate the map with entries
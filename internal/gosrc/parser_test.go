@@ -2,8 +2,10 @@ package gosrc
 
 import (
 	"go/ast"
+	"go/doc"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -154,3 +156,71 @@ func TestParsePackage_parseError(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSynopsis(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "no comment",
+			files: map[string]string{"foo.go": "package foo"},
+		},
+		{
+			name: "first sentence",
+			files: map[string]string{
+				"foo.go": "// Package foo does things.\n// It does them well.\npackage foo\n\nfunc Foo() {}",
+			},
+			want: "Package foo does things.",
+		},
+		{
+			name: "first file by name",
+			files: map[string]string{
+				"b.go": "// Package foo is from b.\npackage foo",
+				"a.go": "// Package foo is from a.\npackage foo",
+			},
+			want: "Package foo is from a.",
+		},
+		{
+			name: "test file",
+			files: map[string]string{
+				"foo.go":      "package foo",
+				"foo_test.go": "// Package foo is tested.\npackage foo",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			ref := PackageRef{Name: "foo", ImportPath: "example.com/foo"}
+			for name, contents := range tt.files {
+				path := filepath.Join(dir, name)
+				require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+
+				if strings.HasSuffix(name, "_test.go") {
+					ref.TestFiles = append(ref.TestFiles, path)
+				} else {
+					ref.Files = append(ref.Files, path)
+				}
+			}
+
+			var parser Parser
+			got, err := parser.ParseSynopsis(&ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// Must match the synopsis of the fully parsed package.
+			bpkg, err := parser.ParsePackage(&ref)
+			require.NoError(t, err)
+			dpkg, err := doc.NewFromFiles(bpkg.Fset, slices.Concat(bpkg.Syntax, bpkg.TestSyntax), ref.ImportPath)
+			require.NoError(t, err)
+			assert.Equal(t, dpkg.Synopsis(dpkg.Doc), got)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"slices"
 	"sync"
 
 	"braces.dev/errtrace"
@@ -22,21 +23,37 @@ type Highlighter struct {
 	// or classes, assumign use of an appropriate style sheet.
 	UseClasses bool
 
+	// DarkStyle, if set, is used instead of Style
+	// when the reader prefers a dark color scheme.
+	//
+	// This requires UseClasses.
+	// With inline styles, only Style is used.
+	DarkStyle *chroma.Style
+
+	// Options are additional options for the HTML formatter,
+	// e.g. to change the tab width or the prefix of CSS classes.
+	// UseClasses takes precedence over these.
+	Options []chromahtml.Option
+
 	once      sync.Once
 	formatter *chromahtml.Formatter
 }
 
 func (h *Highlighter) init() {
 	h.once.Do(func() {
-		h.formatter = chromahtml.New(
+		opts := append(slices.Clone(h.Options),
 			chromahtml.PreventSurroundingPre(true),
 			chromahtml.WithClasses(h.UseClasses),
 		)
+		h.formatter = chromahtml.New(opts...)
 	})
 }
 
 // WriteCSS writes the style classes for this highlighter to writer.
 // If this highlighter is not using classes, WriteCSS is a no-op.
+//
+// If DarkStyle is set, its classes are written after those of Style
+// inside a media query for readers that prefer a dark color scheme.
 func (h *Highlighter) WriteCSS(w io.Writer) error {
 	h.init()
 
@@ -44,7 +61,21 @@ func (h *Highlighter) WriteCSS(w io.Writer) error {
 		return nil
 	}
 
-	return errtrace.Wrap(h.formatter.WriteCSS(w, h.Style))
+	if err := h.formatter.WriteCSS(w, h.Style); err != nil {
+		return errtrace.Wrap(err)
+	}
+	if h.DarkStyle == nil {
+		return nil
+	}
+
+	if _, err := io.WriteString(w, "@media (prefers-color-scheme: dark) {\n"); err != nil {
+		return errtrace.Wrap(err)
+	}
+	if err := h.formatter.WriteCSS(w, h.DarkStyle); err != nil {
+		return errtrace.Wrap(err)
+	}
+	_, err := io.WriteString(w, "}\n")
+	return errtrace.Wrap(err)
 }
 
 // Highlight renders the given code block into HTML.
@@ -2,6 +2,7 @@ package highlight
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/alecthomas/chroma/v2"
@@ -98,6 +99,40 @@ func TestHighlighter_Highlight(t *testing.T) {
 	})
 }
 
+func TestCode_Text(t *testing.T) {
+	t.Parallel()
+
+	code := &Code{
+		Spans: []Span{
+			&TextSpan{Text: []byte("a < ")},
+			&AnchorSpan{
+				ID: "foo",
+				Spans: []Span{
+					&LinkSpan{
+						Dest:  "https://example.com",
+						Spans: []Span{&TextSpan{Text: []byte("b")}},
+					},
+				},
+			},
+			&TokenSpan{
+				Tokens: []chroma.Token{
+					{Type: chroma.Comment, Value: " /* foo */"},
+				},
+			},
+			&ErrorSpan{Msg: "\nbad", Err: errors.New("great sadness")},
+		},
+	}
+	assert.Equal(t, "a < b /* foo */\nbad: great sadness", code.Text())
+	assert.Empty(t, (*Code)(nil).Text())
+
+	text, ranges := code.TextRanges()
+	assert.Equal(t, code.Text(), text)
+	assert.Equal(t, []SpanRange{
+		{Span: code.Spans[1], Start: 4, End: 5},
+		{Span: code.Spans[1].(*AnchorSpan).Spans[0], Start: 4, End: 5},
+	}, ranges)
+}
+
 func TestHighlighter_Highlight_noClasses(t *testing.T) {
 	t.Parallel()
 
@@ -117,3 +152,47 @@ func TestHighlighter_Highlight_noClasses(t *testing.T) {
 	})
 	assert.Equal(t, want, string(got))
 }
+
+func TestHighlighter_WriteCSS(t *testing.T) {
+	t.Parallel()
+
+	dark := chroma.MustNewStyle("dark", map[chroma.TokenType]string{
+		chroma.Comment:    "#aaaaaa",
+		chroma.Background: "bg:#111111",
+	})
+
+	t.Run("light only", func(t *testing.T) {
+		t.Parallel()
+
+		var sb strings.Builder
+		h := Highlighter{Style: PlainStyle, UseClasses: true}
+		assert.NoError(t, h.WriteCSS(&sb))
+		assert.Contains(t, sb.String(), "#666666")
+		assert.NotContains(t, sb.String(), "@media")
+	})
+
+	t.Run("light and dark", func(t *testing.T) {
+		t.Parallel()
+
+		var sb strings.Builder
+		h := Highlighter{Style: PlainStyle, DarkStyle: dark, UseClasses: true}
+		assert.NoError(t, h.WriteCSS(&sb))
+
+		lightCSS, darkCSS, ok := strings.Cut(sb.String(), "@media (prefers-color-scheme: dark) {\n")
+		if assert.True(t, ok, "dark styles must be in a media query:\n%s", sb.String()) {
+			assert.Contains(t, lightCSS, "#666666")
+			assert.NotContains(t, lightCSS, "#aaaaaa")
+			assert.Contains(t, darkCSS, "#aaaaaa")
+			assert.True(t, strings.HasSuffix(darkCSS, "}\n"), "media query must be closed")
+		}
+	})
+
+	t.Run("inline", func(t *testing.T) {
+		t.Parallel()
+
+		var sb strings.Builder
+		h := Highlighter{Style: PlainStyle, DarkStyle: dark}
+		assert.NoError(t, h.WriteCSS(&sb))
+		assert.Empty(t, sb.String())
+	})
+}
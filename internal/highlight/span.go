@@ -1,12 +1,83 @@
 package highlight
 
-import chroma "github.com/alecthomas/chroma/v2"
+import (
+	"fmt"
+	"strings"
+
+	chroma "github.com/alecthomas/chroma/v2"
+)
 
 // Code is a code block comprised of multiple text nodes.
 type Code struct {
 	Spans []Span
 }
 
+// Text returns the contents of the code block as plain text,
+// without highlighting, anchors, or links.
+func (c *Code) Text() string {
+	text, _ := c.TextRanges()
+	return text
+}
+
+// SpanRange is the position of an [AnchorSpan] or [LinkSpan]
+// in the plain text of a code block.
+type SpanRange struct {
+	// Span is an *AnchorSpan or a *LinkSpan.
+	Span Span
+
+	// Start and End are byte offsets into the text.
+	Start, End int
+}
+
+// TextRanges returns the contents of the code block as plain text
+// like [Code.Text],
+// along with the positions of its anchors and links in that text
+// in the order they begin.
+func (c *Code) TextRanges() (string, []SpanRange) {
+	if c == nil {
+		return "", nil
+	}
+
+	var w textWriter
+	w.spans(c.Spans)
+	return w.text.String(), w.ranges
+}
+
+type textWriter struct {
+	text   strings.Builder
+	ranges []SpanRange
+}
+
+func (w *textWriter) spans(spans []Span) {
+	for _, span := range spans {
+		switch s := span.(type) {
+		case *TextSpan:
+			w.text.Write(s.Text)
+		case *TokenSpan:
+			for _, tok := range s.Tokens {
+				w.text.WriteString(tok.Value)
+			}
+		case *AnchorSpan:
+			w.nested(s, s.Spans)
+		case *LinkSpan:
+			w.nested(s, s.Spans)
+		case *ErrorSpan:
+			fmt.Fprintf(&w.text, "%v: %v", s.Msg, s.Err)
+		default:
+			panic(fmt.Sprintf("unrecognized node type %T", s))
+		}
+	}
+}
+
+// nested writes the contents of a span that holds other spans,
+// recording its position.
+func (w *textWriter) nested(span Span, spans []Span) {
+	idx := len(w.ranges)
+	w.ranges = append(w.ranges, SpanRange{Span: span, Start: w.text.Len()})
+	w.spans(spans)
+	w.ranges[idx].End = w.text.Len()
+}
+
 type (
 	// Span is a part of a code block.
 	Span interface{ span() }
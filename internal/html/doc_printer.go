@@ -2,9 +2,10 @@ package html
 
 import "go/doc/comment"
 
-// DocPrinter formats godoc comments as HTML.
+// DocPrinter formats godoc comments as HTML or Markdown.
 type DocPrinter interface {
 	HTML(*comment.Doc) []byte
+	Markdown(*comment.Doc) []byte
 	WithHeadingLevel(int) DocPrinter
 }
 
@@ -21,3 +22,13 @@ func (dp *CommentDocPrinter) WithHeadingLevel(lvl int) DocPrinter {
 	out.HeadingLevel = lvl
 	return &out
 }
+
+// Markdown formats the comment as GitHub-flavored Markdown.
+//
+// Headings are generated without explicit IDs
+// because GitHub-flavored Markdown has no syntax for them.
+func (dp *CommentDocPrinter) Markdown(doc *comment.Doc) []byte {
+	p := dp.Printer
+	p.HeadingID = func(*comment.Heading) string { return "" }
+	return p.Markdown(doc)
+}
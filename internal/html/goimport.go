@@ -0,0 +1,110 @@
+package html
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.abhg.dev/doc2go/internal/pathx"
+)
+
+// GoImport specifies the repository that hosts a module,
+// so that pages for the module's packages
+// can serve as its vanity import path.
+type GoImport struct {
+	// Module is the path of the module, e.g. "go.abhg.dev/doc2go".
+	Module string
+
+	// VCS is the version control system of the repository,
+	// e.g. "git" or "hg".
+	VCS string
+
+	// RepoURL is the URL of the root of the repository.
+	RepoURL string
+
+	// Subdir is the directory of the module inside the repository,
+	// or empty if the module is at the root of the repository.
+	Subdir string
+}
+
+// goImportMeta holds the contents of the meta tags
+// that tell the go command and pkg.go.dev where a package's source lives.
+type goImportMeta struct {
+	// Import is the content of the go-import meta tag.
+	Import string
+
+	// Source is the content of the go-source meta tag,
+	// or an empty string if we don't know how to link to
+	// files in the repository.
+	Source string
+}
+
+// Returns the go-import and go-source meta tag contents for the current page,
+// or nil if it isn't part of a module listed in GoImports.
+func (r *render) goImport() *goImportMeta {
+	var match *GoImport
+	for _, gi := range r.GoImports {
+		if !pathx.Descends(gi.Module, r.Path) {
+			continue
+		}
+		// The most specific module wins:
+		// example.com/foo/bar may be a separate module
+		// from example.com/foo.
+		if match == nil || len(gi.Module) > len(match.Module) {
+			match = gi
+		}
+	}
+	if match == nil {
+		return nil
+	}
+
+	meta := goImportMeta{
+		Import: fmt.Sprintf("%v %v %v", match.Module, match.VCS, match.RepoURL),
+	}
+	if match.Subdir != "" {
+		meta.Import += " " + match.Subdir
+	}
+	if home, dir, file, ok := sourceTemplates(match.RepoURL, match.Subdir); ok {
+		meta.Source = fmt.Sprintf("%v %v %v %v", match.Module, home, dir, file)
+	}
+	return &meta
+}
+
+// sourceTemplates returns the go-source home page
+// and templates for directories and files
+// of repositories on well-known hosts.
+// These link to the default branch of the repository.
+//
+// subdir is the directory of the module inside the repository, if any.
+// Directories in the templates are relative to it.
+//
+// ok is false if the repository host isn't known.
+func sourceTemplates(repoURL, subdir string) (home, dir, file string, ok bool) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", "", "", false
+	}
+
+	root := strings.TrimSuffix(repoURL, "/")
+	root = strings.TrimSuffix(root, ".git")
+
+	var sub string
+	if subdir != "" {
+		sub = "/" + subdir
+	}
+	switch u.Host {
+	case "github.com":
+		return root, root + "/tree/HEAD" + sub + "{/dir}",
+			root + "/blob/HEAD" + sub + "{/dir}/{file}#L{line}",
+			true
+	case "gitlab.com":
+		return root, root + "/-/tree/HEAD" + sub + "{/dir}",
+			root + "/-/blob/HEAD" + sub + "{/dir}/{file}#L{line}",
+			true
+	case "bitbucket.org":
+		return root, root + "/src/HEAD" + sub + "{/dir}",
+			root + "/src/HEAD" + sub + "{/dir}/{file}#lines-{line}",
+			true
+	}
+	return "", "", "", false
+}
@@ -0,0 +1,220 @@
+package html
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"braces.dev/errtrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"golang.org/x/net/html"
+)
+
+func TestGoImport(t *testing.T) {
+	t.Parallel()
+
+	goImports := []*GoImport{
+		{
+			Module:  "go.abhg.dev/doc2go",
+			VCS:     "git",
+			RepoURL: "https://github.com/abhinav/doc2go",
+		},
+		{
+			Module:  "go.abhg.dev/doc2go/tools",
+			VCS:     "hg",
+			RepoURL: "https://hg.example.com/tools",
+		},
+		{
+			Module:  "go.abhg.dev/sub",
+			VCS:     "git",
+			RepoURL: "https://github.com/abhinav/mono",
+			Subdir:  "go/sub",
+		},
+	}
+
+	renderPackage := func(importPath string) func(*Renderer, io.Writer) error {
+		return func(r *Renderer, w io.Writer) error {
+			return errtrace.Wrap(r.RenderPackage(w, &PackageInfo{
+				Package:    &godoc.Package{Name: "foo", ImportPath: importPath},
+				DocPrinter: new(CommentDocPrinter),
+			}))
+		}
+	}
+
+	tests := []struct {
+		desc       string
+		render     func(*Renderer, io.Writer) error
+		wantImport string // empty if there should be no tags
+		wantSource string
+	}{
+		{
+			desc:       "module root",
+			render:     renderPackage("go.abhg.dev/doc2go"),
+			wantImport: "go.abhg.dev/doc2go git https://github.com/abhinav/doc2go",
+			wantSource: "go.abhg.dev/doc2go https://github.com/abhinav/doc2go " +
+				"https://github.com/abhinav/doc2go/tree/HEAD{/dir} " +
+				"https://github.com/abhinav/doc2go/blob/HEAD{/dir}/{file}#L{line}",
+		},
+		{
+			desc:       "package",
+			render:     renderPackage("go.abhg.dev/doc2go/internal/html"),
+			wantImport: "go.abhg.dev/doc2go git https://github.com/abhinav/doc2go",
+			wantSource: "go.abhg.dev/doc2go https://github.com/abhinav/doc2go " +
+				"https://github.com/abhinav/doc2go/tree/HEAD{/dir} " +
+				"https://github.com/abhinav/doc2go/blob/HEAD{/dir}/{file}#L{line}",
+		},
+		{
+			desc: "directory",
+			render: func(r *Renderer, w io.Writer) error {
+				return errtrace.Wrap(r.RenderPackageIndex(w, &PackageIndex{Path: "go.abhg.dev/doc2go/internal"}))
+			},
+			wantImport: "go.abhg.dev/doc2go git https://github.com/abhinav/doc2go",
+			wantSource: "go.abhg.dev/doc2go https://github.com/abhinav/doc2go " +
+				"https://github.com/abhinav/doc2go/tree/HEAD{/dir} " +
+				"https://github.com/abhinav/doc2go/blob/HEAD{/dir}/{file}#L{line}",
+		},
+		{
+			desc:       "module in subdirectory",
+			render:     renderPackage("go.abhg.dev/sub/foo"),
+			wantImport: "go.abhg.dev/sub git https://github.com/abhinav/mono go/sub",
+			wantSource: "go.abhg.dev/sub https://github.com/abhinav/mono " +
+				"https://github.com/abhinav/mono/tree/HEAD/go/sub{/dir} " +
+				"https://github.com/abhinav/mono/blob/HEAD/go/sub{/dir}/{file}#L{line}",
+		},
+		{
+			desc:       "nested module/unknown host",
+			render:     renderPackage("go.abhg.dev/doc2go/tools/lint"),
+			wantImport: "go.abhg.dev/doc2go/tools hg https://hg.example.com/tools",
+		},
+		{
+			desc:   "outside modules",
+			render: renderPackage("go.abhg.dev/doc2gox"),
+		},
+		{
+			desc: "directory above modules",
+			render: func(r *Renderer, w io.Writer) error {
+				return errtrace.Wrap(r.RenderPackageIndex(w, &PackageIndex{Path: "go.abhg.dev"}))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			renderer := Renderer{
+				Highlighter: _fakeHighlighter,
+				GoImports:   goImports,
+			}
+
+			var buff bytes.Buffer
+			require.NoError(t, tt.render(&renderer, &buff))
+
+			doc, err := html.Parse(bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err, "invalid HTML:\n%s", buff.Bytes())
+
+			// Tags must not leave stray whitespace in <head>.
+			head, _, ok := strings.Cut(buff.String(), "</head>")
+			require.True(t, ok, "no </head>:\n%s", buff.Bytes())
+			head = strings.TrimRight(head, " ")
+			assert.True(t, strings.HasSuffix(head, "\n"), "</head> must start a line:\n%s", head)
+			for line := range strings.Lines(head) {
+				assert.NotEmpty(t, strings.TrimSpace(line), "blank line in <head>:\n%s", head)
+			}
+
+			imports := querySelectorAll(doc, `head meta[name="go-import"]`)
+			sources := querySelectorAll(doc, `head meta[name="go-source"]`)
+			if tt.wantImport == "" {
+				assert.Empty(t, imports)
+				assert.Empty(t, sources)
+				return
+			}
+
+			require.Len(t, imports, 1)
+			assert.Equal(t, tt.wantImport, attr(imports[0], "content"))
+			if tt.wantSource == "" {
+				assert.Empty(t, sources)
+				return
+			}
+
+			require.Len(t, sources, 1)
+			assert.Equal(t, tt.wantSource, attr(sources[0], "content"))
+		})
+	}
+}
+
+func TestSourceTemplates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give     string
+		subdir   string
+		wantHome string
+		wantDir  string
+		wantFile string
+		wantOK   bool
+	}{
+		{
+			give:     "https://github.com/abhinav/doc2go.git",
+			wantHome: "https://github.com/abhinav/doc2go",
+			wantDir:  "https://github.com/abhinav/doc2go/tree/HEAD{/dir}",
+			wantFile: "https://github.com/abhinav/doc2go/blob/HEAD{/dir}/{file}#L{line}",
+			wantOK:   true,
+		},
+		{
+			give:     "https://gitlab.com/foo/bar/",
+			wantHome: "https://gitlab.com/foo/bar",
+			wantDir:  "https://gitlab.com/foo/bar/-/tree/HEAD{/dir}",
+			wantFile: "https://gitlab.com/foo/bar/-/blob/HEAD{/dir}/{file}#L{line}",
+			wantOK:   true,
+		},
+		{
+			give:     "https://bitbucket.org/foo/bar",
+			wantHome: "https://bitbucket.org/foo/bar",
+			wantDir:  "https://bitbucket.org/foo/bar/src/HEAD{/dir}",
+			wantFile: "https://bitbucket.org/foo/bar/src/HEAD{/dir}/{file}#lines-{line}",
+			wantOK:   true,
+		},
+		{
+			give:     "https://github.com/abhinav/mono",
+			subdir:   "go/sub",
+			wantHome: "https://github.com/abhinav/mono",
+			wantDir:  "https://github.com/abhinav/mono/tree/HEAD/go/sub{/dir}",
+			wantFile: "https://github.com/abhinav/mono/blob/HEAD/go/sub{/dir}/{file}#L{line}",
+			wantOK:   true,
+		},
+		{
+			give:     "https://gitlab.com/foo/bar",
+			subdir:   "sub",
+			wantHome: "https://gitlab.com/foo/bar",
+			wantDir:  "https://gitlab.com/foo/bar/-/tree/HEAD/sub{/dir}",
+			wantFile: "https://gitlab.com/foo/bar/-/blob/HEAD/sub{/dir}/{file}#L{line}",
+			wantOK:   true,
+		},
+		{
+			give:     "https://bitbucket.org/foo/bar",
+			subdir:   "sub",
+			wantHome: "https://bitbucket.org/foo/bar",
+			wantDir:  "https://bitbucket.org/foo/bar/src/HEAD/sub{/dir}",
+			wantFile: "https://bitbucket.org/foo/bar/src/HEAD/sub{/dir}/{file}#lines-{line}",
+			wantOK:   true,
+		},
+		{give: "https://git.example.com/foo/bar"},
+		{give: "://"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give+"#"+tt.subdir, func(t *testing.T) {
+			t.Parallel()
+
+			home, dir, file, ok := sourceTemplates(tt.give, tt.subdir)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantHome, home)
+			assert.Equal(t, tt.wantDir, dir)
+			assert.Equal(t, tt.wantFile, file)
+		})
+	}
+}
@@ -9,22 +9,42 @@ import (
 	"html/template"
 	"io"
 	"io/fs"
-	"os"
+	"net/url"
 	"path"
 	"path/filepath"
 	"strings"
 	ttemplate "text/template"
 
 	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/frontmatter"
 	"go.abhg.dev/doc2go/internal/godoc"
 	"go.abhg.dev/doc2go/internal/highlight"
 	"go.abhg.dev/doc2go/internal/relative"
+	"go.abhg.dev/doc2go/internal/siteurl"
 )
 
 // StaticDir is the name of the directory in the output
 // where static files are stored.
 const StaticDir = "_"
 
+const (
+	// VersionsManifest is the name of the JSON file
+	// in the output directory that lists the sites
+	// generated into its subdirectories.
+	VersionsManifest = "versions.json"
+
+	// PagesManifest is the name of the JSON file
+	// inside the StaticDir of a site generated into a subdirectory
+	// that lists the pages in that site.
+	PagesManifest = "pages.json"
+
+	// SymbolIndex is the name of the JSON file
+	// inside the StaticDir of a site
+	// that lists packages and their symbols for the search box
+	// enabled by [Renderer.SymbolSearch].
+	SymbolIndex = "symbols.json"
+)
+
 var (
 	//go:embed tmpl/*.html
 	_tmplFS embed.FS
@@ -32,37 +52,10 @@ var (
 	//go:embed static/**
 	_staticFS embed.FS
 
-	// Trick borrowed from pkgsite:
-	// Unusable function references at parse time,
-	// and then Clone and replace at render time.
-	// This way, template validity is still
-	// verified at init.
-	_packageTmpl = template.Must(
-		template.New("package.html").
-			Funcs((*render)(nil).FuncMap()).
-			ParseFS(_tmplFS,
-				"tmpl/package.html", "tmpl/layout.html", "tmpl/subpackages.html", "tmpl/pagefind.html"),
-	)
-
-	_commandTmpl = template.Must(
-		template.New("command.html").
-			Funcs((*render)(nil).FuncMap()).
-			ParseFS(_tmplFS,
-				"tmpl/command.html", "tmpl/layout.html", "tmpl/subpackages.html", "tmpl/pagefind.html"),
-	)
-
-	_packageIndexTmpl = template.Must(
-		template.New("directory.html").
+	_redirectTmpl = template.Must(
+		template.New("redirect.html").
 			Funcs((*render)(nil).FuncMap()).
-			ParseFS(_tmplFS,
-				"tmpl/directory.html", "tmpl/layout.html", "tmpl/subpackages.html", "tmpl/pagefind.html"),
-	)
-
-	_siteIndexTmpl = template.Must(
-		template.New("siteindex.html").
-			Funcs((*render)(nil).FuncMap()).
-			ParseFS(_tmplFS,
-				"tmpl/siteindex.html", "tmpl/layout.html", "tmpl/pagefind.html"),
+			ParseFS(_tmplFS, "tmpl/redirect.html"),
 	)
 )
 
@@ -101,98 +94,167 @@ type Renderer struct {
 	// Pagefind specifies whether we have enabled client-side search with
 	// pagefind.
 	Pagefind bool
+
+	// SymbolSearch specifies whether pages include a search box
+	// for packages and symbols listed in the [SymbolIndex].
+	//
+	// Unlike Pagefind, this is supported in embedded mode.
+	SymbolSearch bool
+
+	// BaseURL is the absolute URL that the output directory is served from,
+	// if known.
+	//
+	// If set, pages include a canonical URL
+	// and OpenGraph metadata for link previews.
+	BaseURL *url.URL
+
+	// GoImports lists modules whose pages should include
+	// go-import and go-source meta tags.
+	GoImports []*GoImport
+
+	// Templates used to render pages.
+	//
+	// Defaults to the built-in templates.
+	Templates *Templates
+
+	// DarkMode specifies whether pages switch to a dark palette
+	// when the reader prefers a dark color scheme.
+	//
+	// Pair this with a Highlighter that has a dark style.
+	DarkMode bool
+
+	// ExtraStatic holds additional files to write into StaticDir
+	// alongside the built-in files.
+	// Keys are /-separated paths relative to StaticDir.
+	//
+	// These are not written in embedded mode.
+	ExtraStatic map[string][]byte
+
+	// ExtraCSS and ExtraJS are /-separated paths relative to StaticDir
+	// of stylesheets and scripts that every page should include
+	// after the built-in ones, in order.
+	//
+	// These are not included in embedded mode.
+	ExtraCSS, ExtraJS []string
+}
+
+func (r *Renderer) templates() *Templates {
+	if r.Templates != nil {
+		return r.Templates
+	}
+	return _defaultTemplates
 }
 
 func (r *Renderer) templateName() string {
 	if r.Embedded {
-		return "Body"
+		return "Embedded"
 	}
 	return "Page"
 }
 
-// WriteStatic dumps the contents of static/ into the given directory.
+// _symbolSearchFiles are the files inside static/
+// needed by the symbol search box.
+var _symbolSearchFiles = []string{"css/search.css", "js/search.js"}
+
+// StaticFiles returns the contents of static/ in-memory.
+// Keys of the returned map are /-separated paths
+// relative to [StaticDir].
 //
-// This is a no-op if the renderer is running in embedded mode.
-func (r *Renderer) WriteStatic(dir string) error {
+// In embedded mode, this returns only the files needed by SymbolSearch,
+// or an empty map if it's disabled.
+func (r *Renderer) StaticFiles() (map[string][]byte, error) {
+	files := make(map[string][]byte)
 	if r.Embedded {
-		return nil
+		if !r.SymbolSearch {
+			return files, nil
+		}
+
+		for _, name := range _symbolSearchFiles {
+			bs, err := fs.ReadFile(_staticFS, path.Join("static", name))
+			if err != nil {
+				return nil, errtrace.Wrap(err)
+			}
+			files[name] = bs
+		}
+		return files, nil
 	}
 
-	dir = filepath.Join(dir, StaticDir)
 	static, err := fs.Sub(_staticFS, "static")
 	if err != nil {
-		return errtrace.Wrap(err)
+		return nil, errtrace.Wrap(err)
 	}
-	return errtrace.Wrap(fs.WalkDir(static, ".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil || path == "." {
+	err = fs.WalkDir(static, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
 			return errtrace.Wrap(err)
 		}
 
-		outPath := filepath.Join(dir, path)
-		if d.IsDir() {
-			return errtrace.Wrap(os.MkdirAll(outPath, 0o1755))
-		}
-
 		bs, err := fs.ReadFile(static, path)
 		if err != nil {
 			return errtrace.Wrap(err)
 		}
 
-		// FIXME: This is a hack. That we need to append to main.css
-		// should be represented elsewhere.
-		if path == "css/main.css" {
-			buff := bytes.NewBuffer(bs)
-			buff.WriteString("\n")
-			if err := r.Highlighter.WriteCSS(buff); err != nil {
-				return errtrace.Wrap(err)
-			}
-			bs = buff.Bytes()
+		files[path] = bs
+		return nil
+	})
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	var highlightCSS bytes.Buffer
+	if err := r.Highlighter.WriteCSS(&highlightCSS); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	files[_highlightCSS] = highlightCSS.Bytes()
+
+	for name, bs := range r.ExtraStatic {
+		if _, ok := files[name]; ok {
+			return nil, errtrace.Wrap(fmt.Errorf("static file %q conflicts with a built-in file", name))
 		}
+		files[name] = bs
+	}
 
-		return errtrace.Wrap(os.WriteFile(outPath, bs, 0o644))
-	}))
+	return files, nil
 }
 
-type frontmatterPackageData struct {
-	Name     string
-	Synopsis string
-}
+const (
+	// _mainCSS is the path to the main stylesheet inside static/.
+	_mainCSS = "css/main.css"
 
-type frontmatterData struct {
-	Path        string
-	Basename    string
-	NumChildren int
-	Package     frontmatterPackageData
-}
+	// _darkCSS is the path inside static/ to the stylesheet
+	// that overrides colors in _mainCSS for DarkMode.
+	_darkCSS = "css/dark.css"
 
-func (d frontmatterData) Name() string {
-	if n := d.Package.Name; n != "" && n != "main" {
-		return n
-	}
-	if d.Basename != "" {
-		return d.Basename
-	}
-	return ""
-}
+	// _highlightCSS is the path inside StaticDir
+	// to the stylesheet for syntax highlighting.
+	// It's generated from the Highlighter.
+	_highlightCSS = "css/highlight.css"
+)
 
-func (r *Renderer) renderFrontmatter(w io.Writer, d frontmatterData) error {
-	if r.FrontMatter == nil {
-		return nil
+// inlineCSS returns the contents of the main stylesheet
+// followed by the stylesheet for syntax highlighting
+// for pages that include them inline.
+func (r *Renderer) inlineCSS() ([]byte, error) {
+	bs, err := fs.ReadFile(_staticFS, path.Join("static", _mainCSS))
+	if err != nil {
+		return nil, errtrace.Wrap(err)
 	}
 
-	var buff bytes.Buffer
-	if err := r.FrontMatter.Execute(&buff, d); err != nil {
-		return errtrace.Wrap(err)
-	}
+	buff := bytes.NewBuffer(bs)
+	buff.WriteString("\n")
+	if r.DarkMode {
+		dark, err := fs.ReadFile(_staticFS, path.Join("static", _darkCSS))
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
 
-	bs := bytes.TrimSpace(buff.Bytes())
-	if len(bs) == 0 {
-		return nil
+		buff.WriteString("@media (prefers-color-scheme: dark) {\n")
+		buff.Write(dark)
+		buff.WriteString("}\n")
 	}
-	bs = append(bs, '\n', '\n')
-
-	_, err := w.Write(bs)
-	return errtrace.Wrap(err)
+	if err := r.Highlighter.WriteCSS(buff); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	return buff.Bytes(), nil
 }
 
 // Breadcrumb holds information about parents of a page
@@ -217,6 +279,15 @@ type PackageInfo struct {
 	SubDirDepth int
 	PkgVersion  string
 
+	// SubDir is the subdirectory of the output directory
+	// that the page is generated into, if any.
+	// Pages inside a SubDir get a version switcher.
+	SubDir string
+
+	// CanonicalSubDir is the subdirectory of the output directory
+	// holding the preferred version of this page, if it's not SubDir.
+	CanonicalSubDir string
+
 	// DocPrinter specifies how to render godoc comments.
 	DocPrinter DocPrinter
 }
@@ -235,11 +306,11 @@ func (b *PackageInfo) IsInternal() bool {
 // RenderPackage renders the documentation for a single Go package.
 // It does not include subpackage information.
 func (r *Renderer) RenderPackage(w io.Writer, info *PackageInfo) error {
-	err := r.renderFrontmatter(w, frontmatterData{
+	err := frontmatter.Render(w, r.FrontMatter, frontmatter.Data{
 		Path:        info.ImportPath,
 		Basename:    info.Basename(),
 		NumChildren: info.NumChildren,
-		Package: frontmatterPackageData{
+		Package: frontmatter.PackageData{
 			Name:     info.Name,
 			Synopsis: info.Synopsis,
 		},
@@ -255,14 +326,20 @@ func (r *Renderer) RenderPackage(w io.Writer, info *PackageInfo) error {
 		Highlighter:           r.Highlighter,
 		NormalizeRelativePath: r.NormalizeRelativePath,
 		SubDirDepth:           info.SubDirDepth,
+		SubDir:                info.SubDir,
+		CanonicalSubDir:       info.CanonicalSubDir,
 		Pagefind:              r.Pagefind,
+		SymbolSearch:          r.SymbolSearch,
+		DarkMode:              r.DarkMode,
+		ExtraCSS:              r.ExtraCSS,
+		ExtraJS:               r.ExtraJS,
+		BaseURL:               r.BaseURL,
+		GoImports:             r.GoImports,
 	}
 
-	var tmpl *template.Template
+	tmpl := r.templates().pkg
 	if info.BinName != "" {
-		tmpl = _commandTmpl
-	} else {
-		tmpl = _packageTmpl
+		tmpl = r.templates().cmd
 	}
 
 	return errtrace.Wrap(template.Must(tmpl.Clone()).
@@ -282,6 +359,15 @@ type PackageIndex struct {
 	// 1 means it's being written to a subdirectory of the output directory.
 	SubDirDepth int
 
+	// SubDir is the subdirectory of the output directory
+	// that the page is generated into, if any.
+	// Pages inside a SubDir get a version switcher.
+	SubDir string
+
+	// CanonicalSubDir is the subdirectory of the output directory
+	// holding the preferred version of this page, if it's not SubDir.
+	CanonicalSubDir string
+
 	NumChildren int
 	Subpackages []Subpackage
 	Breadcrumbs []Breadcrumb
@@ -319,27 +405,41 @@ type Subpackage struct {
 	Synopsis string
 }
 
+// IsInternal reports whether this subpackage should be considered
+// internal to the package it's listed under.
+func (s Subpackage) IsInternal() bool {
+	return isInternal(s.RelativePath)
+}
+
 // RenderPackageIndex renders the list of descendants for a package
 // as HTML.
 func (r *Renderer) RenderPackageIndex(w io.Writer, pidx *PackageIndex) error {
-	fmdata := frontmatterData{
+	fmdata := frontmatter.Data{
 		Path:        pidx.Path,
 		Basename:    pidx.Basename(),
 		NumChildren: pidx.NumChildren,
 	}
-	if err := r.renderFrontmatter(w, fmdata); err != nil {
+	if err := frontmatter.Render(w, r.FrontMatter, fmdata); err != nil {
 		return errtrace.Wrap(err)
 	}
 	render := render{
 		Home:                  r.Home,
 		Path:                  pidx.Path,
 		SubDirDepth:           pidx.SubDirDepth,
+		SubDir:                pidx.SubDir,
+		CanonicalSubDir:       pidx.CanonicalSubDir,
 		Internal:              r.Internal,
 		Highlighter:           r.Highlighter,
 		NormalizeRelativePath: r.NormalizeRelativePath,
 		Pagefind:              r.Pagefind,
+		SymbolSearch:          r.SymbolSearch,
+		DarkMode:              r.DarkMode,
+		ExtraCSS:              r.ExtraCSS,
+		ExtraJS:               r.ExtraJS,
+		BaseURL:               r.BaseURL,
+		GoImports:             r.GoImports,
 	}
-	return errtrace.Wrap(template.Must(_packageIndexTmpl.Clone()).
+	return errtrace.Wrap(template.Must(r.templates().pkgIndex.Clone()).
 		Funcs(render.FuncMap()).
 		ExecuteTemplate(w, r.templateName(), pidx))
 }
@@ -351,7 +451,24 @@ type SiteIndex struct {
 	// Path will be empty unless -home was used.
 	Path string
 
-	Sites []string
+	// Releases are sites named after stable semantic versions,
+	// newest first.
+	Releases []string
+
+	// PreReleases are sites named after pre-release semantic versions,
+	// newest first.
+	PreReleases []string
+
+	// Others are sites whose names aren't semantic versions,
+	// sorted by name.
+	Others []string
+
+	// Latest is the newest of Releases, if any.
+	Latest string
+
+	// LatestAlias is the name of the directory
+	// that redirects to Latest, if one was generated.
+	LatestAlias string
 }
 
 // RenderSiteIndex renders the list of sub-sites as HTML.
@@ -368,19 +485,58 @@ func (r *Renderer) RenderSiteIndex(w io.Writer, sidx *SiteIndex) error {
 		Home:                  r.Home,
 		Path:                  sidx.Path,
 		NormalizeRelativePath: r.NormalizeRelativePath,
+		DarkMode:              r.DarkMode,
+		ExtraCSS:              r.ExtraCSS,
+		ExtraJS:               r.ExtraJS,
+		BaseURL:               r.BaseURL,
 	}
-	return errtrace.Wrap(template.Must(_siteIndexTmpl.Clone()).
+	return errtrace.Wrap(template.Must(r.templates().siteIndex.Clone()).
 		Funcs(render.FuncMap()).
 		ExecuteTemplate(w, r.templateName(), data))
 }
 
+// Redirect is a page that sends readers to another page.
+type Redirect struct {
+	// From is the path to the page from the root of the output.
+	From string
+
+	// To is the path to the destination from the root of the output.
+	To string
+}
+
+// RenderRedirect renders a page that redirects to another page.
+//
+// The page is a complete HTML page even in embedded mode.
+func (r *Renderer) RenderRedirect(w io.Writer, redirect *Redirect) error {
+	render := render{
+		Path:                  redirect.From,
+		NormalizeRelativePath: r.NormalizeRelativePath,
+	}
+	return errtrace.Wrap(template.Must(_redirectTmpl.Clone()).
+		Funcs(render.FuncMap()).
+		ExecuteTemplate(w, "Redirect", redirect))
+}
+
 type render struct {
 	Home string
 	Path string
 
-	SubDirDepth int
-	Internal    bool
-	Pagefind    bool
+	SubDirDepth     int
+	SubDir          string
+	CanonicalSubDir string
+	Internal        bool
+	Pagefind        bool
+	SymbolSearch    bool
+	DarkMode        bool
+
+	// ExtraCSS and ExtraJS are paths relative to StaticDir
+	// of stylesheets and scripts to include after the built-in ones.
+	ExtraCSS, ExtraJS []string
+
+	// BaseURL is the URL the output directory is served from, if any.
+	BaseURL *url.URL
+
+	GoImports []*GoImport
 
 	// DocPrinter converts Go comment.Doc objects into HTML.
 	DocPrinter DocPrinter
@@ -407,6 +563,15 @@ func (r *render) FuncMap() template.FuncMap {
 			// Extra space because this will be next to a tag.
 			return " data-pagefind-ignore"
 		},
+		"symbolSearch": func() bool { return r.SymbolSearch },
+		// symbolIndex:
+		// The relative path to the SymbolIndex of this site.
+		"symbolIndex": func() string {
+			return r.siteStatic(SymbolIndex)
+		},
+		"darkMode":   func() bool { return r.DarkMode },
+		"extraCSS":   func() []string { return r.ExtraCSS },
+		"extraJS":    func() []string { return r.ExtraJS },
 		"static":     r.static,
 		"siteStatic": r.siteStatic,
 		// relativevPath:
@@ -432,6 +597,15 @@ func (r *render) FuncMap() template.FuncMap {
 			return r.relativePath(r.Home)
 		},
 		"filterSubpackages": r.filterSubpackages,
+		"versionSwitcher":   r.versionSwitcher,
+		// canonicalURL:
+		// The absolute URL of the preferred version of this page,
+		// or an empty string if the base URL isn't known.
+		"canonicalURL": r.canonicalURL,
+		// goImport:
+		// Contents of the go-import and go-source meta tags
+		// for the current page, if it's part of a known module.
+		"goImport": r.goImport,
 		// normalizeRelativePath:
 		// Normalizes a relative path to have a '/' or not
 		// depending on the rel-link-style flag.
@@ -488,6 +662,73 @@ func (r *render) siteStatic(p string) string {
 	return r.relativePathFile(path.Join(elem...))
 }
 
+// versionSwitcher holds the information needed
+// to switch to the current page in other versions of the site.
+type versionSwitcher struct {
+	// Version is the name of the current version.
+	Version string
+
+	// Manifest is the relative path to the VersionsManifest.
+	Manifest string
+
+	// Page is the path to the current page from the root of the site.
+	Page string
+
+	// PageHref and RootHref are links to the current page
+	// and the root of the site,
+	// relative to the root of the site.
+	PageHref string
+	RootHref string
+}
+
+// Returns information for the version switcher,
+// or nil if the site isn't generated into a subdirectory.
+func (r *render) versionSwitcher() *versionSwitcher {
+	if r.SubDir == "" {
+		return nil
+	}
+
+	elem := []string{r.Home}
+	for i := 0; i < r.SubDirDepth; i++ {
+		elem = append(elem, "..")
+	}
+	elem = append(elem, VersionsManifest)
+
+	normalize := func(p string) string {
+		if p == "" {
+			p = "."
+		}
+		if f := r.NormalizeRelativePath; f != nil {
+			p = f(p)
+		}
+		return p
+	}
+
+	page := relative.Path(r.Home, r.Path)
+	return &versionSwitcher{
+		Version:  r.SubDir,
+		Manifest: r.relativePathFile(path.Join(elem...)),
+		Page:     page,
+		PageHref: normalize(page),
+		RootHref: normalize(""),
+	}
+}
+
+// Returns the absolute URL of the preferred version of the current page,
+// or an empty string if we don't know where the site is published.
+func (r *render) canonicalURL() string {
+	if r.BaseURL == nil {
+		return ""
+	}
+
+	site := r.SubDir
+	if r.CanonicalSubDir != "" {
+		site = r.CanonicalSubDir
+	}
+	page := path.Join(site, relative.Path(r.Home, r.Path))
+	return siteurl.Page(r.BaseURL, page, r.NormalizeRelativePath)
+}
+
 func (r *render) code(code *highlight.Code) template.HTML {
 	return template.HTML(r.Highlighter.Highlight(code))
 }
@@ -507,7 +748,7 @@ func (r *render) filterSubpackages(pkgs []Subpackage) []Subpackage {
 
 	filtered := make([]Subpackage, 0, len(pkgs))
 	for _, pkg := range pkgs {
-		if !isInternal(pkg.RelativePath) {
+		if !pkg.IsInternal() {
 			filtered = append(filtered, pkg)
 		}
 	}
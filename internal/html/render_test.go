@@ -5,7 +5,10 @@ import (
 	"go/doc/comment"
 	"io"
 	"io/fs"
-	"os"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -23,53 +26,211 @@ import (
 
 var _fakeHighlighter = &fixedHighlighter{code: "foo"}
 
-func TestRenderer_WriteStatic(t *testing.T) {
+func TestRenderer_StaticFiles(t *testing.T) {
 	t.Parallel()
 
-	dir := t.TempDir()
-	err := (&Renderer{
+	files, err := (&Renderer{
 		Highlighter: _fakeHighlighter,
-	}).WriteStatic(dir)
+	}).StaticFiles()
 	require.NoError(t, err)
 
 	var want []string
-	err = fs.WalkDir(_staticFS, "static", func(path string, _ fs.DirEntry, err error) error {
+	err = fs.WalkDir(_staticFS, "static", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return errtrace.Wrap(err)
 		}
-		want = append(want, strings.TrimPrefix(path, "static"))
-		return nil
-	})
-	require.NoError(t, err)
-	sort.Strings(want)
-
-	var got []string
-	err = fs.WalkDir(os.DirFS(dir), "_", func(path string, _ fs.DirEntry, err error) error {
-		if err != nil {
-			return errtrace.Wrap(err)
+		if !d.IsDir() {
+			want = append(want, strings.TrimPrefix(path, "static/"))
 		}
-		got = append(got, strings.TrimPrefix(path, "_"))
 		return nil
 	})
 	require.NoError(t, err)
-	sort.Strings(got)
+	want = append(want, _highlightCSS) // generated
+	sort.Strings(want)
 
+	got := slices.Sorted(maps.Keys(files))
 	assert.Equal(t, want, got)
+
+	for _, name := range want {
+		if name == _highlightCSS {
+			continue
+		}
+		bs, err := fs.ReadFile(_staticFS, path.Join("static", name))
+		require.NoError(t, err)
+		assert.Equal(t, bs, files[name], "contents of %v", name)
+	}
 }
 
-func TestRenderer_WriteStatic_embedded(t *testing.T) {
+func TestRenderer_StaticFiles_embedded(t *testing.T) {
 	t.Parallel()
 
-	dir := t.TempDir()
-	err := (&Renderer{
+	files, err := (&Renderer{
 		Highlighter: _fakeHighlighter,
 		Embedded:    true,
-	}).WriteStatic(dir)
+	}).StaticFiles()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestRenderer_StaticFiles_embeddedSymbolSearch(t *testing.T) {
+	t.Parallel()
+
+	files, err := (&Renderer{
+		Highlighter:  _fakeHighlighter,
+		Embedded:     true,
+		SymbolSearch: true,
+	}).StaticFiles()
 	require.NoError(t, err)
+	assert.ElementsMatch(t, _symbolSearchFiles, slices.Collect(maps.Keys(files)))
+}
 
-	ents, err := os.ReadDir(dir)
+func TestRenderer_StaticFiles_extra(t *testing.T) {
+	t.Parallel()
+
+	files, err := (&Renderer{
+		Highlighter: &fixedHighlighter{css: ".chroma {}"},
+		ExtraStatic: map[string][]byte{
+			"extra/brand.css":       []byte("body {}"),
+			"extra/fonts/inter.ttf": []byte("font"),
+		},
+	}).StaticFiles()
 	require.NoError(t, err)
-	assert.Empty(t, ents)
+
+	assert.Equal(t, ".chroma {}", string(files["css/highlight.css"]))
+	assert.NotContains(t, string(files["css/main.css"]), ".chroma",
+		"highlighting must not be appended to main.css")
+	assert.Equal(t, "body {}", string(files["extra/brand.css"]))
+	assert.Equal(t, "font", string(files["extra/fonts/inter.ttf"]))
+
+	t.Run("conflict", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := (&Renderer{
+			Highlighter: _fakeHighlighter,
+			ExtraStatic: map[string][]byte{"css/main.css": nil},
+		}).StaticFiles()
+		assert.ErrorContains(t, err, `"css/main.css" conflicts with a built-in file`)
+	})
+}
+
+func TestRenderer_extraAssets(t *testing.T) {
+	t.Parallel()
+
+	renderer := Renderer{
+		Highlighter: _fakeHighlighter,
+		ExtraCSS:    []string{"extra/brand.css", "extra/fonts.css"},
+		ExtraJS:     []string{"extra/analytics.js"},
+	}
+
+	tests := []struct {
+		desc   string
+		render func(io.Writer) error
+		prefix string
+	}{
+		{
+			desc: "package",
+			render: func(w io.Writer) error {
+				return errtrace.Wrap(renderer.RenderPackage(w, &PackageInfo{
+					Package:    &godoc.Package{Name: "foo", ImportPath: "example.com/foo"},
+					DocPrinter: new(CommentDocPrinter),
+				}))
+			},
+			prefix: "../../_/",
+		},
+		{
+			desc: "directory",
+			render: func(w io.Writer) error {
+				return errtrace.Wrap(renderer.RenderPackageIndex(w, &PackageIndex{Path: "example.com"}))
+			},
+			prefix: "../_/",
+		},
+		{
+			desc: "site index",
+			render: func(w io.Writer) error {
+				return errtrace.Wrap(renderer.RenderSiteIndex(w, &SiteIndex{}))
+			},
+			prefix: "_/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var buff bytes.Buffer
+			require.NoError(t, tt.render(&buff))
+
+			doc, err := html.Parse(bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err, "invalid HTML:\n%s", buff.Bytes())
+
+			var styles, scripts []string
+			for _, n := range querySelectorAll(doc, `link[rel="stylesheet"]`) {
+				styles = append(styles, strings.TrimPrefix(attr(n, "href"), tt.prefix))
+			}
+			for _, n := range querySelectorAll(doc, "script") {
+				scripts = append(scripts, strings.TrimPrefix(attr(n, "src"), tt.prefix))
+			}
+
+			assert.Equal(t, []string{
+				"css/main.css",
+				"css/highlight.css",
+				"extra/brand.css",
+				"extra/fonts.css",
+			}, styles)
+			if assert.NotEmpty(t, scripts) {
+				assert.Equal(t, "extra/analytics.js", scripts[len(scripts)-1])
+			}
+		})
+	}
+}
+
+func TestRenderer_darkMode(t *testing.T) {
+	t.Parallel()
+
+	render := func(t *testing.T, renderer *Renderer) *html.Node {
+		var buff bytes.Buffer
+		require.NoError(t, renderer.RenderPackage(&buff, &PackageInfo{
+			Package:    &godoc.Package{Name: "foo", ImportPath: "foo"},
+			DocPrinter: new(CommentDocPrinter),
+		}))
+
+		doc, err := html.Parse(bytes.NewReader(buff.Bytes()))
+		require.NoError(t, err, "invalid HTML:\n%s", buff.Bytes())
+		return doc
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		doc := render(t, &Renderer{Highlighter: _fakeHighlighter})
+		assert.Nil(t, querySelector(doc, `meta[name="color-scheme"]`))
+		assert.Nil(t, querySelector(doc, `link[href$="css/dark.css"]`))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+
+		doc := render(t, &Renderer{Highlighter: _fakeHighlighter, DarkMode: true})
+
+		meta := querySelector(doc, `meta[name="color-scheme"]`)
+		require.NotNil(t, meta)
+		assert.Equal(t, "light dark", attr(meta, "content"))
+
+		link := querySelector(doc, `link[href="../_/css/dark.css"]`)
+		require.NotNil(t, link)
+		assert.Equal(t, "(prefers-color-scheme: dark)", attr(link, "media"))
+	})
+
+	t.Run("single page", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		require.NoError(t, (&Renderer{
+			Highlighter: _fakeHighlighter,
+			DarkMode:    true,
+		}).RenderSinglePage(&buff, &SinglePage{Title: "foo"}))
+		assert.Contains(t, buff.String(), "@media (prefers-color-scheme: dark) {")
+	})
 }
 
 func TestRenderer_RenderPackage_title(t *testing.T) {
@@ -265,12 +426,13 @@ func TestRenderPackage_index(t *testing.T) {
 		}
 		assert.Equal(t, tt.want, items)
 
+		static, err := renderer.StaticFiles()
+		require.NoError(t, err)
 		for _, link := range querySelectorAll(doc, "link") {
 			href := attr(link, "href")
 			if assert.NotEmpty(t, href) {
-				staticPath := "static/" + strings.TrimPrefix(href, "_/")
-				_, err := _staticFS.ReadFile(staticPath)
-				require.NoError(t, err)
+				_, ok := static[strings.TrimPrefix(href, "_/")]
+				assert.True(t, ok, "link to unknown static file %q", href)
 			}
 		}
 	}
@@ -848,6 +1010,486 @@ func TestNavbarRightLinks(t *testing.T) {
 	})
 }
 
+func TestVersionSwitcher(t *testing.T) {
+	t.Parallel()
+
+	type switcher struct {
+		Manifest string
+		Page     string
+		PageHref string
+		RootHref string
+		Options  []string
+	}
+
+	tests := []struct {
+		desc     string
+		renderer Renderer
+		info     PackageInfo
+		want     *switcher // nil if there should be no switcher
+	}{
+		{
+			desc: "no subdir",
+			info: PackageInfo{
+				Package: &godoc.Package{Name: "foo", ImportPath: "example.com/foo"},
+			},
+		},
+		{
+			desc: "subdir",
+			info: PackageInfo{
+				Package:     &godoc.Package{Name: "foo", ImportPath: "example.com/foo"},
+				SubDirDepth: 1,
+				SubDir:      "v1.2.0",
+			},
+			want: &switcher{
+				Manifest: "../../../versions.json",
+				Page:     "example.com/foo",
+				PageHref: "example.com/foo",
+				RootHref: ".",
+				Options:  []string{"v1.2.0"},
+			},
+		},
+		{
+			desc: "home",
+			renderer: Renderer{
+				Home: "example.com",
+				NormalizeRelativePath: func(s string) string {
+					return strings.TrimSuffix(s, "/") + "/"
+				},
+			},
+			info: PackageInfo{
+				Package:     &godoc.Package{Name: "foo", ImportPath: "example.com/foo"},
+				SubDirDepth: 1,
+				SubDir:      "main",
+			},
+			want: &switcher{
+				Manifest: "../../versions.json",
+				Page:     "foo",
+				PageHref: "foo/",
+				RootHref: "./",
+				Options:  []string{"main"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			renderer := tt.renderer
+			renderer.Highlighter = _fakeHighlighter
+			info := tt.info
+			info.DocPrinter = new(CommentDocPrinter)
+			info.Breadcrumbs = []Breadcrumb{{Text: info.ImportPath, Path: info.ImportPath}}
+
+			var buff bytes.Buffer
+			require.NoError(t, renderer.RenderPackage(&buff, &info))
+
+			doc, err := html.Parse(bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err, "invalid HTML:\n%s", buff.Bytes())
+
+			selects := querySelectorAll(doc, "nav select#version-switcher")
+			var scripts []string
+			for _, s := range querySelectorAll(doc, "script[src]") {
+				scripts = append(scripts, path.Base(attr(s, "src")))
+			}
+
+			if tt.want == nil {
+				assert.Empty(t, selects)
+				assert.NotContains(t, scripts, "versions.js")
+				return
+			}
+
+			require.Len(t, selects, 1)
+			sel := selects[0]
+			got := switcher{
+				Manifest: attr(sel, "data-manifest"),
+				Page:     attr(sel, "data-page"),
+				PageHref: attr(sel, "data-page-href"),
+				RootHref: attr(sel, "data-root-href"),
+			}
+			for _, opt := range querySelectorAll(sel, "option") {
+				got.Options = append(got.Options, text(opt))
+			}
+			assert.Equal(t, *tt.want, got)
+			assert.Contains(t, scripts, "versions.js")
+		})
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	t.Parallel()
+
+	baseURL, err := url.Parse("https://example.com/docs")
+	require.NoError(t, err)
+
+	type page struct {
+		Canonical string
+		Meta      map[string]string
+	}
+
+	tests := []struct {
+		desc     string
+		renderer Renderer
+		render   func(*Renderer, io.Writer) error
+		want     *page // nil if there should be no canonical URL
+	}{
+		{
+			desc: "no base URL",
+			render: func(r *Renderer, w io.Writer) error {
+				return errtrace.Wrap(r.RenderPackage(w, &PackageInfo{
+					Package:    &godoc.Package{Name: "foo", ImportPath: "example.com/foo"},
+					DocPrinter: new(CommentDocPrinter),
+				}))
+			},
+		},
+		{
+			desc:     "package",
+			renderer: Renderer{BaseURL: baseURL},
+			render: func(r *Renderer, w io.Writer) error {
+				return errtrace.Wrap(r.RenderPackage(w, &PackageInfo{
+					Package: &godoc.Package{
+						Name:       "foo",
+						ImportPath: "example.com/foo",
+						Synopsis:   "Package foo does <things>.",
+					},
+					DocPrinter: new(CommentDocPrinter),
+				}))
+			},
+			want: &page{
+				Canonical: "https://example.com/docs/example.com/foo/",
+				Meta: map[string]string{
+					"og:url":              "https://example.com/docs/example.com/foo/",
+					"og:type":             "website",
+					"og:title":            "foo",
+					"og:description":      "Package foo does <things>.",
+					"twitter:card":        "summary",
+					"twitter:title":       "foo",
+					"twitter:description": "Package foo does <things>.",
+				},
+			},
+		},
+		{
+			desc: "command",
+			renderer: Renderer{
+				Home:    "example.com",
+				BaseURL: baseURL,
+				NormalizeRelativePath: func(s string) string {
+					return strings.TrimSuffix(s, "/") + "/index.html"
+				},
+			},
+			render: func(r *Renderer, w io.Writer) error {
+				return errtrace.Wrap(r.RenderPackage(w, &PackageInfo{
+					Package: &godoc.Package{
+						Name:       "main",
+						ImportPath: "example.com/cmd/foo",
+						BinName:    "foo",
+					},
+					DocPrinter: new(CommentDocPrinter),
+				}))
+			},
+			want: &page{
+				Canonical: "https://example.com/docs/cmd/foo/index.html",
+				Meta: map[string]string{
+					"og:url":        "https://example.com/docs/cmd/foo/index.html",
+					"og:type":       "website",
+					"og:title":      "foo",
+					"twitter:card":  "summary",
+					"twitter:title": "foo",
+				},
+			},
+		},
+		{
+			desc:     "subdir",
+			renderer: Renderer{BaseURL: baseURL},
+			render: func(r *Renderer, w io.Writer) error {
+				return errtrace.Wrap(r.RenderPackage(w, &PackageInfo{
+					Package:     &godoc.Package{Name: "foo", ImportPath: "example.com/foo"},
+					DocPrinter:  new(CommentDocPrinter),
+					SubDirDepth: 1,
+					SubDir:      "main",
+				}))
+			},
+			want: &page{
+				Canonical: "https://example.com/docs/main/example.com/foo/",
+				Meta: map[string]string{
+					"og:url":        "https://example.com/docs/main/example.com/foo/",
+					"og:type":       "website",
+					"og:title":      "foo",
+					"twitter:card":  "summary",
+					"twitter:title": "foo",
+				},
+			},
+		},
+		{
+			desc:     "subdir/canonical",
+			renderer: Renderer{BaseURL: baseURL},
+			render: func(r *Renderer, w io.Writer) error {
+				return errtrace.Wrap(r.RenderPackageIndex(w, &PackageIndex{
+					Path:            "example.com",
+					SubDirDepth:     1,
+					SubDir:          "v1.0.0",
+					CanonicalSubDir: "v1.2.0",
+				}))
+			},
+			want: &page{
+				Canonical: "https://example.com/docs/v1.2.0/example.com/",
+				Meta: map[string]string{
+					"og:url":        "https://example.com/docs/v1.2.0/example.com/",
+					"og:type":       "website",
+					"og:title":      "example.com",
+					"twitter:card":  "summary",
+					"twitter:title": "example.com",
+				},
+			},
+		},
+		{
+			desc:     "site index",
+			renderer: Renderer{BaseURL: baseURL},
+			render: func(r *Renderer, w io.Writer) error {
+				return errtrace.Wrap(r.RenderSiteIndex(w, &SiteIndex{Releases: []string{"v1.0.0"}}))
+			},
+			want: &page{
+				Canonical: "https://example.com/docs/",
+				Meta: map[string]string{
+					"og:url":       "https://example.com/docs/",
+					"og:type":      "website",
+					"twitter:card": "summary",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			renderer := tt.renderer
+			renderer.Highlighter = _fakeHighlighter
+
+			var buff bytes.Buffer
+			require.NoError(t, tt.render(&renderer, &buff))
+
+			doc, err := html.Parse(bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err, "invalid HTML:\n%s", buff.Bytes())
+
+			links := querySelectorAll(doc, `head link[rel="canonical"]`)
+			metas := make(map[string]string)
+			for _, m := range querySelectorAll(doc, "head meta[content]") {
+				key := attr(m, "property")
+				if key == "" {
+					key = attr(m, "name")
+				}
+				if strings.HasPrefix(key, "og:") || strings.HasPrefix(key, "twitter:") {
+					metas[key] = attr(m, "content")
+				}
+			}
+
+			if tt.want == nil {
+				assert.Empty(t, links)
+				assert.Empty(t, metas)
+				return
+			}
+
+			require.Len(t, links, 1)
+			assert.Equal(t, *tt.want, page{
+				Canonical: attr(links[0], "href"),
+				Meta:      metas,
+			})
+		})
+	}
+}
+
+func TestSymbolSearch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		renderer Renderer
+		want     bool
+	}{
+		{desc: "disabled"},
+		{
+			desc:     "standalone",
+			renderer: Renderer{SymbolSearch: true},
+			want:     true,
+		},
+		{
+			desc:     "embedded",
+			renderer: Renderer{SymbolSearch: true, Embedded: true},
+			want:     true,
+		},
+		{
+			desc:     "embedded/disabled",
+			renderer: Renderer{Embedded: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			renderer := tt.renderer
+			renderer.Highlighter = _fakeHighlighter
+
+			var buff bytes.Buffer
+			require.NoError(t, renderer.RenderPackage(&buff, &PackageInfo{
+				Package:    &godoc.Package{Name: "foo", ImportPath: "example.com/foo"},
+				DocPrinter: new(CommentDocPrinter),
+			}))
+
+			doc, err := html.Parse(bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err, "invalid HTML:\n%s", buff.Bytes())
+
+			widget := querySelector(doc, "#symbol-search")
+			if !tt.want {
+				assert.Nil(t, widget)
+				return
+			}
+			require.NotNil(t, widget, "search box not found:\n%s", buff.Bytes())
+			assert.Equal(t, "../../_/symbols.json", attr(widget, "data-index"))
+			assert.NotNil(t, querySelector(widget, `input[type="search"]`))
+
+			var styles, scripts []string
+			for _, n := range querySelectorAll(doc, `link[rel="stylesheet"]`) {
+				styles = append(styles, attr(n, "href"))
+			}
+			for _, n := range querySelectorAll(doc, "script") {
+				scripts = append(scripts, attr(n, "src"))
+			}
+			assert.Contains(t, styles, "../../_/css/search.css")
+			assert.Contains(t, scripts, "../../_/js/search.js")
+		})
+	}
+}
+
+func TestRenderSiteIndex(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc       string
+		idx        SiteIndex
+		wantLatest string   // href of the latest release link
+		wantHeads  []string // section headings
+		wantLinks  []string // hrefs of listed sites
+		wantMarked []string // hrefs of sites marked latest
+	}{
+		{
+			desc:      "others only",
+			idx:       SiteIndex{Others: []string{"dev", "main"}},
+			wantLinks: []string{"dev", "main"},
+		},
+		{
+			desc: "releases",
+			idx: SiteIndex{
+				Releases:    []string{"v2.0.0", "v1.0.0"},
+				PreReleases: []string{"v3.0.0-rc.1"},
+				Others:      []string{"main"},
+				Latest:      "v2.0.0",
+				LatestAlias: "latest",
+			},
+			wantLatest: "latest",
+			wantHeads:  []string{"Releases", "Pre-releases", "Other versions"},
+			wantLinks:  []string{"v2.0.0", "v1.0.0", "v3.0.0-rc.1", "main"},
+			wantMarked: []string{"v2.0.0"},
+		},
+		{
+			desc: "no alias",
+			idx: SiteIndex{
+				Releases: []string{"v1.0.0"},
+				Latest:   "v1.0.0",
+			},
+			wantLatest: "v1.0.0",
+			wantHeads:  []string{"Releases"},
+			wantLinks:  []string{"v1.0.0"},
+			wantMarked: []string{"v1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var buff bytes.Buffer
+			require.NoError(t, new(Renderer).RenderSiteIndex(&buff, &tt.idx))
+
+			doc, err := html.Parse(bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err, "invalid HTML:\n%s", buff.Bytes())
+
+			var latest string
+			for _, a := range querySelectorAll(doc, "main p a") {
+				latest = attr(a, "href")
+			}
+			assert.Equal(t, tt.wantLatest, latest, "latest release link")
+
+			var heads, links, marked []string
+			for _, h := range querySelectorAll(doc, "main h2") {
+				heads = append(heads, text(h))
+			}
+			for _, a := range querySelectorAll(doc, "main li a") {
+				links = append(links, attr(a, "href"))
+			}
+			for _, a := range querySelectorAll(doc, "main li.latest a") {
+				marked = append(marked, attr(a, "href"))
+			}
+			assert.Equal(t, tt.wantHeads, heads, "headings")
+			assert.Equal(t, tt.wantLinks, links, "links")
+			assert.Equal(t, tt.wantMarked, marked, "latest")
+		})
+	}
+}
+
+func TestRenderRedirect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		renderer Renderer
+		redirect Redirect
+		want     string
+	}{
+		{
+			desc:     "root",
+			redirect: Redirect{From: "latest", To: "v1.0.0"},
+			want:     "../v1.0.0",
+		},
+		{
+			desc:     "page",
+			redirect: Redirect{From: "latest/foo/bar", To: "v1.0.0/foo/bar"},
+			want:     "../../../v1.0.0/foo/bar",
+		},
+		{
+			desc: "normalized",
+			renderer: Renderer{
+				NormalizeRelativePath: func(s string) string {
+					return strings.TrimSuffix(s, "/") + "/"
+				},
+			},
+			redirect: Redirect{From: "latest/foo", To: "v1.0.0/foo"},
+			want:     "../../v1.0.0/foo/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var buff bytes.Buffer
+			require.NoError(t, tt.renderer.RenderRedirect(&buff, &tt.redirect))
+
+			doc, err := html.Parse(bytes.NewReader(buff.Bytes()))
+			require.NoError(t, err, "invalid HTML:\n%s", buff.Bytes())
+
+			metas := querySelectorAll(doc, `meta[http-equiv="refresh"]`)
+			require.Len(t, metas, 1)
+			assert.Equal(t, "0; url="+tt.want, attr(metas[0], "content"))
+
+			links := querySelectorAll(doc, "body a")
+			require.Len(t, links, 1)
+			assert.Equal(t, tt.want, attr(links[0], "href"))
+		})
+	}
+}
+
 func TestFrontmatter(t *testing.T) {
 	t.Parallel()
 
@@ -956,48 +1598,6 @@ func TestBasename(t *testing.T) {
 	}
 }
 
-func TestFrontmatterDataName(t *testing.T) {
-	tests := []struct {
-		desc string
-		data frontmatterData
-		want string
-	}{
-		{desc: "empty"},
-		{
-			desc: "package",
-			data: frontmatterData{
-				Package: frontmatterPackageData{
-					Name: "foo",
-				},
-			},
-			want: "foo",
-		},
-		{
-			desc: "main package",
-			data: frontmatterData{
-				Package: frontmatterPackageData{
-					Name: "main",
-				},
-				Basename: "bar",
-			},
-			want: "bar",
-		},
-		{
-			desc: "dir",
-			data: frontmatterData{
-				Basename: "baz",
-			},
-			want: "baz",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.desc, func(t *testing.T) {
-			assert.Equal(t, tt.want, tt.data.Name())
-		})
-	}
-}
-
 func TestDict(t *testing.T) {
 	tests := []struct {
 		name string
@@ -1120,10 +1720,11 @@ func attr(n *html.Node, key string) string {
 	return ""
 }
 
-type fixedHighlighter struct{ code string }
+type fixedHighlighter struct{ code, css string }
 
-func (*fixedHighlighter) WriteCSS(io.Writer) error {
-	return nil
+func (h *fixedHighlighter) WriteCSS(w io.Writer) error {
+	_, err := io.WriteString(w, h.css)
+	return errtrace.Wrap(err)
 }
 
 func (h *fixedHighlighter) Highlight(*highlight.Code) string {
@@ -0,0 +1,204 @@
+package html
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"braces.dev/errtrace"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var _singlePageTmpl = template.Must(
+	template.New("singlepage.html").
+		Funcs((*render)(nil).FuncMap()).
+		ParseFS(_tmplFS, "tmpl/singlepage.html"),
+)
+
+// SinglePage holds the packages that should be rendered
+// into a single, self-contained HTML document.
+type SinglePage struct {
+	// Title of the document.
+	Title string
+
+	// PkgVersion is the version of the packages, if known.
+	PkgVersion string
+
+	// Packages in the order they should appear in the document.
+	//
+	// Subpackages and Breadcrumbs of these packages are ignored.
+	// The document's table of contents lists all packages instead.
+	Packages []*PackageInfo
+}
+
+// singlePagePackage is a package rendered into a [SinglePage].
+type singlePagePackage struct {
+	ID         string
+	Href       string
+	ImportPath string
+	Synopsis   string
+	Body       template.HTML
+}
+
+// RenderSinglePage renders the documentation for multiple packages
+// into a single HTML document with a table of contents.
+//
+// Stylesheets are included in the document,
+// and links between the packages are turned into links
+// to sections of the document.
+// Element IDs are namespaced by import path to avoid conflicts,
+// so "Foo" in package example.com/bar becomes "example.com/bar:Foo".
+func (r *Renderer) RenderSinglePage(w io.Writer, page *SinglePage) error {
+	css, err := r.inlineCSS()
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	local := make(map[string]struct{}, len(page.Packages))
+	for _, info := range page.Packages {
+		local[info.ImportPath] = struct{}{}
+	}
+
+	pkgs := make([]singlePagePackage, len(page.Packages))
+	for i, info := range page.Packages {
+		body, err := r.renderSinglePagePackage(info, local)
+		if err != nil {
+			return errtrace.Wrap(err)
+		}
+
+		pkgs[i] = singlePagePackage{
+			ID:         singlePageID(info.ImportPath, ""),
+			Href:       "#" + singlePageID(info.ImportPath, ""),
+			ImportPath: info.ImportPath,
+			Synopsis:   info.Synopsis,
+			Body:       body,
+		}
+	}
+
+	data := struct {
+		Title      string
+		PkgVersion string
+		CSS        template.CSS
+		Packages   []singlePagePackage
+	}{
+		Title:      page.Title,
+		PkgVersion: page.PkgVersion,
+		CSS:        template.CSS(css),
+		Packages:   pkgs,
+	}
+	return errtrace.Wrap(_singlePageTmpl.ExecuteTemplate(w, "SinglePage", data))
+}
+
+// renderSinglePagePackage renders the body of a package's page,
+// and rewrites its IDs and links for inclusion in a single page.
+func (r *Renderer) renderSinglePagePackage(info *PackageInfo, local map[string]struct{}) (template.HTML, error) {
+	render := render{
+		Home:        r.Home,
+		Path:        info.ImportPath,
+		DocPrinter:  info.DocPrinter,
+		Internal:    r.Internal,
+		Highlighter: r.Highlighter,
+	}
+
+	tmpl := r.templates().pkg
+	if info.BinName != "" {
+		tmpl = r.templates().cmd
+	}
+
+	// Subpackages are listed in the table of contents instead.
+	pkgInfo := *info
+	pkgInfo.Subpackages = nil
+	pkgInfo.Breadcrumbs = nil
+
+	var buf bytes.Buffer
+	err := template.Must(tmpl.Clone()).
+		Funcs(render.FuncMap()).
+		ExecuteTemplate(&buf, "Body", &pkgInfo)
+	if err != nil {
+		return "", errtrace.Wrap(err)
+	}
+
+	body, err := rewriteSinglePageLinks(&buf, info.ImportPath, local)
+	return template.HTML(body), errtrace.Wrap(err)
+}
+
+// rewriteSinglePageLinks namespaces the IDs in an HTML fragment
+// generated for the package at importPath,
+// and turns links to the given local packages into links within the page.
+func rewriteSinglePageLinks(r io.Reader, importPath string, local map[string]struct{}) (string, error) {
+	nodes, err := xhtml.ParseFragment(r, &xhtml.Node{
+		Type:     xhtml.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return "", errtrace.Wrap(err)
+	}
+
+	var rewrite func(*xhtml.Node)
+	rewrite = func(n *xhtml.Node) {
+		for i, attr := range n.Attr {
+			switch attr.Key {
+			case "id":
+				n.Attr[i].Val = singlePageID(importPath, attr.Val)
+			case "href":
+				n.Attr[i].Val = singlePageHref(importPath, attr.Val, local)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			rewrite(c)
+		}
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		rewrite(n)
+		if err := xhtml.Render(&sb, n); err != nil {
+			return "", errtrace.Wrap(err)
+		}
+	}
+	return sb.String(), nil
+}
+
+// singlePageHref rewrites a link found on the page for importPath.
+//
+// Links within the page and to other local packages
+// are turned into links to the corresponding sections of the single page.
+// All other links are left as-is.
+func singlePageHref(importPath, href string, local map[string]struct{}) string {
+	u, err := url.Parse(href)
+	if err != nil || u.Scheme != "" || u.Host != "" || path.IsAbs(u.Path) {
+		return href
+	}
+
+	target := importPath
+	if u.Path != "" {
+		target = path.Join(importPath, u.Path)
+	}
+	if _, ok := local[target]; !ok {
+		// The link may include the basename of the page
+		// (e.g. "../bar/index.html").
+		target = path.Dir(target)
+		if _, ok := local[target]; !ok {
+			return href
+		}
+	}
+
+	return "#" + singlePageID(target, u.Fragment)
+}
+
+// singlePageID returns the ID of the element on a single page
+// that corresponds to the element with the given ID
+// on the page for importPath.
+//
+// If id is empty, the ID of the package's section is returned.
+func singlePageID(importPath, id string) string {
+	if id == "" {
+		return importPath
+	}
+	return importPath + ":" + id
+}
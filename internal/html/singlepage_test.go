@@ -0,0 +1,156 @@
+package html
+
+import (
+	"bytes"
+	"go/doc/comment"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"golang.org/x/net/html"
+)
+
+func TestRenderSinglePage(t *testing.T) {
+	t.Parallel()
+
+	// Links to example.com/bar, relative to the given directory.
+	docPrinter := func(rel string) DocPrinter {
+		return &CommentDocPrinter{
+			Printer: comment.Printer{
+				DocLinkURL: func(link *comment.DocLink) string {
+					return rel + "#" + link.Name
+				},
+			},
+		}
+	}
+
+	var buf bytes.Buffer
+	err := (&Renderer{
+		Highlighter: _fakeHighlighter,
+	}).RenderSinglePage(&buf, &SinglePage{
+		Title:      "My SDK",
+		PkgVersion: "v1.2.3",
+		Packages: []*PackageInfo{
+			{
+				Package: &godoc.Package{
+					Name:       "bar",
+					ImportPath: "example.com/bar",
+					Synopsis:   "Package bar does things.",
+					Import:     textSpan(`import "example.com/bar"`),
+					Doc:        parseDoc("See [example.com/bar.Baz]."),
+					Functions: []*godoc.Function{
+						{
+							Name:      "Baz",
+							Decl:      textSpan("func Baz()"),
+							ShortDecl: "func Baz()",
+						},
+					},
+				},
+				DocPrinter: docPrinter(""),
+			},
+			{
+				Package: &godoc.Package{
+					Name:       "main",
+					ImportPath: "example.com/cmd/qux",
+					BinName:    "qux",
+					Synopsis:   "qux runs things.",
+					Doc:        parseDoc("qux runs [example.com/bar.Baz]."),
+				},
+				DocPrinter: docPrinter("../../bar"),
+				Subpackages: []Subpackage{
+					{RelativePath: "sub", Synopsis: "Not listed."},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	doc, err := html.Parse(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "My SDK", text(querySelector(doc, "title")))
+	assert.Equal(t, "My SDK", text(querySelector(doc, "header h1")))
+	assert.NotNil(t, querySelector(doc, "head style"), "CSS must be inlined")
+	assert.Nil(t, querySelector(doc, `link[rel="stylesheet"]`))
+
+	var toc []string
+	for _, a := range querySelectorAll(doc, "#pkg-contents a") {
+		toc = append(toc, attr(a, "href")+" "+text(a))
+	}
+	assert.Equal(t, []string{
+		"#example.com/bar example.com/bar",
+		"#example.com/cmd/qux example.com/cmd/qux",
+	}, toc)
+
+	var sections []string
+	for _, s := range querySelectorAll(doc, "section.package") {
+		sections = append(sections, attr(s, "id"))
+	}
+	assert.Equal(t, []string{"example.com/bar", "example.com/cmd/qux"}, sections)
+
+	bar := querySelector(doc, `section[id="example.com/bar"]`)
+	require.NotNil(t, bar)
+	assert.NotNil(t, querySelector(bar, `[id="example.com/bar:Baz"]`))
+	assert.Nil(t, querySelector(bar, `[id="Baz"]`))
+	assert.Equal(t, "#example.com/bar:Baz", attr(querySelector(bar, "p a"), "href"))
+
+	qux := querySelector(doc, `section[id="example.com/cmd/qux"]`)
+	require.NotNil(t, qux)
+	link := querySelector(qux, "p a")
+	require.NotNil(t, link)
+	assert.Equal(t, "#example.com/bar:Baz", attr(link, "href"))
+	assert.NotContains(t, allText(qux), "Not listed.")
+}
+
+func TestSinglePageHref(t *testing.T) {
+	t.Parallel()
+
+	local := map[string]struct{}{
+		"example.com/foo":     {},
+		"example.com/foo/bar": {},
+		"example.com/baz":     {},
+	}
+
+	tests := []struct {
+		give string
+		want string
+	}{
+		{give: "#Foo", want: "#example.com/foo:Foo"},
+		{give: "#", want: "#example.com/foo"},
+		{give: "", want: "#example.com/foo"},
+		{give: "bar", want: "#example.com/foo/bar"},
+		{give: "bar/", want: "#example.com/foo/bar"},
+		{give: "bar/#Bar", want: "#example.com/foo/bar:Bar"},
+		{give: "bar/index.html#Bar", want: "#example.com/foo/bar:Bar"},
+		{give: "../baz#Baz.Qux", want: "#example.com/baz:Baz.Qux"},
+		{give: "../qux", want: "../qux"},
+		{give: "https://pkg.go.dev/fmt", want: "https://pkg.go.dev/fmt"},
+		{give: "/example.com/foo", want: "/example.com/foo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, singlePageHref("example.com/foo", tt.give, local))
+		})
+	}
+}
+
+func TestRewriteSinglePageLinks(t *testing.T) {
+	t.Parallel()
+
+	got, err := rewriteSinglePageLinks(
+		strings.NewReader(`<h3 id="Foo">Foo</h3>`+
+			`<pre>func <a href="../bar#Bar">Bar</a>()</pre>`),
+		"example.com/foo",
+		map[string]struct{}{"example.com/bar": {}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`<h3 id="example.com/foo:Foo">Foo</h3>`+
+			`<pre>func <a href="#example.com/bar:Bar">Bar</a>()</pre>`,
+		got)
+}
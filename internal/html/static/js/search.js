@@ -0,0 +1,207 @@
+// Fuzzy search for packages and symbols listed in the symbol index,
+// and navigation to the selected result.
+// The index is loaded the first time the search box is used.
+(() => {
+	let widget = document.getElementById("symbol-search")
+	if (!widget) {
+		return
+	}
+
+	const maxResults = 20
+
+	let input = widget.querySelector("input")
+	let list = widget.querySelector("ul")
+	let indexURL = new URL(widget.dataset.index, window.location.href)
+	// The index is inside the static directory at the root of the site.
+	let siteRoot = new URL("../", indexURL)
+
+	let entries = []
+	let loading = null
+	function load() {
+		if (loading) {
+			return loading
+		}
+
+		loading = fetch(indexURL)
+			.then((resp) => {
+				if (!resp.ok) {
+					throw new Error(`${indexURL}: ${resp.status}`)
+				}
+				return resp.json()
+			})
+			.then((index) => {
+				for (let pkg of index.packages) {
+					let pkgURL = new URL(pkg.url, siteRoot)
+					entries.push({
+						label: pkg.path,
+						name: pkg.path,
+						kind: pkg.kind,
+						synopsis: pkg.synopsis,
+						href: pkgURL.href,
+					})
+
+					for (let sym of pkg.symbols || []) {
+						let symURL = new URL(pkgURL)
+						symURL.hash = sym.id
+						entries.push({
+							label: `${pkg.name}.${sym.name}`,
+							name: sym.name,
+							kind: sym.kind,
+							synopsis: sym.synopsis,
+							href: symURL.href,
+						})
+					}
+				}
+			})
+			.catch((err) => console.warn("doc2go: unable to load symbol index:", err))
+		return loading
+	}
+
+	// Scores how well the lowercase query matches the given text,
+	// or returns -1 if it doesn't match at all.
+	//
+	// All characters of the query must appear in the text in order.
+	// Consecutive characters and characters at the start of words
+	// earn more points, and shorter texts win ties.
+	function score(query, text) {
+		let lower = text.toLowerCase()
+		let total = 0
+		let from = 0
+		let prev = -2
+		for (let ch of query) {
+			let i = lower.indexOf(ch, from)
+			if (i < 0) {
+				return -1
+			}
+
+			total += 1
+			if (i === prev + 1) {
+				total += 4
+			}
+			if (i === 0 || "./_ ".includes(text[i - 1]) ||
+				(text[i] !== lower[i] && text[i - 1] === lower[i - 1])) {
+				total += 3
+			}
+			prev = i
+			from = i + 1
+		}
+		return total - text.length / 100
+	}
+
+	function search(query) {
+		query = query.trim().toLowerCase().replace(/\s+/g, "")
+		if (!query) {
+			return []
+		}
+
+		let results = []
+		for (let entry of entries) {
+			let s = score(query, entry.label)
+			if (s < 0) {
+				continue
+			}
+			// Exact matches of a symbol or package name go first.
+			let name = entry.name.toLowerCase()
+			if (name === query || name.endsWith("/" + query)) {
+				s += 100
+			}
+			results.push({entry, score: s})
+		}
+
+		results.sort((a, b) => b.score - a.score ||
+			a.entry.label.localeCompare(b.entry.label))
+		return results.slice(0, maxResults).map((r) => r.entry)
+	}
+
+	let active = -1
+	function setActive(idx) {
+		let items = list.children
+		if (items.length === 0) {
+			active = -1
+			return
+		}
+		active = (idx + items.length) % items.length
+		for (let i = 0; i < items.length; i++) {
+			let selected = i === active
+			items[i].classList.toggle("active", selected)
+			items[i].setAttribute("aria-selected", selected)
+		}
+		items[active].scrollIntoView({block: "nearest"})
+	}
+
+	function update() {
+		let results = search(input.value)
+		list.replaceChildren(...results.map((entry) => {
+			let a = document.createElement("a")
+			a.href = entry.href
+
+			let label = document.createElement("span")
+			label.className = "symbol-search-label"
+			label.textContent = entry.label
+			a.append(label)
+
+			let kind = document.createElement("span")
+			kind.className = "symbol-search-kind"
+			kind.textContent = entry.kind
+			a.append(" ", kind)
+
+			if (entry.synopsis) {
+				let synopsis = document.createElement("span")
+				synopsis.className = "symbol-search-synopsis"
+				synopsis.textContent = entry.synopsis
+				a.append(synopsis)
+			}
+
+			let li = document.createElement("li")
+			li.setAttribute("role", "option")
+			li.append(a)
+			return li
+		}))
+		list.hidden = results.length === 0
+		setActive(0)
+	}
+
+	input.addEventListener("focus", () => load().then(update))
+	input.addEventListener("input", () => load().then(update))
+	input.addEventListener("blur", () => {
+		// Let clicks on results land first.
+		setTimeout(() => { list.hidden = true }, 200)
+	})
+	input.addEventListener("keydown", (event) => {
+		switch (event.key) {
+		case "ArrowDown":
+			setActive(active + 1)
+			break
+		case "ArrowUp":
+			setActive(active - 1)
+			break
+		case "Enter":
+			if (active >= 0) {
+				list.children[active].querySelector("a").click()
+			}
+			break
+		case "Escape":
+			list.hidden = true
+			input.blur()
+			break
+		default:
+			return
+		}
+		event.preventDefault()
+	})
+
+	// Press '/' anywhere on the page to start searching.
+	document.addEventListener("keydown", (event) => {
+		if (event.key !== "/" || event.ctrlKey || event.metaKey || event.altKey) {
+			return
+		}
+		let target = event.target
+		if (target.isContentEditable || ["INPUT", "SELECT", "TEXTAREA"].includes(target.tagName)) {
+			return
+		}
+		event.preventDefault()
+		input.focus()
+	})
+
+	input.hidden = false
+})()
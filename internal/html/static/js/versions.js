@@ -0,0 +1,50 @@
+// Populates the version switcher in the navbar
+// with the versions listed in the versions manifest,
+// and navigates to the same page in the selected version.
+// If that version doesn't have the page, navigates to its root instead.
+(() => {
+	let select = document.getElementById("version-switcher")
+	if (!select) {
+		return
+	}
+
+	let current = select.value
+	let manifestURL = new URL(select.dataset.manifest, window.location.href)
+	let outputRoot = new URL(".", manifestURL)
+
+	fetch(manifestURL)
+		.then((resp) => {
+			if (!resp.ok) {
+				throw new Error(`${manifestURL}: ${resp.status}`)
+			}
+			return resp.json()
+		})
+		.then((manifest) => {
+			select.replaceChildren(...manifest.versions.map((v) => {
+				let opt = document.createElement("option")
+				opt.value = v.name
+				opt.textContent = v.name === manifest.latest
+					? `${v.name} (latest)`
+					: v.name
+				opt.selected = v.name === current
+				return opt
+			}))
+			select.hidden = false
+		})
+		.catch((err) => console.warn("doc2go: unable to load versions:", err))
+
+	select.addEventListener("change", () => {
+		let versionRoot = new URL(select.value + "/", outputRoot)
+		let pagesURL = new URL("_/pages.json", versionRoot)
+
+		fetch(pagesURL)
+			.then((resp) => resp.ok ? resp.json() : {pages: []})
+			.catch(() => ({pages: []}))
+			.then((manifest) => {
+				let href = manifest.pages.includes(select.dataset.page)
+					? select.dataset.pageHref
+					: select.dataset.rootHref
+				window.location.href = new URL(href, versionRoot).href
+			})
+	})
+})()
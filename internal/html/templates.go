@@ -0,0 +1,154 @@
+package html
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"slices"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/must"
+)
+
+// TemplateFiles lists the names of the templates
+// that may be replaced with [ParseTemplates].
+var TemplateFiles = []string{
+	"layout.html",
+	"package.html",
+	"command.html",
+	"directory.html",
+	"siteindex.html",
+	"subpackages.html",
+	"pagefind.html",
+	"search.html",
+}
+
+// Templates is a set of templates used to render pages.
+//
+// The zero value is not ready to use.
+// Use [ParseTemplates] to build one.
+type Templates struct {
+	pkg       *template.Template
+	cmd       *template.Template
+	pkgIndex  *template.Template
+	siteIndex *template.Template
+}
+
+// _defaultTemplates are the templates built into doc2go.
+var _defaultTemplates = func() *Templates {
+	ts, err := parseTemplates(nil)
+	must.NotErrorf(err, "invalid built-in templates")
+	return ts
+}()
+
+// ParseTemplates parses the templates used to render pages,
+// replacing the built-in version of each of [TemplateFiles]
+// with the file of the same name in dir, if any.
+// Other files in dir are ignored.
+//
+// Replacement templates have access to the same functions
+// as the built-in templates.
+// Between them, they must define the "Page" and "Embedded" templates
+// used to render complete and embedded pages,
+// and the "Body" template of each kind of page.
+func ParseTemplates(dir fs.FS) (*Templates, error) {
+	return errtrace.Wrap2(parseTemplates(dir))
+}
+
+func parseTemplates(dir fs.FS) (*Templates, error) {
+	var (
+		ts   Templates
+		errs []error
+	)
+	for _, t := range []struct {
+		dst   **template.Template
+		files []string
+		defs  []string // templates that must be defined
+	}{
+		{
+			dst:   &ts.pkg,
+			files: []string{"package.html", "layout.html", "subpackages.html", "pagefind.html", "search.html"},
+			defs:  []string{"Page", "Embedded", "Body"},
+		},
+		{
+			dst:   &ts.cmd,
+			files: []string{"command.html", "layout.html", "subpackages.html", "pagefind.html", "search.html"},
+			defs:  []string{"Page", "Embedded", "Body"},
+		},
+		{
+			dst:   &ts.pkgIndex,
+			files: []string{"directory.html", "layout.html", "subpackages.html", "pagefind.html", "search.html"},
+			defs:  []string{"Page", "Embedded", "Body"},
+		},
+		{
+			dst:   &ts.siteIndex,
+			files: []string{"siteindex.html", "layout.html", "pagefind.html", "search.html"},
+			defs:  []string{"Page", "Embedded", "Body"},
+		},
+	} {
+		tmpl, err := parseTemplate(dir, t.files...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, name := range t.defs {
+			if tmpl.Lookup(name) == nil {
+				errs = append(errs, fmt.Errorf("%v: template %q is not defined", t.files[0], name))
+			}
+		}
+		*t.dst = tmpl
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	return &ts, nil
+}
+
+// parseTemplate parses the given files into a single template
+// named after the first file.
+//
+// Files found in dir take precedence over the built-in templates.
+// dir may be nil.
+func parseTemplate(dir fs.FS, files ...string) (*template.Template, error) {
+	// Trick borrowed from pkgsite:
+	// Unusable function references at parse time,
+	// and then Clone and replace at render time.
+	// This way, template validity is still
+	// verified at parse time.
+	root := template.New(files[0]).Funcs((*render)(nil).FuncMap())
+	for _, name := range files {
+		bs, err := readTemplate(dir, name)
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+
+		tmpl := root
+		if name != root.Name() {
+			tmpl = root.New(name)
+		}
+		if _, err := tmpl.Parse(string(bs)); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+	}
+	return root, nil
+}
+
+// readTemplate reads a template file from dir,
+// falling back to the built-in version if it's not there.
+func readTemplate(dir fs.FS, name string) ([]byte, error) {
+	if dir != nil && slices.Contains(TemplateFiles, name) {
+		bs, err := fs.ReadFile(dir, name)
+		if err == nil {
+			return bs, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, errtrace.Wrap(err)
+		}
+	}
+
+	return errtrace.Wrap2(fs.ReadFile(_tmplFS, path.Join("tmpl", name)))
+}
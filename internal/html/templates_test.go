@@ -0,0 +1,116 @@
+package html
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/godoc"
+)
+
+func TestParseTemplates(t *testing.T) {
+	t.Parallel()
+
+	layout := `{{ define "Page" -}}
+<header>ACME Corp</header>
+{{ template "Body" $ }}
+<footer>{{ static "css/main.css" }}</footer>
+{{- end }}
+{{ define "Embedded" }}{{ template "Body" $ }}{{ end }}`
+
+	templates, err := ParseTemplates(fstest.MapFS{
+		"layout.html":      {Data: []byte(layout)},
+		"subpackages.html": {Data: []byte(`SUBPACKAGES`)},
+		"redirect.html":    {Data: []byte(`{{ ignored`)},
+	})
+	require.NoError(t, err)
+
+	renderer := Renderer{
+		Highlighter: _fakeHighlighter,
+		Templates:   templates,
+	}
+
+	t.Run("package", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		require.NoError(t, renderer.RenderPackage(&buff, &PackageInfo{
+			Package: &godoc.Package{
+				Name:       "foo",
+				ImportPath: "example.com/foo",
+				Functions:  []*godoc.Function{{Name: "Foo"}},
+			},
+			DocPrinter: new(CommentDocPrinter),
+		}))
+
+		got := buff.String()
+		assert.Contains(t, got, "<header>ACME Corp</header>")
+		assert.Contains(t, got, "<footer>../../_/css/main.css</footer>")
+		assert.Contains(t, got, `id="Foo"`, "built-in package template must be used")
+		assert.NotContains(t, got, "<!DOCTYPE html>")
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		require.NoError(t, renderer.RenderPackageIndex(&buff, &PackageIndex{
+			Path:        "example.com",
+			Subpackages: []Subpackage{{RelativePath: "foo"}},
+		}))
+
+		got := buff.String()
+		assert.Contains(t, got, "<header>ACME Corp</header>")
+		assert.Contains(t, got, "SUBPACKAGES")
+	})
+}
+
+func TestParseTemplates_errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		give fstest.MapFS
+		want []string
+	}{
+		{
+			desc: "syntax error",
+			give: fstest.MapFS{
+				"package.html": {Data: []byte(`{{ define "Body" }}{{ if }}{{ end }}`)},
+			},
+			want: []string{"package.html", "missing value for if"},
+		},
+		{
+			desc: "unknown function",
+			give: fstest.MapFS{
+				"layout.html": {Data: []byte(`{{ define "Page" }}{{ noSuchFunc }}{{ end }}`)},
+			},
+			want: []string{"layout.html", `function "noSuchFunc" not defined`},
+		},
+		{
+			desc: "missing definition",
+			give: fstest.MapFS{
+				"layout.html": {Data: []byte(`{{ define "Page" }}{{ template "Body" $ }}{{ end }}`)},
+			},
+			want: []string{
+				`package.html: template "Embedded" is not defined`,
+				`directory.html: template "Embedded" is not defined`,
+				`siteindex.html: template "Embedded" is not defined`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseTemplates(tt.give)
+			require.Error(t, err)
+			for _, want := range tt.want {
+				assert.ErrorContains(t, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package jsondoc
+
+import "go.abhg.dev/doc2go/internal/highlight"
+
+// newCode converts a highlighted code block into a [Code],
+// keeping its text, links, and anchors.
+func newCode(code *highlight.Code) *Code {
+	if code == nil {
+		return nil
+	}
+
+	text, ranges := code.TextRanges()
+	c := Code{Text: text}
+	for _, r := range ranges {
+		switch s := r.Span.(type) {
+		case *highlight.AnchorSpan:
+			c.Anchors = append(c.Anchors, &Anchor{ID: s.ID, Start: r.Start, End: r.End})
+		case *highlight.LinkSpan:
+			c.Links = append(c.Links, &Link{Dest: s.Dest, Start: r.Start, End: r.End})
+		}
+	}
+	return &c
+}
@@ -0,0 +1,109 @@
+package jsondoc
+
+import (
+	"errors"
+	"testing"
+
+	chroma "github.com/alecthomas/chroma/v2"
+	"github.com/stretchr/testify/assert"
+	"go.abhg.dev/doc2go/internal/highlight"
+)
+
+func TestNewCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		give *highlight.Code
+		want *Code
+	}{
+		{desc: "nil"},
+		{
+			desc: "text",
+			give: &highlight.Code{
+				Spans: []highlight.Span{
+					&highlight.TextSpan{Text: []byte("func ")},
+					&highlight.TokenSpan{
+						Tokens: []chroma.Token{
+							{Type: chroma.NameFunction, Value: "Foo"},
+							{Type: chroma.Punctuation, Value: "()"},
+						},
+					},
+				},
+			},
+			want: &Code{Text: "func Foo()"},
+		},
+		{
+			desc: "links and anchors",
+			give: &highlight.Code{
+				Spans: []highlight.Span{
+					&highlight.TextSpan{Text: []byte("func ")},
+					&highlight.AnchorSpan{
+						ID: "Foo",
+						Spans: []highlight.Span{
+							&highlight.TextSpan{Text: []byte("Foo")},
+						},
+					},
+					&highlight.TextSpan{Text: []byte("(b ")},
+					&highlight.LinkSpan{
+						Dest: "../bar#Bar",
+						Spans: []highlight.Span{
+							&highlight.TextSpan{Text: []byte("bar.Bar")},
+						},
+					},
+					&highlight.TextSpan{Text: []byte(")")},
+				},
+			},
+			want: &Code{
+				Text: "func Foo(b bar.Bar)",
+				Links: []*Link{
+					{Start: 11, End: 18, Dest: "../bar#Bar"},
+				},
+				Anchors: []*Anchor{
+					{Start: 5, End: 8, ID: "Foo"},
+				},
+			},
+		},
+		{
+			desc: "nested",
+			give: &highlight.Code{
+				Spans: []highlight.Span{
+					&highlight.TextSpan{Text: []byte("type ")},
+					&highlight.AnchorSpan{
+						ID: "Foo",
+						Spans: []highlight.Span{
+							&highlight.LinkSpan{
+								Dest: "#Foo",
+								Spans: []highlight.Span{
+									&highlight.TextSpan{Text: []byte("Foo")},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: &Code{
+				Text:    "type Foo",
+				Links:   []*Link{{Start: 5, End: 8, Dest: "#Foo"}},
+				Anchors: []*Anchor{{Start: 5, End: 8, ID: "Foo"}},
+			},
+		},
+		{
+			desc: "error",
+			give: &highlight.Code{
+				Spans: []highlight.Span{
+					&highlight.ErrorSpan{Msg: "bad code", Err: errors.New("great sadness")},
+				},
+			},
+			want: &Code{Text: "bad code: great sadness"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, newCode(tt.give))
+		})
+	}
+}
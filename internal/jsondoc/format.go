@@ -0,0 +1,224 @@
+package jsondoc
+
+import "go.abhg.dev/doc2go/internal/sitegen"
+
+// SchemaVersion is the version of the JSON format
+// reported in every file as "schemaVersion".
+//
+// It changes when fields are removed or change meaning.
+// New fields may be added without changing it,
+// so consumers should ignore fields they don't recognize.
+const SchemaVersion = 1
+
+// Package is the JSON form of a package's documentation.
+type Package struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Kind is "package", or "command" for package main.
+	Kind sitegen.PageKind `json:"kind"`
+
+	ImportPath string `json:"importPath"`
+	Name       string `json:"name"`
+
+	// BinName is the name of the executable built from a command.
+	BinName string `json:"binName,omitempty"`
+
+	Synopsis   string `json:"synopsis,omitempty"`
+	PkgVersion string `json:"pkgVersion,omitempty"`
+	Doc        *Doc   `json:"doc,omitempty"`
+
+	Constants []*Value    `json:"constants,omitempty"`
+	Variables []*Value    `json:"variables,omitempty"`
+	Functions []*Function `json:"functions,omitempty"`
+	Types     []*Type     `json:"types,omitempty"`
+	Examples  []*Example  `json:"examples,omitempty"`
+
+	Subpackages []*Subpackage `json:"subpackages,omitempty"`
+}
+
+// Directory is the JSON form of a directory
+// that holds packages but isn't one itself.
+type Directory struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Kind is always "directory".
+	Kind sitegen.PageKind `json:"kind"`
+
+	// ImportPath of the directory.
+	// This is empty for the root of the site.
+	ImportPath string `json:"importPath"`
+
+	Subpackages []*Subpackage `json:"subpackages,omitempty"`
+}
+
+// Subpackage is a package listed in a [Package] or [Directory].
+type Subpackage struct {
+	ImportPath string `json:"importPath"`
+
+	// Path to the subpackage relative to the parent.
+	RelativePath string `json:"relativePath"`
+
+	// Link to the subpackage's JSON file relative to the parent's.
+	Link string `json:"link"`
+
+	Synopsis string `json:"synopsis,omitempty"`
+}
+
+// Doc is a documentation comment.
+type Doc struct {
+	// HTML and Markdown renderings of the comment.
+	// Links to other symbols are resolved
+	// in the same way as in [Code].
+	HTML     string `json:"html"`
+	Markdown string `json:"markdown"`
+}
+
+// Value is a constant or variable declaration.
+// A single declaration may define several names.
+type Value struct {
+	Names      []string `json:"names"`
+	Doc        *Doc     `json:"doc,omitempty"`
+	Decl       *Code    `json:"decl"`
+	Deprecated bool     `json:"deprecated,omitempty"`
+}
+
+// Function is a function or method.
+type Function struct {
+	Name string `json:"name"`
+
+	// Receiver of a method as written in the source, e.g. "f *Foo",
+	// and the name of the receiver type, e.g. "Foo".
+	// Empty for functions.
+	Recv     string `json:"recv,omitempty"`
+	RecvType string `json:"recvType,omitempty"`
+
+	// ShortDecl is a one-line form of the declaration,
+	// e.g. "func (f *Foo) Bar(int) error".
+	ShortDecl string `json:"shortDecl"`
+
+	Doc        *Doc       `json:"doc,omitempty"`
+	Decl       *Code      `json:"decl"`
+	Examples   []*Example `json:"examples,omitempty"`
+	Deprecated bool       `json:"deprecated,omitempty"`
+}
+
+// Type is a type declaration
+// with the values, functions, and methods associated with it.
+type Type struct {
+	Name string `json:"name"`
+	Doc  *Doc   `json:"doc,omitempty"`
+	Decl *Code  `json:"decl"`
+
+	Constants []*Value    `json:"constants,omitempty"`
+	Variables []*Value    `json:"variables,omitempty"`
+	Functions []*Function `json:"functions,omitempty"`
+	Methods   []*Function `json:"methods,omitempty"`
+
+	Examples   []*Example `json:"examples,omitempty"`
+	Deprecated bool       `json:"deprecated,omitempty"`
+}
+
+// Example is a runnable example.
+type Example struct {
+	// ID of the example, e.g. "example-Foo.Bar-suffix".
+	// Unique within a package.
+	ID string `json:"id"`
+
+	// Name of the function or type the example is for,
+	// and for methods, the name of the receiver type.
+	// Both are empty for package examples.
+	Parent ExampleParent `json:"parent"`
+
+	Suffix string `json:"suffix,omitempty"`
+	Doc    *Doc   `json:"doc,omitempty"`
+	Code   *Code  `json:"code"`
+	Output string `json:"output,omitempty"`
+}
+
+// ExampleParent identifies the symbol that an [Example] is for.
+type ExampleParent struct {
+	Recv string `json:"recv,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Code is a block of Go code.
+//
+// Regions of the code that refer to other symbols
+// or define a symbol are listed in Links and Anchors.
+// Their offsets are in bytes from the start of Text.
+type Code struct {
+	Text    string    `json:"text"`
+	Links   []*Link   `json:"links,omitempty"`
+	Anchors []*Anchor `json:"anchors,omitempty"`
+}
+
+// Link is a region of [Code] that refers to another symbol.
+type Link struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+
+	// Dest is the URL of the symbol's documentation.
+	// Links to packages in the same site
+	// are relative to the current package's file,
+	// and links to symbols in the same package
+	// have only a fragment, e.g. "#Foo".
+	Dest string `json:"dest"`
+}
+
+// Anchor is a region of [Code] that defines a symbol.
+type Anchor struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+
+	// ID of the symbol, e.g. "Foo" or "Foo.Bar".
+	// Links to this symbol use it as the fragment.
+	ID string `json:"id"`
+}
+
+// SiteIndex is the JSON form of the index of versions
+// generated with -subdir.
+type SiteIndex struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Names of the versions, newest first.
+	Releases    []string `json:"releases,omitempty"`
+	PreReleases []string `json:"preReleases,omitempty"`
+
+	// Versions that aren't semantic versions, sorted by name.
+	Others []string `json:"others,omitempty"`
+
+	// Latest is the newest release, if any,
+	// and LatestAlias is the directory that redirects to it.
+	Latest      string `json:"latest,omitempty"`
+	LatestAlias string `json:"latestAlias,omitempty"`
+}
+
+// Redirect is written in place of a page that moved.
+type Redirect struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Redirect is the link to the page's new location
+	// relative to this file.
+	Redirect string `json:"redirect"`
+}
+
+// Manifest lists every page in a site.
+type Manifest struct {
+	SchemaVersion int `json:"schemaVersion"`
+
+	// Pages sorted by import path.
+	Pages []*ManifestPage `json:"pages"`
+}
+
+// ManifestPage is a page listed in a [Manifest].
+type ManifestPage struct {
+	ImportPath string `json:"importPath"`
+
+	// Kind is one of "package", "command", and "directory".
+	Kind sitegen.PageKind `json:"kind"`
+
+	// Path to the page's JSON file relative to the manifest.
+	Path string `json:"path"`
+
+	Synopsis string `json:"synopsis,omitempty"`
+}
@@ -0,0 +1,247 @@
+// Package jsondoc renders godoc.Package as JSON
+// for use by other documentation frontends.
+//
+// The format is described by the types in this package.
+package jsondoc
+
+import (
+	"encoding/json"
+	"go/doc/comment"
+	"io"
+	"path"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/relative"
+	"go.abhg.dev/doc2go/internal/sitegen"
+)
+
+// ManifestName is the name of the file
+// that lists every page in the site.
+const ManifestName = "manifest.json"
+
+// Renderer renders components into JSON.
+//
+// It accepts the same inputs as [html.Renderer],
+// and also writes a [Manifest] of the site.
+type Renderer struct {
+	// Path to the home page of the generated site.
+	Home string
+
+	// Internal specifies whether subpackage listings
+	// should include internal packages.
+	Internal bool
+
+	// NormalizeRelativePath is an optional function that
+	// normalizes relative links between files.
+	NormalizeRelativePath func(string) string
+}
+
+var _ sitegen.ManifestRenderer = (*Renderer)(nil)
+
+// StaticFiles returns an empty map.
+// JSON output doesn't need any static files.
+func (r *Renderer) StaticFiles() (map[string][]byte, error) {
+	return make(map[string][]byte), nil
+}
+
+// RenderPackage renders the documentation for a single Go package.
+func (r *Renderer) RenderPackage(w io.Writer, info *html.PackageInfo) error {
+	cv := converter{DocPrinter: info.DocPrinter}
+	pkg := Package{
+		SchemaVersion: SchemaVersion,
+		Kind:          sitegen.PackagePage,
+		ImportPath:    info.ImportPath,
+		Name:          info.Name,
+		BinName:       info.BinName,
+		Synopsis:      info.Synopsis,
+		PkgVersion:    info.PkgVersion,
+		Doc:           cv.doc(3, info.Doc),
+		Constants:     cv.values(info.Constants),
+		Variables:     cv.values(info.Variables),
+		Functions:     cv.funcs(4, info.Functions),
+		Types:         cv.types(info.Types),
+		Examples:      cv.examples(3, info.Examples),
+		Subpackages:   r.subpackages(info.ImportPath, info.Subpackages),
+	}
+	if info.BinName != "" {
+		pkg.Kind = sitegen.CommandPage
+	}
+	return errtrace.Wrap(writeJSON(w, pkg))
+}
+
+// RenderPackageIndex renders the list of descendants for a directory
+// as JSON.
+func (r *Renderer) RenderPackageIndex(w io.Writer, pidx *html.PackageIndex) error {
+	return errtrace.Wrap(writeJSON(w, Directory{
+		SchemaVersion: SchemaVersion,
+		Kind:          sitegen.DirectoryPage,
+		ImportPath:    pidx.Path,
+		Subpackages:   r.subpackages(pidx.Path, pidx.Subpackages),
+	}))
+}
+
+// RenderSiteIndex renders the list of sub-sites as JSON.
+func (r *Renderer) RenderSiteIndex(w io.Writer, sidx *html.SiteIndex) error {
+	return errtrace.Wrap(writeJSON(w, SiteIndex{
+		SchemaVersion: SchemaVersion,
+		Releases:      sidx.Releases,
+		PreReleases:   sidx.PreReleases,
+		Others:        sidx.Others,
+		Latest:        sidx.Latest,
+		LatestAlias:   sidx.LatestAlias,
+	}))
+}
+
+// RenderRedirect renders a file that points to another page.
+func (r *Renderer) RenderRedirect(w io.Writer, redirect *html.Redirect) error {
+	return errtrace.Wrap(writeJSON(w, Redirect{
+		SchemaVersion: SchemaVersion,
+		Redirect:      r.normalizeRelativePath(relative.Path(redirect.From, redirect.To)),
+	}))
+}
+
+// ManifestName returns the name of the manifest file
+// relative to the root of the site.
+func (r *Renderer) ManifestName() string {
+	return ManifestName
+}
+
+// RenderManifest renders a [Manifest] listing the given pages.
+func (r *Renderer) RenderManifest(w io.Writer, pages []sitegen.PageResult) error {
+	manifest := Manifest{
+		SchemaVersion: SchemaVersion,
+		Pages:         make([]*ManifestPage, len(pages)),
+	}
+	for i, page := range pages {
+		manifest.Pages[i] = &ManifestPage{
+			ImportPath: page.ImportPath,
+			Kind:       page.Kind,
+			Path:       r.normalizeRelativePath(relative.Path(r.Home, page.ImportPath)),
+			Synopsis:   page.Synopsis,
+		}
+	}
+	return errtrace.Wrap(writeJSON(w, manifest))
+}
+
+func (r *Renderer) subpackages(from string, subpkgs []html.Subpackage) []*Subpackage {
+	var out []*Subpackage
+	for _, pkg := range subpkgs {
+		if !r.Internal && pkg.IsInternal() {
+			continue
+		}
+
+		out = append(out, &Subpackage{
+			ImportPath:   path.Join(from, pkg.RelativePath),
+			RelativePath: pkg.RelativePath,
+			Link:         r.normalizeRelativePath(pkg.RelativePath),
+			Synopsis:     pkg.Synopsis,
+		})
+	}
+	return out
+}
+
+func (r *Renderer) normalizeRelativePath(p string) string {
+	if f := r.NormalizeRelativePath; f != nil {
+		return f(p)
+	}
+	return p
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return errtrace.Wrap(enc.Encode(v))
+}
+
+// converter converts godoc types into their JSON forms.
+type converter struct {
+	DocPrinter html.DocPrinter
+}
+
+// doc converts a doc comment,
+// rendering headings in it at the given level.
+func (cv *converter) doc(lvl int, doc *comment.Doc) *Doc {
+	if doc == nil || len(doc.Content) == 0 {
+		return nil
+	}
+
+	dp := cv.DocPrinter.WithHeadingLevel(lvl)
+	return &Doc{
+		HTML:     string(dp.HTML(doc)),
+		Markdown: string(dp.Markdown(doc)),
+	}
+}
+
+func (cv *converter) values(vals []*godoc.Value) []*Value {
+	out := make([]*Value, len(vals))
+	for i, v := range vals {
+		out[i] = &Value{
+			Names:      v.Names,
+			Doc:        cv.doc(4, v.Doc),
+			Decl:       newCode(v.Decl),
+			Deprecated: v.Deprecated,
+		}
+	}
+	return out
+}
+
+func (cv *converter) funcs(lvl int, fns []*godoc.Function) []*Function {
+	out := make([]*Function, len(fns))
+	for i, fn := range fns {
+		out[i] = &Function{
+			Name:       fn.Name,
+			Recv:       fn.Recv,
+			RecvType:   fn.RecvType,
+			ShortDecl:  fn.ShortDecl,
+			Doc:        cv.doc(lvl, fn.Doc),
+			Decl:       newCode(fn.Decl),
+			Examples:   cv.examples(lvl, fn.Examples),
+			Deprecated: fn.Deprecated,
+		}
+	}
+	return out
+}
+
+func (cv *converter) types(types []*godoc.Type) []*Type {
+	out := make([]*Type, len(types))
+	for i, t := range types {
+		out[i] = &Type{
+			Name:       t.Name,
+			Doc:        cv.doc(4, t.Doc),
+			Decl:       newCode(t.Decl),
+			Constants:  cv.values(t.Constants),
+			Variables:  cv.values(t.Variables),
+			Functions:  cv.funcs(5, t.Functions),
+			Methods:    cv.funcs(5, t.Methods),
+			Examples:   cv.examples(4, t.Examples),
+			Deprecated: t.Deprecated,
+		}
+	}
+	return out
+}
+
+func (cv *converter) examples(lvl int, egs []*godoc.Example) []*Example {
+	out := make([]*Example, len(egs))
+	for i, eg := range egs {
+		id := "example-" + eg.Parent.String()
+		if eg.Suffix != "" {
+			id += "-" + eg.Suffix
+		}
+
+		out[i] = &Example{
+			ID: id,
+			Parent: ExampleParent{
+				Recv: eg.Parent.Recv,
+				Name: eg.Parent.Name,
+			},
+			Suffix: eg.Suffix,
+			Doc:    cv.doc(lvl, eg.Doc),
+			Code:   newCode(eg.Code),
+			Output: eg.Output,
+		}
+	}
+	return out
+}
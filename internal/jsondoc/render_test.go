@@ -0,0 +1,292 @@
+package jsondoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/doc/comment"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/highlight"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/sitegen"
+)
+
+func TestRenderer_RenderPackage(t *testing.T) {
+	t.Parallel()
+
+	pkg := godoc.Package{
+		Name:       "foo",
+		ImportPath: "example.com/foo",
+		Synopsis:   "Package foo does things.",
+		Doc:        parseDoc("Package foo does things."),
+		Constants: []*godoc.Value{
+			{
+				Names: []string{"A", "B"},
+				Decl:  textSpan("const (\n\tA = 1\n\tB = 2\n)"),
+			},
+		},
+		Functions: []*godoc.Function{
+			{
+				Name:       "Bar",
+				Decl:       textSpan("func Bar()"),
+				ShortDecl:  "func Bar()",
+				Deprecated: true,
+				Examples: []*godoc.Example{
+					{
+						Parent: godoc.ExampleParent{Name: "Bar"},
+						Suffix: "loud",
+						Code:   textSpan("foo.Bar()"),
+						Output: "bar\n",
+					},
+				},
+			},
+		},
+		Types: []*godoc.Type{
+			{
+				Name: "Baz",
+				Decl: textSpan("type Baz struct{}"),
+				Methods: []*godoc.Function{
+					{
+						Name:      "Qux",
+						Decl:      textSpan("func (*Baz) Qux()"),
+						ShortDecl: "func (*Baz) Qux()",
+						Recv:      "*Baz",
+						RecvType:  "Baz",
+					},
+				},
+			},
+		},
+	}
+
+	renderer := Renderer{NormalizeRelativePath: indexLink}
+
+	var buff bytes.Buffer
+	require.NoError(t, renderer.RenderPackage(&buff, &html.PackageInfo{
+		Package:    &pkg,
+		PkgVersion: "v1.2.3",
+		DocPrinter: new(html.CommentDocPrinter),
+		Subpackages: []html.Subpackage{
+			{RelativePath: "bar", Synopsis: "Package bar does other things."},
+			{RelativePath: "internal/baz"},
+		},
+	}))
+
+	var got Package
+	require.NoError(t, json.Unmarshal(buff.Bytes(), &got), "invalid JSON:\n%s", buff.String())
+
+	assert.Equal(t, Package{
+		SchemaVersion: SchemaVersion,
+		Kind:          sitegen.PackagePage,
+		ImportPath:    "example.com/foo",
+		Name:          "foo",
+		Synopsis:      "Package foo does things.",
+		PkgVersion:    "v1.2.3",
+		Doc: &Doc{
+			HTML:     "<p>Package foo does things.\n",
+			Markdown: "Package foo does things.\n",
+		},
+		Constants: []*Value{
+			{
+				Names: []string{"A", "B"},
+				Decl:  &Code{Text: "const (\n\tA = 1\n\tB = 2\n)"},
+			},
+		},
+		Functions: []*Function{
+			{
+				Name:      "Bar",
+				ShortDecl: "func Bar()",
+				Decl:      &Code{Text: "func Bar()"},
+				Examples: []*Example{
+					{
+						ID:     "example-Bar-loud",
+						Parent: ExampleParent{Name: "Bar"},
+						Suffix: "loud",
+						Code:   &Code{Text: "foo.Bar()"},
+						Output: "bar\n",
+					},
+				},
+				Deprecated: true,
+			},
+		},
+		Types: []*Type{
+			{
+				Name: "Baz",
+				Decl: &Code{Text: "type Baz struct{}"},
+				Methods: []*Function{
+					{
+						Name:      "Qux",
+						Recv:      "*Baz",
+						RecvType:  "Baz",
+						ShortDecl: "func (*Baz) Qux()",
+						Decl:      &Code{Text: "func (*Baz) Qux()"},
+					},
+				},
+			},
+		},
+		Subpackages: []*Subpackage{
+			{
+				ImportPath:   "example.com/foo/bar",
+				RelativePath: "bar",
+				Link:         "bar/index.json",
+				Synopsis:     "Package bar does other things.",
+			},
+		},
+	}, got)
+}
+
+func TestRenderer_RenderPackage_command(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	require.NoError(t, new(Renderer).RenderPackage(&buff, &html.PackageInfo{
+		Package: &godoc.Package{
+			Name:       "main",
+			BinName:    "foo",
+			ImportPath: "example.com/cmd/foo",
+		},
+		DocPrinter: new(html.CommentDocPrinter),
+	}))
+
+	assert.JSONEq(t, `{
+		"schemaVersion": 1,
+		"kind": "command",
+		"importPath": "example.com/cmd/foo",
+		"name": "main",
+		"binName": "foo"
+	}`, buff.String())
+}
+
+func TestRenderer_RenderPackageIndex(t *testing.T) {
+	t.Parallel()
+
+	renderer := Renderer{
+		Internal:              true,
+		NormalizeRelativePath: indexLink,
+	}
+
+	var buff bytes.Buffer
+	require.NoError(t, renderer.RenderPackageIndex(&buff, &html.PackageIndex{
+		Path: "example.com",
+		Subpackages: []html.Subpackage{
+			{RelativePath: "foo", Synopsis: "Package foo does things."},
+			{RelativePath: "internal/bar"},
+		},
+	}))
+
+	assert.JSONEq(t, `{
+		"schemaVersion": 1,
+		"kind": "directory",
+		"importPath": "example.com",
+		"subpackages": [
+			{
+				"importPath": "example.com/foo",
+				"relativePath": "foo",
+				"link": "foo/index.json",
+				"synopsis": "Package foo does things."
+			},
+			{
+				"importPath": "example.com/internal/bar",
+				"relativePath": "internal/bar",
+				"link": "internal/bar/index.json"
+			}
+		]
+	}`, buff.String())
+}
+
+func TestRenderer_RenderSiteIndex(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	require.NoError(t, new(Renderer).RenderSiteIndex(&buff, &html.SiteIndex{
+		Releases:    []string{"v1.1.0", "v1.0.0"},
+		Others:      []string{"main"},
+		Latest:      "v1.1.0",
+		LatestAlias: "latest",
+	}))
+
+	assert.JSONEq(t, `{
+		"schemaVersion": 1,
+		"releases": ["v1.1.0", "v1.0.0"],
+		"others": ["main"],
+		"latest": "v1.1.0",
+		"latestAlias": "latest"
+	}`, buff.String())
+}
+
+func TestRenderer_RenderRedirect(t *testing.T) {
+	t.Parallel()
+
+	renderer := Renderer{NormalizeRelativePath: indexLink}
+
+	var buff bytes.Buffer
+	require.NoError(t, renderer.RenderRedirect(&buff, &html.Redirect{
+		From: "latest/foo",
+		To:   "v1.1.0/foo",
+	}))
+
+	assert.JSONEq(t, `{
+		"schemaVersion": 1,
+		"redirect": "../../v1.1.0/foo/index.json"
+	}`, buff.String())
+}
+
+func TestRenderer_RenderManifest(t *testing.T) {
+	t.Parallel()
+
+	renderer := Renderer{
+		Home:                  "example.com",
+		NormalizeRelativePath: indexLink,
+	}
+	assert.Equal(t, "manifest.json", renderer.ManifestName())
+
+	var buff bytes.Buffer
+	require.NoError(t, renderer.RenderManifest(&buff, []sitegen.PageResult{
+		{ImportPath: "example.com", Kind: sitegen.DirectoryPage},
+		{ImportPath: "example.com/cmd/foo", Kind: sitegen.CommandPage},
+		{
+			ImportPath: "example.com/foo",
+			Kind:       sitegen.PackagePage,
+			Synopsis:   "Package foo does things.",
+		},
+	}))
+
+	assert.JSONEq(t, `{
+		"schemaVersion": 1,
+		"pages": [
+			{"importPath": "example.com", "kind": "directory", "path": "index.json"},
+			{"importPath": "example.com/cmd/foo", "kind": "command", "path": "cmd/foo/index.json"},
+			{
+				"importPath": "example.com/foo",
+				"kind": "package",
+				"path": "foo/index.json",
+				"synopsis": "Package foo does things."
+			}
+		]
+	}`, buff.String())
+}
+
+func indexLink(s string) string {
+	s = strings.TrimSuffix(s, "/")
+	if s == "" {
+		return "index.json"
+	}
+	return s + "/index.json"
+}
+
+func textSpan(str string) *highlight.Code {
+	return &highlight.Code{
+		Spans: []highlight.Span{
+			&highlight.TextSpan{
+				Text: []byte(str),
+			},
+		},
+	}
+}
+
+func parseDoc(s string) *comment.Doc {
+	return new(comment.Parser).Parse(s)
+}
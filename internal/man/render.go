@@ -0,0 +1,240 @@
+// Package man renders documentation for commands as roff man pages.
+package man
+
+import (
+	"errors"
+	"fmt"
+	"go/doc/comment"
+	"io"
+	"strings"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/html"
+)
+
+// Section of the manual that man pages are generated for.
+const Section = 1
+
+// Renderer renders the documentation for commands as man pages.
+//
+// The package documentation becomes the DESCRIPTION of the page,
+// with headings in it starting new sections.
+// Links in the documentation are turned into numbered footnotes
+// listed in a NOTES section at the end.
+//
+// The zero value is ready to use.
+type Renderer struct{}
+
+// FileName returns the name of the man page file
+// for the given command, e.g. "foo.1".
+func FileName(binName string) string {
+	return fmt.Sprintf("%v.%d", binName, Section)
+}
+
+// RenderPackage renders the man page for a command.
+//
+// It fails if the package isn't a command.
+func (r *Renderer) RenderPackage(w io.Writer, info *html.PackageInfo) error {
+	if info.BinName == "" {
+		return errtrace.Wrap(fmt.Errorf("%v is not a command", info.ImportPath))
+	}
+
+	p := printer{w: w}
+	source := strings.TrimSpace(info.ImportPath + " " + info.PkgVersion)
+	p.macro("TH", strings.ToUpper(info.BinName), fmt.Sprint(Section), "", source, "")
+
+	p.macro("SH", "NAME")
+	name := escape(info.BinName)
+	if synopsis := nameDescription(info.BinName, info.Synopsis); synopsis != "" {
+		name += ` \- ` + escape(synopsis)
+	}
+	p.line(name)
+
+	if info.Doc != nil && len(info.Doc.Content) > 0 {
+		p.macro("SH", "DESCRIPTION")
+		p.blocks(info.Doc.Content)
+	}
+
+	if len(p.notes) > 0 {
+		p.macro("SH", "NOTES")
+		for i, url := range p.notes {
+			p.macro("IP", fmt.Sprintf("%d.", i+1), "4")
+			p.line(escape(url))
+		}
+	}
+
+	return errtrace.Wrap(p.err)
+}
+
+// nameDescription returns the description of a command
+// for the NAME section of its man page.
+//
+// The section already starts with the name of the command,
+// so it's dropped from the start of the synopsis,
+// e.g. "foo does things." becomes "does things".
+func nameDescription(binName, synopsis string) string {
+	synopsis = strings.TrimSuffix(synopsis, ".")
+	if prefix := binName + " "; len(synopsis) > len(prefix) &&
+		strings.EqualFold(synopsis[:len(prefix)], prefix) {
+		synopsis = strings.TrimSpace(synopsis[len(prefix):])
+	}
+	return synopsis
+}
+
+// printer writes roff to a writer.
+// Write errors are recorded in err,
+// and further writes are skipped after the first error.
+type printer struct {
+	w     io.Writer
+	err   error
+	notes []string // URLs of links, in order of appearance
+}
+
+func (p *printer) write(s string) {
+	if p.err == nil {
+		_, p.err = io.WriteString(p.w, s)
+	}
+}
+
+// line writes a line of text that's already been escaped.
+func (p *printer) line(s string) {
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		// Protect lines that would otherwise be read as requests.
+		s = `\&` + s
+	}
+	p.write(s + "\n")
+}
+
+// macro writes a request with the given arguments.
+// Arguments are escaped and quoted.
+func (p *printer) macro(name string, args ...string) {
+	escaped := make([]string, len(args))
+	for i, arg := range args {
+		escaped[i] = escape(arg)
+	}
+	p.request(name, escaped...)
+}
+
+// request writes a request with the given arguments
+// that have already been escaped.
+// Arguments are quoted.
+func (p *printer) request(name string, args ...string) {
+	var sb strings.Builder
+	sb.WriteString(".")
+	sb.WriteString(name)
+	for _, arg := range args {
+		sb.WriteString(` "`)
+		sb.WriteString(strings.ReplaceAll(arg, `"`, `\(dq`))
+		sb.WriteString(`"`)
+	}
+	p.write(sb.String() + "\n")
+}
+
+func (p *printer) blocks(blocks []comment.Block) {
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case *comment.Paragraph:
+			p.macro("PP")
+			p.text(b.Text)
+		case *comment.Heading:
+			var sb strings.Builder
+			plainText(&sb, b.Text)
+			p.macro("SH", strings.ToUpper(sb.String()))
+		case *comment.Code:
+			p.macro("PP")
+			p.macro("EX")
+			for _, line := range strings.Split(strings.TrimSuffix(b.Text, "\n"), "\n") {
+				p.line(escape(line))
+			}
+			p.macro("EE")
+		case *comment.List:
+			p.list(b)
+		default:
+			p.err = errors.Join(p.err, fmt.Errorf("unsupported block %T", b))
+		}
+	}
+}
+
+func (p *printer) list(l *comment.List) {
+	for _, item := range l.Items {
+		tag := `\(bu`
+		if item.Number != "" {
+			tag = escape(item.Number + ".")
+		}
+		p.request("IP", tag, "4")
+
+		for i, block := range item.Content {
+			para, ok := block.(*comment.Paragraph)
+			if !ok {
+				p.blocks([]comment.Block{block})
+				continue
+			}
+			if i > 0 {
+				// Further paragraphs of the same item
+				// keep its indentation.
+				p.macro("IP")
+			}
+			p.text(para.Text)
+		}
+	}
+}
+
+// text writes inline text
+// with links replaced by references to footnotes.
+func (p *printer) text(text []comment.Text) {
+	var sb strings.Builder
+	p.inline(&sb, text)
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			p.line(line)
+		}
+	}
+}
+
+func (p *printer) inline(sb *strings.Builder, text []comment.Text) {
+	for _, t := range text {
+		switch t := t.(type) {
+		case comment.Plain:
+			sb.WriteString(escape(string(t)))
+		case comment.Italic:
+			sb.WriteString(`\fI`)
+			sb.WriteString(escape(string(t)))
+			sb.WriteString(`\fR`)
+		case *comment.Link:
+			p.inline(sb, t.Text)
+			if !t.Auto {
+				p.notes = append(p.notes, t.URL)
+				fmt.Fprintf(sb, "[%d]", len(p.notes))
+			}
+		case *comment.DocLink:
+			// Links to Go symbols aren't useful outside a browser.
+			p.inline(sb, t.Text)
+		}
+	}
+}
+
+// plainText writes the text without any formatting.
+func plainText(sb *strings.Builder, text []comment.Text) {
+	for _, t := range text {
+		switch t := t.(type) {
+		case comment.Plain:
+			sb.WriteString(string(t))
+		case comment.Italic:
+			sb.WriteString(string(t))
+		case *comment.Link:
+			plainText(sb, t.Text)
+		case *comment.DocLink:
+			plainText(sb, t.Text)
+		}
+	}
+}
+
+// _escaper escapes characters that have meaning in roff text.
+var _escaper = strings.NewReplacer(
+	`\`, `\e`,
+	`-`, `\-`,
+)
+
+func escape(s string) string {
+	return _escaper.Replace(s)
+}
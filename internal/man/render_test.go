@@ -0,0 +1,179 @@
+package man
+
+import (
+	"bytes"
+	"go/doc/comment"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/html"
+)
+
+func TestRenderer_RenderPackage(t *testing.T) {
+	t.Parallel()
+
+	doc := new(comment.Parser).Parse(`Foo does things
+to files.
+
+# Usage
+
+Run it like so:
+
+	foo -x file.txt
+	.hidden
+
+The following flags are supported:
+
+  - -x: enable x
+  - -y: see [the docs]
+
+Steps:
+
+ 1. Install it
+ 2. Run it
+
+Read more at https://example.com/foo
+or in _the_ [manual].
+
+[the docs]: https://example.com/docs
+[manual]: https://example.com/manual
+`)
+
+	var buff bytes.Buffer
+	require.NoError(t, new(Renderer).RenderPackage(&buff, &html.PackageInfo{
+		Package: &godoc.Package{
+			Name:       "main",
+			BinName:    "foo",
+			ImportPath: "example.com/cmd/foo",
+			Synopsis:   "Foo does things to files.",
+			Doc:        doc,
+		},
+		PkgVersion: "v1.2.3",
+	}))
+
+	assert.Equal(t, `.TH "FOO" "1" "" "example.com/cmd/foo v1.2.3" ""
+.SH "NAME"
+foo \- does things to files
+.SH "DESCRIPTION"
+.PP
+Foo does things
+to files.
+.SH "USAGE"
+.PP
+Run it like so:
+.PP
+.EX
+foo \-x file.txt
+\&.hidden
+.EE
+.PP
+The following flags are supported:
+.IP "\(bu" "4"
+\-x: enable x
+.IP "\(bu" "4"
+\-y: see the docs[1]
+.PP
+Steps:
+.IP "1." "4"
+Install it
+.IP "2." "4"
+Run it
+.PP
+Read more at https://example.com/foo
+or in _the_ manual[2].
+.SH "NOTES"
+.IP "1." "4"
+https://example.com/docs
+.IP "2." "4"
+https://example.com/manual
+`, buff.String())
+}
+
+func TestRenderer_RenderPackage_minimal(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	require.NoError(t, new(Renderer).RenderPackage(&buff, &html.PackageInfo{
+		Package: &godoc.Package{
+			Name:       "main",
+			BinName:    "my-tool",
+			ImportPath: "example.com/my-tool",
+		},
+	}))
+
+	assert.Equal(t, `.TH "MY\-TOOL" "1" "" "example.com/my\-tool" ""
+.SH "NAME"
+my\-tool
+`, buff.String())
+}
+
+func TestRenderer_RenderPackage_synopsisName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		synopsis string
+		want     string
+	}{
+		{
+			desc:     "starts with name",
+			synopsis: "doc2go generates static HTML documentation.",
+			want:     `doc2go \- generates static HTML documentation`,
+		},
+		{
+			desc:     "starts with capitalized name",
+			synopsis: "Doc2go generates static HTML documentation.",
+			want:     `doc2go \- generates static HTML documentation`,
+		},
+		{
+			desc:     "name is a prefix of a word",
+			synopsis: "doc2gopher generates things.",
+			want:     `doc2go \- doc2gopher generates things`,
+		},
+		{
+			desc:     "only name",
+			synopsis: "doc2go.",
+			want:     `doc2go \- doc2go`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var buff bytes.Buffer
+			require.NoError(t, new(Renderer).RenderPackage(&buff, &html.PackageInfo{
+				Package: &godoc.Package{
+					Name:       "main",
+					BinName:    "doc2go",
+					ImportPath: "go.abhg.dev/doc2go",
+					Synopsis:   tt.synopsis,
+				},
+			}))
+
+			assert.Equal(t, `.TH "DOC2GO" "1" "" "go.abhg.dev/doc2go" ""
+.SH "NAME"
+`+tt.want+"\n", buff.String())
+		})
+	}
+}
+
+func TestRenderer_RenderPackage_notCommand(t *testing.T) {
+	t.Parallel()
+
+	err := new(Renderer).RenderPackage(new(bytes.Buffer), &html.PackageInfo{
+		Package: &godoc.Package{
+			Name:       "foo",
+			ImportPath: "example.com/foo",
+		},
+	})
+	assert.ErrorContains(t, err, "example.com/foo is not a command")
+}
+
+func TestFileName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "foo.1", FileName("foo"))
+}
@@ -0,0 +1,345 @@
+// Package markdown renders GitHub-flavored Markdown from godoc.Package.
+package markdown
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/doc/comment"
+	"io"
+	"strings"
+	"text/template"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/frontmatter"
+	"go.abhg.dev/doc2go/internal/highlight"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/relative"
+)
+
+var (
+	//go:embed tmpl/*.md
+	_tmplFS embed.FS
+
+	// Same trick as the html package:
+	// parse with unusable function references
+	// so that templates are verified at init,
+	// and then Clone and replace them at render time.
+	_packageTmpl = template.Must(
+		template.New("package.md").
+			Funcs((*render)(nil).FuncMap()).
+			ParseFS(_tmplFS, "tmpl/package.md", "tmpl/subpackages.md"),
+	)
+
+	_commandTmpl = template.Must(
+		template.New("command.md").
+			Funcs((*render)(nil).FuncMap()).
+			ParseFS(_tmplFS, "tmpl/command.md", "tmpl/subpackages.md"),
+	)
+
+	_packageIndexTmpl = template.Must(
+		template.New("directory.md").
+			Funcs((*render)(nil).FuncMap()).
+			ParseFS(_tmplFS, "tmpl/directory.md", "tmpl/subpackages.md"),
+	)
+
+	_siteIndexTmpl = template.Must(
+		template.New("siteindex.md").
+			Funcs((*render)(nil).FuncMap()).
+			ParseFS(_tmplFS, "tmpl/siteindex.md"),
+	)
+
+	_redirectTmpl = template.Must(
+		template.New("redirect.md").
+			Funcs((*render)(nil).FuncMap()).
+			ParseFS(_tmplFS, "tmpl/redirect.md"),
+	)
+)
+
+// Renderer renders components into Markdown.
+//
+// It accepts the same inputs as [html.Renderer].
+// Features that require a browser,
+// such as search and version switching,
+// are not supported.
+type Renderer struct {
+	// Path to the home page of the generated site.
+	Home string
+
+	// Internal specifies whether directory listings
+	// should include internal packages.
+	Internal bool
+
+	// FrontMatter to include at the top of each file, if any.
+	FrontMatter *template.Template
+
+	// NormalizeRelativePath is an optional function that
+	// normalizes relative paths printed in the generated Markdown.
+	//
+	// Markdown viewers generally don't resolve links to directories,
+	// so this should add the name of the generated file.
+	NormalizeRelativePath func(string) string
+}
+
+// StaticFiles returns an empty map.
+// Markdown pages don't need any static files.
+func (r *Renderer) StaticFiles() (map[string][]byte, error) {
+	return make(map[string][]byte), nil
+}
+
+// RenderPackage renders the documentation for a single Go package.
+func (r *Renderer) RenderPackage(w io.Writer, info *html.PackageInfo) error {
+	err := frontmatter.Render(w, r.FrontMatter, frontmatter.Data{
+		Path:        info.ImportPath,
+		Basename:    info.Basename(),
+		NumChildren: info.NumChildren,
+		Package: frontmatter.PackageData{
+			Name:     info.Name,
+			Synopsis: info.Synopsis,
+		},
+	})
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	render := render{
+		Path:                  info.ImportPath,
+		DocPrinter:            info.DocPrinter,
+		Internal:              r.Internal,
+		NormalizeRelativePath: r.NormalizeRelativePath,
+	}
+
+	tmpl := _packageTmpl
+	if info.BinName != "" {
+		tmpl = _commandTmpl
+	}
+
+	return errtrace.Wrap(render.execute(w, tmpl, info))
+}
+
+// RenderPackageIndex renders the list of descendants for a package
+// as Markdown.
+func (r *Renderer) RenderPackageIndex(w io.Writer, pidx *html.PackageIndex) error {
+	err := frontmatter.Render(w, r.FrontMatter, frontmatter.Data{
+		Path:        pidx.Path,
+		Basename:    pidx.Basename(),
+		NumChildren: pidx.NumChildren,
+	})
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	render := render{
+		Path:                  pidx.Path,
+		Internal:              r.Internal,
+		NormalizeRelativePath: r.NormalizeRelativePath,
+	}
+	return errtrace.Wrap(render.execute(w, _packageIndexTmpl, pidx))
+}
+
+// RenderSiteIndex renders the list of sub-sites as Markdown.
+func (r *Renderer) RenderSiteIndex(w io.Writer, sidx *html.SiteIndex) error {
+	render := render{
+		Path:                  sidx.Path,
+		NormalizeRelativePath: r.NormalizeRelativePath,
+	}
+	return errtrace.Wrap(render.execute(w, _siteIndexTmpl, sidx))
+}
+
+// RenderRedirect renders a page that links to another page.
+//
+// Markdown can't redirect the reader,
+// so the page only holds a link to the destination.
+func (r *Renderer) RenderRedirect(w io.Writer, redirect *html.Redirect) error {
+	render := render{
+		Path:                  redirect.From,
+		NormalizeRelativePath: r.NormalizeRelativePath,
+	}
+	return errtrace.Wrap(render.execute(w, _redirectTmpl, redirect))
+}
+
+type render struct {
+	Path     string
+	Internal bool
+
+	// DocPrinter converts Go comment.Doc objects into Markdown.
+	DocPrinter html.DocPrinter
+
+	NormalizeRelativePath func(string) string
+}
+
+// execute renders the given template with data into w.
+//
+// Leading and trailing blank lines are dropped
+// so that templates don't have to be careful about them,
+// and the output ends with a single newline.
+func (r *render) execute(w io.Writer, tmpl *template.Template, data any) error {
+	var buff bytes.Buffer
+	err := template.Must(tmpl.Clone()).
+		Funcs(r.FuncMap()).
+		Execute(&buff, data)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	bs := bytes.Trim(buff.Bytes(), "\n")
+	bs = append(bs, '\n')
+	_, err = w.Write(bs)
+	return errtrace.Wrap(err)
+}
+
+func (r *render) FuncMap() template.FuncMap {
+	// NOTE:
+	// This function cannot have any state that relies on reading from the
+	// render struct because it's called at init time with a nil receiver.
+	return template.FuncMap{
+		"doc":  r.doc,
+		"code": code,
+		// fence(lang, text):
+		// Renders text as a fenced code block.
+		"fence": fence,
+		// codeSpan:
+		// Renders text as inline code.
+		"codeSpan": codeSpan,
+		// escape:
+		// Escapes characters that have meaning in Markdown.
+		"escape": escape,
+		// relativePath:
+		// Returns the relative path to the package or directory
+		// identified by the given import path.
+		"relativePath": r.relativePath,
+		// normalizeRelativePath:
+		// Normalizes a relative path
+		// with the user-provided function, if any.
+		"normalizeRelativePath": r.normalizeRelativePath,
+		"filterSubpackages":     r.filterSubpackages,
+		"repeat":                strings.Repeat,
+		"add":                   func(a, b int) int { return a + b },
+		// dict(k1, v1, k2, v2, ...):
+		// Turns key-value pairs into a map.
+		"dict": dict,
+	}
+}
+
+func (r *render) doc(lvl int, doc *comment.Doc) string {
+	if doc == nil {
+		return ""
+	}
+	bs := r.DocPrinter.WithHeadingLevel(lvl).Markdown(doc)
+	return strings.TrimSpace(string(bs))
+}
+
+// Returns the relative path to the package or directory
+// identified by the given import path,
+// based on the package being generated.
+func (r *render) relativePath(p string) string {
+	return r.normalizeRelativePath(relative.Path(r.Path, p))
+}
+
+func (r *render) normalizeRelativePath(p string) string {
+	if f := r.NormalizeRelativePath; f != nil {
+		return f(p)
+	}
+	return p
+}
+
+func (r *render) filterSubpackages(pkgs []html.Subpackage) []html.Subpackage {
+	// No filtering if listing internal packages.
+	if r.Internal {
+		return pkgs
+	}
+
+	filtered := make([]html.Subpackage, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if !pkg.IsInternal() {
+			filtered = append(filtered, pkg)
+		}
+	}
+	return filtered
+}
+
+// code renders a code block as a fenced Go code block.
+// Highlighting, anchors, and links are dropped.
+func code(code *highlight.Code) string {
+	return fence("go", code.Text())
+}
+
+// fence renders text as a fenced code block
+// with the given language, if any.
+//
+// The fence is made long enough
+// that it can't be closed by backticks inside the text.
+func fence(lang, text string) string {
+	delim := strings.Repeat("`", max(3, longestRun(text, '`')+1))
+
+	var sb strings.Builder
+	sb.WriteString(delim)
+	sb.WriteString(lang)
+	sb.WriteByte('\n')
+	if text = strings.TrimRight(text, "\n"); text != "" {
+		sb.WriteString(text)
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(delim)
+	return sb.String()
+}
+
+// codeSpan renders text as inline code.
+func codeSpan(text string) string {
+	delim := strings.Repeat("`", longestRun(text, '`')+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		// Leading or trailing backticks
+		// must be separated from the delimiter.
+		text = " " + text + " "
+	}
+	return delim + text + delim
+}
+
+func longestRun(s string, c byte) int {
+	var longest, run int
+	for i := 0; i < len(s); i++ {
+		if s[i] != c {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// _escaper escapes characters that may be interpreted as Markdown
+// in headings, link text, and table cells.
+var _escaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`#`, `\#`,
+	`|`, `\|`,
+)
+
+func escape(s string) string {
+	return _escaper.Replace(s)
+}
+
+// dict turns key-value pairs into a map.
+// Odd numbered arguments are keys, even numbered arguments are values.
+func dict(args ...any) (map[string]any, error) {
+	if len(args)%2 != 0 {
+		return nil, errtrace.Wrap(fmt.Errorf("dict: odd number of arguments"))
+	}
+	dict := make(map[string]any, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			return nil, errtrace.Wrap(fmt.Errorf("dict: [%d] should be string, got %T", i, args[i]))
+		}
+		dict[key] = args[i+1]
+	}
+	return dict, nil
+}
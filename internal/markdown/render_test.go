@@ -0,0 +1,435 @@
+package markdown
+
+import (
+	"bytes"
+	"go/doc/comment"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/highlight"
+	"go.abhg.dev/doc2go/internal/html"
+)
+
+func TestRenderer_RenderPackage(t *testing.T) {
+	t.Parallel()
+
+	pkg := godoc.Package{
+		Name:       "foo",
+		ImportPath: "example.com/foo",
+		Import:     textSpan(`import "example.com/foo"`),
+		Doc:        parseDoc("Package foo does things.\n\n# Usage\n\nCall [Bar]."),
+		Examples: []*godoc.Example{
+			{
+				Code:   textSpan("fmt.Println(foo.Bar())"),
+				Output: "42\n",
+			},
+		},
+		Constants: []*godoc.Value{
+			{
+				Names: []string{"Answer"},
+				Decl:  textSpan("const Answer = 42"),
+				Doc:   parseDoc("Answer is the answer."),
+			},
+		},
+		Variables: []*godoc.Value{
+			{
+				Names: []string{"Raw"},
+				Decl:  textSpan("var Raw = `a\n```\nb`"),
+			},
+		},
+		Functions: []*godoc.Function{
+			{
+				Name:      "Bar",
+				Decl:      textSpan("func Bar() int"),
+				ShortDecl: "func Bar() int",
+				Doc:       parseDoc("Bar returns the answer."),
+				Examples: []*godoc.Example{
+					{
+						Parent: godoc.ExampleParent{Name: "Bar"},
+						Suffix: "second",
+						Doc:    parseDoc("Call it twice."),
+						Code:   textSpan("foo.Bar()\nfoo.Bar()"),
+					},
+				},
+			},
+			{
+				Name:       "Old_func",
+				Decl:       textSpan("func Old_func()"),
+				ShortDecl:  "func Old_func()",
+				Deprecated: true,
+			},
+		},
+		Types: []*godoc.Type{
+			{
+				Name: "Baz",
+				Decl: textSpan("type Baz struct{}"),
+				Doc:  parseDoc("Baz is a thing."),
+				Functions: []*godoc.Function{
+					{
+						Name:      "NewBaz",
+						Decl:      textSpan("func NewBaz() *Baz"),
+						ShortDecl: "func NewBaz() *Baz",
+					},
+				},
+				Methods: []*godoc.Function{
+					{
+						Name:      "Get",
+						Decl:      textSpan("func (b *Baz) Get() string"),
+						ShortDecl: "func (b *Baz) Get() string",
+						Recv:      "b *Baz",
+						RecvType:  "Baz",
+					},
+				},
+			},
+		},
+	}
+	pkg.AllExamples = []*godoc.Example{pkg.Examples[0], pkg.Functions[0].Examples[0]}
+
+	var buff bytes.Buffer
+	renderer := Renderer{NormalizeRelativePath: readmeLink}
+	require.NoError(t, renderer.RenderPackage(&buff, &html.PackageInfo{
+		Package:    &pkg,
+		DocPrinter: new(html.CommentDocPrinter),
+		Subpackages: []html.Subpackage{
+			{RelativePath: "qux", Synopsis: "Package qux does a | b."},
+			{RelativePath: "internal/quux"},
+		},
+	}))
+
+	want := "# package foo\n" +
+		"\n" +
+		"```go\n" +
+		"import \"example.com/foo\"\n" +
+		"```\n" +
+		"\n" +
+		"Package foo does things.\n" +
+		"\n" +
+		"## Usage\n" +
+		"\n" +
+		"Call \\[Bar].\n" +
+		"\n" +
+		"### <a id=\"example-package\"></a>Example\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(foo.Bar())\n" +
+		"```\n" +
+		"\n" +
+		"Output:\n" +
+		"\n" +
+		"```\n" +
+		"42\n" +
+		"```\n" +
+		"\n" +
+		"## Index\n" +
+		"\n" +
+		"- [Constants](#pkg-constants)\n" +
+		"- [Variables](#pkg-variables)\n" +
+		"- [`func Bar() int`](#Bar)\n" +
+		"- [`func Old_func()`](#Old_func) (deprecated)\n" +
+		"- [`type Baz`](#Baz)\n" +
+		"  - [`func NewBaz() *Baz`](#NewBaz)\n" +
+		"  - [`func (b *Baz) Get() string`](#Baz.Get)\n" +
+		"\n" +
+		"### Examples\n" +
+		"\n" +
+		"- [package](#example-package)\n" +
+		"- [Bar (second)](#example-Bar-second)\n" +
+		"\n" +
+		"## <a id=\"pkg-constants\"></a>Constants\n" +
+		"\n" +
+		"```go\n" +
+		"const Answer = 42\n" +
+		"```\n" +
+		"\n" +
+		"Answer is the answer.\n" +
+		"\n" +
+		"## <a id=\"pkg-variables\"></a>Variables\n" +
+		"\n" +
+		"````go\n" +
+		"var Raw = `a\n" +
+		"```\n" +
+		"b`\n" +
+		"````\n" +
+		"\n" +
+		"## <a id=\"pkg-functions\"></a>Functions\n" +
+		"\n" +
+		"### <a id=\"Bar\"></a>func Bar\n" +
+		"\n" +
+		"```go\n" +
+		"func Bar() int\n" +
+		"```\n" +
+		"\n" +
+		"Bar returns the answer.\n" +
+		"\n" +
+		"#### <a id=\"example-Bar-second\"></a>Example (second)\n" +
+		"\n" +
+		"Call it twice.\n" +
+		"\n" +
+		"```go\n" +
+		"foo.Bar()\n" +
+		"foo.Bar()\n" +
+		"```\n" +
+		"\n" +
+		"### <a id=\"Old_func\"></a>func Old\\_func (deprecated)\n" +
+		"\n" +
+		"```go\n" +
+		"func Old_func()\n" +
+		"```\n" +
+		"\n" +
+		"## <a id=\"pkg-types\"></a>Types\n" +
+		"\n" +
+		"### <a id=\"Baz\"></a>type Baz\n" +
+		"\n" +
+		"```go\n" +
+		"type Baz struct{}\n" +
+		"```\n" +
+		"\n" +
+		"Baz is a thing.\n" +
+		"\n" +
+		"#### <a id=\"NewBaz\"></a>func NewBaz\n" +
+		"\n" +
+		"```go\n" +
+		"func NewBaz() *Baz\n" +
+		"```\n" +
+		"\n" +
+		"#### <a id=\"Baz.Get\"></a>func (b \\*Baz) Get\n" +
+		"\n" +
+		"```go\n" +
+		"func (b *Baz) Get() string\n" +
+		"```\n" +
+		"\n" +
+		"## <a id=\"pkg-directories\"></a>Directories\n" +
+		"\n" +
+		"| Path | Synopsis |\n" +
+		"| --- | --- |\n" +
+		"| [qux](qux/README.md) | Package qux does a \\| b. |\n"
+	assert.Equal(t, want, buff.String())
+}
+
+func TestRenderer_RenderPackage_command(t *testing.T) {
+	t.Parallel()
+
+	fm := template.Must(template.New("").Parse("---\ntitle: {{ .Name }}\n---"))
+	renderer := Renderer{
+		FrontMatter:           fm,
+		NormalizeRelativePath: readmeLink,
+	}
+
+	var buff bytes.Buffer
+	require.NoError(t, renderer.RenderPackage(&buff, &html.PackageInfo{
+		Package: &godoc.Package{
+			Name:       "main",
+			BinName:    "foo",
+			ImportPath: "example.com/cmd/foo",
+			Doc:        parseDoc("Foo does things."),
+		},
+		DocPrinter: new(html.CommentDocPrinter),
+	}))
+
+	assert.Equal(t, "---\n"+
+		"title: foo\n"+
+		"---\n"+
+		"\n"+
+		"# foo\n"+
+		"\n"+
+		"Foo does things.\n", buff.String())
+}
+
+func TestRenderer_RenderPackageIndex(t *testing.T) {
+	t.Parallel()
+
+	subpkgs := []html.Subpackage{
+		{RelativePath: "bar", Synopsis: "Package bar is a *thing*."},
+		{RelativePath: "internal/baz"},
+	}
+
+	tests := []struct {
+		desc     string
+		path     string
+		internal bool
+		want     string
+	}{
+		{
+			desc: "root",
+			want: "## <a id=\"pkg-directories\"></a>Directories\n" +
+				"\n" +
+				"| Path | Synopsis |\n" +
+				"| --- | --- |\n" +
+				"| [bar](bar/README.md) | Package bar is a \\*thing\\*. |\n",
+		},
+		{
+			desc:     "internal",
+			path:     "example.com/foo",
+			internal: true,
+			want: "# example.com/foo\n" +
+				"\n" +
+				"## <a id=\"pkg-directories\"></a>Directories\n" +
+				"\n" +
+				"| Path | Synopsis |\n" +
+				"| --- | --- |\n" +
+				"| [bar](bar/README.md) | Package bar is a \\*thing\\*. |\n" +
+				"| [internal/baz](internal/baz/README.md) |  |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			renderer := Renderer{
+				Internal:              tt.internal,
+				NormalizeRelativePath: readmeLink,
+			}
+
+			var buff bytes.Buffer
+			require.NoError(t, renderer.RenderPackageIndex(&buff, &html.PackageIndex{
+				Path:        tt.path,
+				Subpackages: subpkgs,
+			}))
+			assert.Equal(t, tt.want, buff.String())
+		})
+	}
+}
+
+func TestRenderer_RenderSiteIndex(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	renderer := Renderer{NormalizeRelativePath: readmeLink}
+	require.NoError(t, renderer.RenderSiteIndex(&buff, &html.SiteIndex{
+		Releases:    []string{"v1.1.0", "v1.0.0"},
+		PreReleases: []string{"v2.0.0-rc.1"},
+		Others:      []string{"main"},
+		Latest:      "v1.1.0",
+		LatestAlias: "latest",
+	}))
+
+	assert.Equal(t, "Latest release: [**v1.1.0**](latest/README.md)\n"+
+		"\n"+
+		"## Releases\n"+
+		"\n"+
+		"- [**v1.1.0**](v1.1.0/README.md) (latest)\n"+
+		"- [v1.0.0](v1.0.0/README.md)\n"+
+		"\n"+
+		"## Pre-releases\n"+
+		"\n"+
+		"- [v2.0.0-rc.1](v2.0.0-rc.1/README.md)\n"+
+		"\n"+
+		"## Other versions\n"+
+		"\n"+
+		"- [main](main/README.md)\n", buff.String())
+}
+
+func TestRenderer_RenderSiteIndex_noVersions(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	renderer := Renderer{NormalizeRelativePath: readmeLink}
+	require.NoError(t, renderer.RenderSiteIndex(&buff, &html.SiteIndex{
+		Others: []string{"dev", "main"},
+	}))
+
+	assert.Equal(t, "- [dev](dev/README.md)\n"+
+		"- [main](main/README.md)\n", buff.String())
+}
+
+func TestRenderer_RenderRedirect(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	renderer := Renderer{NormalizeRelativePath: readmeLink}
+	require.NoError(t, renderer.RenderRedirect(&buff, &html.Redirect{
+		From: "latest/foo",
+		To:   "v1.1.0/foo",
+	}))
+
+	assert.Equal(t,
+		"This page has moved to [v1.1.0/foo](../../v1.1.0/foo/README.md).\n",
+		buff.String())
+}
+
+func TestRenderer_StaticFiles(t *testing.T) {
+	t.Parallel()
+
+	files, err := new(Renderer).StaticFiles()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestFence(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		lang string
+		give string
+		want string
+	}{
+		{
+			desc: "empty",
+			want: "```\n```",
+		},
+		{
+			desc: "language",
+			lang: "go",
+			give: "x := 1\n",
+			want: "```go\nx := 1\n```",
+		},
+		{
+			desc: "backticks",
+			give: "a ```` b",
+			want: "`````\na ```` b\n`````",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, fence(tt.lang, tt.give))
+		})
+	}
+}
+
+func TestCodeSpan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give string
+		want string
+	}{
+		{"func Foo()", "`func Foo()`"},
+		{"a ` b", "``a ` b``"},
+		{"`a`", "`` `a` ``"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, codeSpan(tt.give))
+		})
+	}
+}
+
+func textSpan(str string) *highlight.Code {
+	return &highlight.Code{
+		Spans: []highlight.Span{
+			&highlight.TextSpan{
+				Text: []byte(str),
+			},
+		},
+	}
+}
+
+func parseDoc(s string) *comment.Doc {
+	return new(comment.Parser).Parse(s)
+}
+
+func readmeLink(s string) string {
+	return strings.TrimSuffix(s, "/") + "/README.md"
+}
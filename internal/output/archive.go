@@ -0,0 +1,184 @@
+package output
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/errdefer"
+)
+
+// ArchiveFormat is a file format supported by [Archive].
+type ArchiveFormat int
+
+const (
+	// TarGz is a gzip-compressed tarball.
+	TarGz ArchiveFormat = iota + 1
+
+	// Zip is a zip file.
+	Zip
+)
+
+func (f ArchiveFormat) String() string {
+	switch f {
+	case TarGz:
+		return "tar.gz"
+	case Zip:
+		return "zip"
+	default:
+		return fmt.Sprintf("ArchiveFormat(%d)", int(f))
+	}
+}
+
+// ArchiveFormatOf reports the archive format for a file
+// based on its extension: .tar.gz, .tgz, or .zip.
+//
+// It returns false if the file isn't an archive.
+func ArchiveFormatOf(name string) (ArchiveFormat, bool) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return TarGz, true
+	case strings.HasSuffix(name, ".zip"):
+		return Zip, true
+	default:
+		return 0, false
+	}
+}
+
+// Archive is an [FS] that writes files into an archive.
+//
+// Files are held in memory until Close is called.
+// They are then written to the archive sorted by name
+// so that the archive doesn't depend on the order
+// in which files were generated.
+type Archive struct {
+	w       io.Writer
+	format  ArchiveFormat
+	modTime time.Time
+	mem     Memory
+}
+
+var _ ReadFS = (*Archive)(nil)
+
+// NewArchive builds an Archive that writes to w in the given format.
+//
+// Close must be called to write the archive.
+// Close does not close w.
+func NewArchive(w io.Writer, format ArchiveFormat) *Archive {
+	return &Archive{
+		w:       w,
+		format:  format,
+		modTime: time.Now(),
+	}
+}
+
+// Create starts writing a file into the archive.
+func (a *Archive) Create(name string) (io.WriteCloser, error) {
+	return errtrace.Wrap2(a.mem.Create(name))
+}
+
+// ReadFile returns the contents of a file written to the archive.
+func (a *Archive) ReadFile(name string) ([]byte, error) {
+	return errtrace.Wrap2(a.mem.ReadFile(name))
+}
+
+// Close writes all files to the underlying writer.
+func (a *Archive) Close() error {
+	files := a.mem.Files()
+
+	// Record directories explicitly
+	// so that extracted directories get sensible permissions.
+	entries := slices.Collect(maps.Keys(files))
+	dirs := make(map[string]struct{})
+	for _, name := range entries {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir+"/"] = struct{}{}
+		}
+	}
+	entries = slices.AppendSeq(entries, maps.Keys(dirs))
+
+	// Directories sort before their contents
+	// because their names end with '/'.
+	slices.Sort(entries)
+
+	switch a.format {
+	case TarGz:
+		return errtrace.Wrap(a.writeTarGz(entries, files))
+	case Zip:
+		return errtrace.Wrap(a.writeZip(entries, files))
+	default:
+		return errtrace.Wrap(fmt.Errorf("unsupported archive format: %v", a.format))
+	}
+}
+
+func (a *Archive) writeTarGz(entries []string, files map[string][]byte) (err error) {
+	gz := gzip.NewWriter(a.w)
+	defer errdefer.Close(&err, gz)
+
+	tw := tar.NewWriter(gz)
+	defer errdefer.Close(&err, tw)
+
+	for _, name := range entries {
+		hdr := tar.Header{
+			Name:    name,
+			ModTime: a.modTime,
+		}
+		bs, isFile := files[name]
+		if isFile {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Mode = 0o644
+			hdr.Size = int64(len(bs))
+		} else {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Mode = 0o755
+		}
+
+		if err := tw.WriteHeader(&hdr); err != nil {
+			return errtrace.Wrap(err)
+		}
+		if _, err := tw.Write(bs); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+func (a *Archive) writeZip(entries []string, files map[string][]byte) (err error) {
+	zw := zip.NewWriter(a.w)
+	defer errdefer.Close(&err, zw)
+
+	for _, name := range entries {
+		hdr := zip.FileHeader{
+			Name:     name,
+			Modified: a.modTime,
+		}
+		bs, isFile := files[name]
+		if isFile {
+			hdr.Method = zip.Deflate
+			hdr.SetMode(0o644)
+		} else {
+			hdr.SetMode(0o755 | fs.ModeDir)
+		}
+
+		w, err := zw.CreateHeader(&hdr)
+		if err != nil {
+			return errtrace.Wrap(err)
+		}
+		if _, err := w.Write(bs); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package output
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveFormatOf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give   string
+		want   ArchiveFormat
+		wantOK bool
+	}{
+		{give: "site.tar.gz", want: TarGz, wantOK: true},
+		{give: "site.tgz", want: TarGz, wantOK: true},
+		{give: "path/to/SITE.ZIP", want: Zip, wantOK: true},
+		{give: "_site"},
+		{give: "site.tar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := ArchiveFormatOf(tt.give)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestArchive(t *testing.T) {
+	t.Parallel()
+
+	wantEntries := []string{
+		"_/",
+		"_/main.css",
+		"foo/",
+		"foo/bar/",
+		"foo/bar/index.html",
+		"foo/index.html",
+		"index.html",
+	}
+	wantFiles := map[string]string{
+		"_/main.css":         "body {}",
+		"foo/bar/index.html": "bar",
+		"foo/index.html":     "foo",
+		"index.html":         "root",
+	}
+
+	tests := []struct {
+		format ArchiveFormat
+		read   func(t *testing.T, bs []byte) (entries []string, files map[string]string)
+	}{
+		{format: TarGz, read: readTarGz},
+		{format: Zip, read: readZip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			t.Parallel()
+
+			var buff bytes.Buffer
+			archive := NewArchive(&buff, tt.format)
+
+			// Write in an order different from the expected one.
+			writeFile(t, archive, "index.html", "root")
+			writeFile(t, archive, "foo/index.html", "foo")
+			writeFile(t, archive, "foo/bar/index.html", "bar")
+			writeFile(t, archive, "_/main.css", "body {}")
+			assert.Zero(t, buff.Len(), "nothing must be written before Close")
+			require.NoError(t, archive.Close())
+
+			entries, files := tt.read(t, buff.Bytes())
+			assert.Equal(t, wantEntries, entries)
+			assert.Equal(t, wantFiles, files)
+		})
+	}
+}
+
+func TestArchive_unknownFormat(t *testing.T) {
+	t.Parallel()
+
+	err := NewArchive(io.Discard, 0).Close()
+	assert.ErrorContains(t, err, "unsupported archive format")
+}
+
+func readTarGz(t *testing.T, bs []byte) (entries []string, files map[string]string) {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(bs))
+	require.NoError(t, err)
+
+	files = make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		entries = append(entries, hdr.Name)
+		if hdr.Typeflag == tar.TypeReg {
+			body, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			files[hdr.Name] = string(body)
+		}
+	}
+	return entries, files
+}
+
+func readZip(t *testing.T, bs []byte) (entries []string, files map[string]string) {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(bs), int64(len(bs)))
+	require.NoError(t, err)
+
+	files = make(map[string]string)
+	for _, f := range zr.File {
+		entries = append(entries, f.Name)
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		r, err := f.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		files[f.Name] = string(body)
+	}
+	return entries, files
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"maps"
+	"sync"
+
+	"braces.dev/errtrace"
+)
+
+// Memory is an [FS] that holds files in memory.
+//
+// The zero value is ready to use.
+type Memory struct {
+	mu    sync.Mutex
+	files map[string][]byte // name => contents
+}
+
+var _ ReadFS = (*Memory)(nil)
+
+// Create starts writing a file in memory.
+// The file becomes visible in [Memory.Files]
+// only after the returned writer is closed.
+func (m *Memory) Create(name string) (io.WriteCloser, error) {
+	if err := validPath(name); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	return &memoryFile{mem: m, name: name}, nil
+}
+
+// Files returns a copy of the files written so far,
+// keyed by their /-separated names.
+func (m *Memory) Files() map[string][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files := maps.Clone(m.files)
+	if files == nil {
+		files = make(map[string][]byte)
+	}
+	return files
+}
+
+// ReadFile returns the contents of a file written to memory.
+func (m *Memory) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bs, ok := m.files[name]
+	if !ok {
+		return nil, errtrace.Wrap(&fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist})
+	}
+	return bytes.Clone(bs), nil
+}
+
+func (m *Memory) put(name string, bs []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+	}
+	m.files[name] = bs
+}
+
+type memoryFile struct {
+	mem  *Memory
+	name string
+	buff bytes.Buffer
+}
+
+func (f *memoryFile) Write(bs []byte) (int, error) {
+	return errtrace.Wrap2(f.buff.Write(bs))
+}
+
+func (f *memoryFile) Close() error {
+	f.mem.put(f.name, f.buff.Bytes())
+	return nil
+}
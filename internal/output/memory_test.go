@@ -0,0 +1,45 @@
+package output
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+
+	var mem Memory
+	assert.Empty(t, mem.Files())
+
+	w, err := mem.Create("foo/index.html")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.NoError(t, err)
+	assert.Empty(t, mem.Files(), "file must not be visible until closed")
+	require.NoError(t, w.Close())
+
+	writeFile(t, &mem, "index.html", "root")
+	writeFile(t, &mem, "index.html", "overwritten")
+
+	assert.Equal(t, map[string][]byte{
+		"foo/index.html": []byte("hello"),
+		"index.html":     []byte("overwritten"),
+	}, mem.Files())
+}
+
+func TestMemory_ReadFile(t *testing.T) {
+	t.Parallel()
+
+	var mem Memory
+	writeFile(t, &mem, "foo/index.html", "hello")
+
+	bs, err := mem.ReadFile("foo/index.html")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(bs))
+
+	_, err = mem.ReadFile("bar/index.html")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,64 @@
+// Package output defines destinations for generated files.
+package output
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"braces.dev/errtrace"
+)
+
+// FS is a destination for generated files.
+//
+// Implementations must be safe for concurrent use.
+type FS interface {
+	// Create creates or truncates the file with the given name
+	// and returns a writer for its contents.
+	// Parent directories are created as needed.
+	//
+	// Names are /-separated paths relative to the root of the FS,
+	// and must be valid per [fs.ValidPath].
+	//
+	// The file is complete only after the writer is closed.
+	Create(name string) (io.WriteCloser, error)
+}
+
+// ReadFS is an [FS] that can read back files written to it.
+type ReadFS interface {
+	FS
+
+	// ReadFile returns the contents of a file
+	// that was previously written to the FS.
+	// It returns an error matching [fs.ErrNotExist]
+	// if the file hasn't been written.
+	ReadFile(name string) ([]byte, error)
+}
+
+// Dir is an [FS] that writes files into a directory on disk.
+type Dir string
+
+var _ FS = Dir("")
+
+// Create creates a file inside the directory.
+func (d Dir) Create(name string) (io.WriteCloser, error) {
+	if err := validPath(name); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	path := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o1755); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	return errtrace.Wrap2(os.Create(path))
+}
+
+func validPath(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return errtrace.Wrap(fmt.Errorf("invalid file name %q", name))
+	}
+	return nil
+}
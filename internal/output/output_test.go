@@ -0,0 +1,57 @@
+package output
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, Dir(dir), "foo/bar/index.html", "hello")
+
+	bs, err := os.ReadFile(filepath.Join(dir, "foo", "bar", "index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(bs))
+}
+
+func TestInvalidPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		give string
+	}{
+		{desc: "empty", give: ""},
+		{desc: "root", give: "."},
+		{desc: "parent", give: "../foo"},
+		{desc: "absolute", give: "/foo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			for _, out := range []FS{Dir(t.TempDir()), new(Memory)} {
+				_, err := out.Create(tt.give)
+				assert.ErrorContains(t, err, "invalid file name")
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, out FS, name, body string) {
+	t.Helper()
+
+	w, err := out.Create(name)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, body)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
@@ -7,12 +7,18 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"os/exec"
+	"time"
 
 	"braces.dev/errtrace"
 	"go.abhg.dev/doc2go/internal/linebuf"
 )
 
+// _gracePeriod is how long pagefind is given to exit
+// after it's interrupted before it's killed.
+const _gracePeriod = 5 * time.Second
+
 // CLI is a handle to the pagefind CLI,
 // which is used to generate a search index for the documentation.
 type CLI struct {
@@ -62,7 +68,20 @@ func (c *CLI) Index(ctx context.Context, req IndexRequest) error {
 	cmd := exec.CommandContext(ctx, exe, args...)
 	cmd.Stdout = out
 	cmd.Stderr = out
+	cmd.Cancel = func() error {
+		// Give pagefind a chance to exit cleanly
+		// before it's killed.
+		// Interrupt isn't supported on all platforms.
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return errtrace.Wrap(cmd.Process.Kill())
+		}
+		return nil
+	}
+	cmd.WaitDelay = _gracePeriod
 	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
 		return errtrace.Wrap(fmt.Errorf("pagefind: %w", err))
 	}
 
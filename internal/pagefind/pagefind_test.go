@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -107,6 +108,9 @@ var _fakePagefindBehaviors = map[string]func(pagefindArgs){
 	"fail": func(pagefindArgs) {
 		log.Fatal("fake pagefind failed")
 	},
+	"hang": func(pagefindArgs) {
+		time.Sleep(time.Minute)
+	},
 }
 
 func TestCLISuccess(t *testing.T) {
@@ -179,3 +183,24 @@ func TestCLIFailure(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "pagefind:")
 }
+
+func TestCLICancel(t *testing.T) {
+	t.Setenv("PATH", _fakeBinDir)
+	t.Setenv("TEST_PAGEFIND_BEHAVIOR", "hang")
+
+	c := CLI{
+		Pagefind: _fakePagefind,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := c.Index(ctx, IndexRequest{
+		SiteDir: t.TempDir(),
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), _gracePeriod+time.Second,
+		"pagefind must be stopped when the context is cancelled")
+}
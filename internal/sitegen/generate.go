@@ -0,0 +1,1684 @@
+// Package sitegen generates documentation websites for Go packages
+// from parsed package references.
+//
+// It's shared by the doc2go command and the public docgen package.
+package sitegen
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/doc/comment"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/buildcache"
+	"go.abhg.dev/doc2go/internal/errdefer"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/output"
+	"go.abhg.dev/doc2go/internal/pagefind"
+	"go.abhg.dev/doc2go/internal/pathtree"
+	"go.abhg.dev/doc2go/internal/pathx"
+	"go.abhg.dev/doc2go/internal/relative"
+	"go.abhg.dev/doc2go/internal/sliceutil"
+)
+
+// Parser loads a package reference from disk
+// and parses its contents.
+type Parser interface {
+	ParsePackage(*gosrc.PackageRef) (*gosrc.Package, error)
+}
+
+var _ Parser = (*gosrc.Parser)(nil)
+
+// Assembler consumes a parsed Go source package,
+// and builds a documentation representation of it.
+type Assembler interface {
+	Assemble(*gosrc.Package) (*godoc.Package, error)
+}
+
+var _ Assembler = (*godoc.Assembler)(nil)
+
+// Renderer renders a Go package's documentation to HTML.
+type Renderer interface {
+	StaticFiles() (map[string][]byte, error)
+	RenderPackage(io.Writer, *html.PackageInfo) error
+	RenderPackageIndex(io.Writer, *html.PackageIndex) error
+	RenderSiteIndex(io.Writer, *html.SiteIndex) error
+	RenderRedirect(io.Writer, *html.Redirect) error
+}
+
+var _ Renderer = (*html.Renderer)(nil)
+
+// ManifestRenderer is an optional interface for a [Renderer]
+// that also describes all pages of the site in a single file.
+type ManifestRenderer interface {
+	// ManifestName is the name of the manifest file
+	// relative to the root of the site.
+	ManifestName() string
+
+	// RenderManifest renders the manifest
+	// for the given pages, sorted by import path.
+	RenderManifest(io.Writer, []PageResult) error
+}
+
+// PageIndexer generates a search index for a website.
+type PageIndexer interface {
+	Index(context.Context, pagefind.IndexRequest) error
+}
+
+var _ PageIndexer = (*pagefind.CLI)(nil)
+
+// PackageCache remembers packages rendered by previous runs
+// so that unchanged packages may be skipped.
+type PackageCache interface {
+	// Lookup reports whether the package at importPath
+	// was previously rendered with the given hash,
+	// and if so, returns what was recorded about it.
+	Lookup(importPath, hash string) (pkg buildcache.Package, ok bool)
+
+	// Store records that the package at importPath
+	// was rendered with the given hash.
+	Store(importPath, hash string, pkg buildcache.Package)
+}
+
+var _ PackageCache = (*buildcache.Cache)(nil)
+
+// CleanMode specifies what a Generator does with files
+// in the output directory that weren't generated by the current run.
+type CleanMode int
+
+const (
+	// CleanDisabled leaves such files alone.
+	CleanDisabled CleanMode = iota
+
+	// CleanEnabled deletes such files.
+	CleanEnabled
+
+	// CleanDryRun logs such files without deleting them.
+	CleanDryRun
+)
+
+// Generator generates documentation for user-specified Go packages.
+type Generator struct {
+	// Log receives messages about files that would be deleted
+	// when Clean is CleanDryRun.
+	Log *log.Logger
+
+	DebugLog *log.Logger
+
+	// Parser parses package information from PackageRefs.
+	Parser Parser // required
+
+	// Assembler converts parsed Package ASTs
+	// into a documentation-level IR.
+	Assembler Assembler // required
+
+	// Renderer renders a package's documentation IR
+	// into HTML.
+	Renderer Renderer // required
+
+	// Pagefind specifies how to generate a search index
+	// for the documentation.
+	//
+	// If nil, a search index will not be generated.
+	Pagefind PageIndexer
+
+	DocLinker godoc.Linker // required
+
+	// OutDir is the destination directory.
+	// It will be created it if it doesn't exist.
+	//
+	// OutDir is ignored if Output is set.
+	OutDir string
+
+	// Output, if set, receives generated files instead of OutDir.
+	// Use this to write the site into memory, an archive,
+	// or another custom destination.
+	//
+	// Pagefind, Cache, and Clean need files on disk,
+	// so they cannot be used with Output.
+	Output output.FS
+
+	// SubDir is an optional subdirectory inside OutDir.
+	// If speciifed, pages will be generated under OutDir/SubDir,
+	// and OutDir will get an index of siblings of SubDir.
+	//
+	// SubDir MUST NOT contain '/'.
+	SubDir     string
+	PkgVersion string
+
+	// SkipSiblingIndex stops Generate from writing
+	// the index of siblings of SubDir.
+	// Use this when generating several subdirectories in a row
+	// so that only the last one writes the index.
+	SkipSiblingIndex bool
+
+	// Siblings lists subdirectories written to Output
+	// by earlier Generators.
+	// They're listed in the sibling index alongside SubDir.
+	//
+	// Siblings is ignored unless Output is set:
+	// siblings in OutDir are found on disk.
+	Siblings []string
+
+	// Basename of generated files.
+	//
+	// Defaults to index.html.
+	Basename string
+
+	// Home page of the documentation.
+	// Anything not under this path will be discarded.
+	Home string
+
+	// Cache, if set, is used to skip packages
+	// whose inputs haven't changed since a previous run.
+	//
+	// Directory listings are always regenerated.
+	Cache PackageCache
+
+	// Jobs is the maximum number of packages
+	// that will be parsed, assembled, and rendered concurrently.
+	//
+	// Defaults to GOMAXPROCS.
+	Jobs int
+
+	// Clean specifies whether files under OutDir/SubDir
+	// that weren't generated by this run should be deleted
+	// after the documentation has been generated.
+	//
+	// CleanEnabled is refused if OutDir/SubDir
+	// contains the working directory.
+	Clean CleanMode
+
+	// InPlace writes files directly into OutDir
+	// instead of staging them until generation succeeds.
+	//
+	// Use this if other programs watch OutDir for changes:
+	// replacing the directory after every run breaks their watches.
+	// If generation fails, OutDir may be left partially updated.
+	InPlace bool
+
+	// Preserve lists additional files under OutDir/SubDir
+	// that must not be deleted by Clean.
+	Preserve []string
+
+	// Sitemap, if set, specifies that a sitemap and robots.txt
+	// should be written to the root of the output directory.
+	// robots.txt is written only if the output directory
+	// is served from the root of its host.
+	//
+	// With SubDir, these list pages of all sibling sites,
+	// and they're written alongside the sibling index.
+	Sitemap *Sitemap
+
+	// SymbolIndex, if set, specifies that an index of packages
+	// and their symbols should be written to the StaticDir of the site
+	// for the renderer's search box.
+	SymbolIndex *SymbolIndex
+
+	// KeepGoing specifies whether generation should continue
+	// if documentation for a package could not be generated.
+	// Failed packages are left out of their parents' listings.
+	//
+	// If any packages failed, Generate returns a [*PackagesError]
+	// after writing the rest of the documentation.
+	//
+	// Without KeepGoing, the first failure stops work
+	// on packages that haven't started yet.
+	KeepGoing bool
+
+	once sync.Once
+	sema chan struct{} // limits concurrent package work
+
+	// rootDir is OutDir, or empty if writing to Output.
+	// siteDir is rootDir/SubDir, where pages are placed.
+	// Pages are written to stageDir first,
+	// and moved into siteDir only if generation succeeds.
+	//
+	// If stageDir is empty, pages are written to siteDir directly.
+	//
+	// With SubDir, the StaticDir shared by all sites in rootDir
+	// is similarly staged in staticStageDir,
+	// the latest alias in aliasStageDir,
+	// and files at the top of rootDir (e.g. the sibling index)
+	// in rootStageDir.
+	rootDir        string
+	siteDir        string
+	stageDir       string
+	staticStageDir string
+	aliasStageDir  string
+	rootStageDir   string
+
+	mu        sync.Mutex
+	generated map[string]struct{} // files in siteDir written by this run
+	keepDirs  []string            // directories that must be kept wholesale
+	written   []string            // see Result.Files
+	packages  []PackageResult     // see Result.Packages
+	pages     []PageResult        // see Result.Pages
+	timings   Timings             // see Result.Timings
+
+	// Hash of the set of packages being documented.
+	// Used to invalidate cached packages
+	// when links between local packages may have changed.
+	siteHash []byte
+
+	// canonicalSite is the sibling site with the preferred versions
+	// of pages listed in canonicalPages,
+	// or of all pages if canonicalPages is nil.
+	// See findCanonicalSite.
+	canonicalSite  string
+	canonicalPages map[string]struct{}
+
+	// symbols holds entries of the SymbolIndex by import path.
+	// prevSymbols holds the entries written by a previous run,
+	// for packages skipped by the Cache.
+	symbols     map[string]*symbolPackage // guarded by mu
+	prevSymbols map[string]*symbolPackage
+}
+
+func (r *Generator) init() {
+	r.once.Do(func() {
+		if r.Log == nil {
+			r.Log = log.New(io.Discard, "", 0)
+		}
+		if r.DebugLog == nil {
+			r.DebugLog = log.New(io.Discard, "", 0)
+		}
+		if r.Basename == "" {
+			r.Basename = "index.html"
+		}
+		if r.Jobs <= 0 {
+			r.Jobs = runtime.GOMAXPROCS(0)
+		}
+		r.sema = make(chan struct{}, r.Jobs)
+		r.generated = make(map[string]struct{})
+		for _, path := range r.Preserve {
+			r.generated[filepath.Clean(path)] = struct{}{}
+		}
+	})
+}
+
+// acquire blocks until the Generator is allowed to start
+// a unit of package work, or the context is cancelled.
+// The returned function must be called to release the slot.
+func (r *Generator) acquire(ctx context.Context) (release func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	select {
+	case r.sema <- struct{}{}:
+		return func() { <-r.sema }, nil
+	case <-ctx.Done():
+		return nil, errtrace.Wrap(ctx.Err())
+	}
+}
+
+// Result describes the outcome of [Generator.Generate].
+type Result struct {
+	// Files written by the Generator, sorted.
+	// These are /-separated paths relative to OutDir,
+	// or to the root of Output if that was used.
+	Files []string
+
+	// Packages that the Generator attempted to document,
+	// sorted by import path.
+	Packages []PackageResult
+
+	// Pages for packages and directories in the site,
+	// sorted by import path.
+	Pages []PageResult
+
+	// Timings of each stage of generation.
+	Timings Timings
+}
+
+// PackageResult is the outcome of documenting a single package.
+type PackageResult struct {
+	ImportPath string
+
+	// Err is non-nil if documentation for the package
+	// could not be generated.
+	Err error
+
+	// Cached is set if the package was unchanged since the last run,
+	// and its page was not regenerated.
+	// Stats for cached packages are those recorded by the run
+	// that rendered it.
+	Cached bool
+
+	// Stats about symbols in the package.
+	Stats godoc.Stats
+}
+
+// PageKind specifies what a page documents.
+type PageKind string
+
+// Kinds of pages generated for packages and directories.
+const (
+	PackagePage   PageKind = "package"
+	CommandPage   PageKind = "command"   // package main
+	DirectoryPage PageKind = "directory" // no package
+)
+
+// PageResult is a page generated for a package or directory.
+type PageResult struct {
+	// ImportPath of the package or directory.
+	ImportPath string
+
+	// File that the page was written to,
+	// in the same format as [Result.Files].
+	File string
+
+	Kind PageKind
+
+	// Synopsis of the package.
+	// Empty for directories.
+	Synopsis string
+}
+
+// Timings is the total time spent in each stage of generation.
+//
+// Packages are processed concurrently,
+// so these may add up to more than the time Generate took.
+type Timings struct {
+	Parse    time.Duration
+	Assemble time.Duration
+	Render   time.Duration
+	Pagefind time.Duration
+}
+
+// Generate runs the generator over the provided packages.
+//
+// Unless InPlace is set, pages, static files, and the sibling index
+// are written to staging directories
+// and moved into OutDir only if generation succeeds.
+// If it fails or the context is cancelled,
+// the existing contents of OutDir are left untouched.
+//
+// The returned Result is non-nil even if generation fails.
+func (r *Generator) Generate(ctx context.Context, pkgRefs []*gosrc.PackageRef) (*Result, error) {
+	r.init()
+
+	r.mu.Lock()
+	r.written = nil
+	r.packages = nil
+	r.pages = nil
+	r.timings = Timings{}
+	r.mu.Unlock()
+
+	err := r.generate(ctx, pkgRefs)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := Result{
+		Files: slices.Sorted(slices.Values(r.written)),
+		Packages: slices.SortedFunc(slices.Values(r.packages), func(a, b PackageResult) int {
+			return strings.Compare(a.ImportPath, b.ImportPath)
+		}),
+		Pages: slices.SortedFunc(slices.Values(r.pages), func(a, b PageResult) int {
+			return strings.Compare(a.ImportPath, b.ImportPath)
+		}),
+		Timings: r.timings,
+	}
+
+	// Without KeepGoing, the first failure has already been reported.
+	if err == nil {
+		var failed []PackageResult
+		for _, pkg := range res.Packages {
+			if pkg.Err != nil {
+				failed = append(failed, pkg)
+			}
+		}
+		if len(failed) > 0 {
+			err = &PackagesError{Packages: failed}
+		}
+	}
+	return &res, errtrace.Wrap(err)
+}
+
+// PackagesError is returned by [Generator.Generate] with KeepGoing
+// if documentation for some packages could not be generated.
+type PackagesError struct {
+	// Packages that failed, sorted by import path.
+	// Err is non-nil for all of them.
+	Packages []PackageResult
+}
+
+func (e *PackagesError) Error() string {
+	var sb strings.Builder
+	if len(e.Packages) == 1 {
+		sb.WriteString("1 package failed:")
+	} else {
+		fmt.Fprintf(&sb, "%d packages failed:", len(e.Packages))
+	}
+	for _, pkg := range e.Packages {
+		fmt.Fprintf(&sb, "\n  %v: %v", pkg.ImportPath, pkg.Err)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the errors for each failed package.
+func (e *PackagesError) Unwrap() []error {
+	errs := make([]error, len(e.Packages))
+	for i, pkg := range e.Packages {
+		errs[i] = pkg.Err
+	}
+	return errs
+}
+
+func (r *Generator) generate(ctx context.Context, pkgRefs []*gosrc.PackageRef) (err error) {
+	if r.Output != nil {
+		if r.Pagefind != nil || r.Cache != nil || r.Clean != CleanDisabled {
+			return errtrace.Wrap(errors.New("search index, cache, and clean require an output directory"))
+		}
+	} else {
+		r.rootDir = r.OutDir
+	}
+
+	r.siteDir = filepath.Join(r.rootDir, r.SubDir)
+	r.stageDir, r.staticStageDir, r.aliasStageDir, r.rootStageDir = "", "", "", ""
+	defer func() {
+		// On success, commit moved the staging directories away.
+		if err != nil {
+			for _, dir := range []string{r.stageDir, r.staticStageDir, r.aliasStageDir, r.rootStageDir} {
+				if dir != "" {
+					err = errtrace.Wrap(errors.Join(err, os.RemoveAll(dir)))
+				}
+			}
+		}
+	}()
+
+	if r.Output == nil && r.Clean == CleanEnabled {
+		// Writing in place would delete the files we're documenting.
+		if ok, err := containsWorkDir(r.siteDir); err != nil {
+			return errtrace.Wrap(err)
+		} else if ok {
+			return errtrace.Wrap(fmt.Errorf("cannot clean %v: it contains the working directory", r.siteDir))
+		}
+	}
+
+	if r.Output == nil && !r.InPlace {
+		r.stageDir, err = r.newStageDir(r.siteDir)
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("create staging directory: %w", err))
+		}
+	}
+	if r.stageDir != "" && r.SubDir != "" {
+		r.staticStageDir, err = r.newStageDir(r.sharedStaticDir())
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("create staging directory: %w", err))
+		}
+
+		// Other sites live in rootDir, so it can't be replaced.
+		// Its own files are staged inside it instead.
+		r.rootStageDir, err = os.MkdirTemp(r.rootDir, ".doc2go.tmp-*")
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("create staging directory: %w", err))
+		}
+	}
+
+	if r.Cache != nil {
+		r.siteHash = hashImportPaths(pkgRefs)
+	}
+
+	if err := r.findCanonicalSite(); err != nil {
+		return errtrace.Wrap(fmt.Errorf("find latest version: %w", err))
+	}
+
+	r.symbols = make(map[string]*symbolPackage)
+	if err := r.loadPrevSymbols(); err != nil {
+		return errtrace.Wrap(fmt.Errorf("read symbol index: %w", err))
+	}
+
+	trees := buildTrees(pkgRefs)
+	if r.Home != "" {
+		trees = filterTrees(r.Home, trees)
+	}
+
+	if _, err := r.renderTrees(ctx, nil, trees); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	if err := r.writeStatic(); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	if err := r.writePagesManifest(); err != nil {
+		return errtrace.Wrap(fmt.Errorf("write pages manifest: %w", err))
+	}
+
+	if mr, ok := r.Renderer.(ManifestRenderer); ok {
+		if err := r.writeManifest(mr); err != nil {
+			return errtrace.Wrap(fmt.Errorf("write manifest: %w", err))
+		}
+	}
+
+	if r.SymbolIndex != nil {
+		if err := r.writeSymbolIndex(); err != nil {
+			return errtrace.Wrap(fmt.Errorf("write symbol index: %w", err))
+		}
+	}
+
+	if r.Sitemap != nil && r.SubDir == "" {
+		if err := r.writeSitemap(r.pagePaths()); err != nil {
+			return errtrace.Wrap(fmt.Errorf("write sitemap: %w", err))
+		}
+	}
+
+	if r.Pagefind != nil {
+		req := pagefind.IndexRequest{
+			SiteDir:     r.stagePath(r.siteDir),
+			AssetSubdir: filepath.Join(html.StaticDir, "pagefind"),
+		}
+
+		start := time.Now()
+		err := r.Pagefind.Index(ctx, req)
+		r.addTiming(&r.timings.Pagefind, start)
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("generate search index: %w", err))
+		}
+
+		// Pagefind decides the names of its own files,
+		// so we keep everything it may have produced.
+		r.keepDir(filepath.Join(r.siteDir, req.AssetSubdir))
+
+		r.DebugLog.Printf("Generated search index in %v", req.AssetSubdir)
+	}
+
+	if !r.SkipSiblingIndex {
+		if err := r.generateSiblingIndex(); err != nil {
+			return errtrace.Wrap(fmt.Errorf("generate version index: %w", err))
+		}
+	}
+
+	// With a staging directory, commit leaves stale files behind instead.
+	if r.Clean != CleanDisabled && r.stageDir == "" {
+		if _, err := os.Stat(r.siteDir); err == nil {
+			if _, err := r.clean(r.siteDir); err != nil {
+				return errtrace.Wrap(fmt.Errorf("clean: %w", err))
+			}
+		}
+	}
+
+	// Past this point, the new site is in place.
+	// Don't abandon it halfway.
+	if err := r.commit(); err != nil {
+		return errtrace.Wrap(fmt.Errorf("move output into place: %w", err))
+	}
+
+	return nil
+}
+
+// containsWorkDir reports whether dir is or contains
+// the working directory.
+func containsWorkDir(dir string) (bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, errtrace.Wrap(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return false, errtrace.Wrap(err)
+	}
+
+	rel, err := filepath.Rel(dir, wd)
+	return err == nil && filepath.IsLocal(rel), nil
+}
+
+// newStageDir creates a temporary directory next to dir
+// to hold its files until generation succeeds.
+//
+// It returns an empty string if files must be written
+// to dir directly:
+// this is the case if dir contains the working directory,
+// which can't be moved out from under us.
+func (r *Generator) newStageDir(dir string) (string, error) {
+	if ok, err := containsWorkDir(dir); err != nil {
+		return "", errtrace.Wrap(err)
+	} else if ok {
+		r.DebugLog.Printf("Output directory contains the working directory. Writing files in place.")
+		return "", nil
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errtrace.Wrap(err)
+	}
+
+	// The staging directory must be on the same filesystem as dir
+	// so that it can be renamed into place.
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0o1755); err != nil {
+		return "", errtrace.Wrap(err)
+	}
+
+	stageDir, err := os.MkdirTemp(parent, "."+filepath.Base(dir)+".tmp-*")
+	if err != nil {
+		return "", errtrace.Wrap(err)
+	}
+
+	// MkdirTemp creates directories only accessible by the owner.
+	if err := os.Chmod(stageDir, 0o1755); err != nil {
+		return "", errtrace.Wrap(errors.Join(err, os.Remove(stageDir)))
+	}
+
+	r.DebugLog.Printf("Staging files for %v in %v", dir, stageDir)
+	return stageDir, nil
+}
+
+// sharedStaticDir returns the StaticDir inside OutDir.
+// With SubDir, it's shared by all sites in OutDir.
+func (r *Generator) sharedStaticDir() string {
+	return filepath.Join(r.rootDir, html.StaticDir)
+}
+
+// latestAliasDir returns the directory of the latest alias
+// next to versioned sites.
+func (r *Generator) latestAliasDir() string {
+	return filepath.Join(r.rootDir, _latestAlias)
+}
+
+// stagePath returns the path that a file destined for path
+// should be written to.
+// Paths inside siteDir, the shared StaticDir, and the latest alias,
+// and files at the top of rootDir
+// are redirected to their staging directories.
+func (r *Generator) stagePath(path string) string {
+	for _, d := range []struct{ dir, stage string }{
+		{r.siteDir, r.stageDir},
+		{r.sharedStaticDir(), r.staticStageDir},
+		{r.latestAliasDir(), r.aliasStageDir},
+	} {
+		if d.stage == "" {
+			continue
+		}
+
+		rel, err := filepath.Rel(d.dir, path)
+		if err == nil && (filepath.IsLocal(rel) || rel == ".") {
+			return filepath.Join(d.stage, rel)
+		}
+	}
+
+	if r.rootStageDir != "" && filepath.Dir(path) == filepath.Clean(r.rootDir) {
+		return filepath.Join(r.rootStageDir, filepath.Base(path))
+	}
+	return path
+}
+
+// leftoverMode specifies what replaceDir does with files
+// in the old directory that weren't written by this run.
+type leftoverMode int
+
+const (
+	// keepLeftovers carries them over to the new directory.
+	keepLeftovers leftoverMode = iota
+
+	// cleanLeftovers carries them over
+	// unless Clean says they're stale.
+	cleanLeftovers
+
+	// dropLeftovers discards them.
+	dropLeftovers
+)
+
+// commit moves the staging directories into place.
+//
+// The shared StaticDir goes first
+// so that the new pages never refer to missing assets.
+// It's shared with other sites, so nothing is ever removed from it.
+// The latest alias and the sibling index go last
+// so that they never point to pages that don't exist yet.
+func (r *Generator) commit() error {
+	if r.staticStageDir != "" {
+		if err := r.replaceDir(r.sharedStaticDir(), r.staticStageDir, keepLeftovers); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+
+	if r.stageDir != "" {
+		mode := keepLeftovers
+		if r.Clean != CleanDisabled {
+			mode = cleanLeftovers
+		}
+		if err := r.replaceDir(r.siteDir, r.stageDir, mode); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+
+	// Redirects for pages that are gone from the latest version
+	// must not be left behind.
+	if r.aliasStageDir != "" {
+		if err := r.replaceDir(r.latestAliasDir(), r.aliasStageDir, dropLeftovers); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+
+	if r.rootStageDir != "" {
+		if err := r.moveFiles(r.rootStageDir, r.rootDir); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// moveFiles moves the files in stageDir into dir one by one,
+// replacing existing files, and then deletes stageDir.
+func (r *Generator) moveFiles(stageDir, dir string) error {
+	ents, err := os.ReadDir(stageDir)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	for _, ent := range ents {
+		if err := os.Rename(filepath.Join(stageDir, ent.Name()), filepath.Join(dir, ent.Name())); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+
+	r.DebugLog.Printf("Moved files in %v into place", dir)
+	return errtrace.Wrap(os.Remove(stageDir))
+}
+
+// replaceDir replaces dir with stageDir.
+//
+// Files in dir that weren't regenerated by this run
+// (e.g. pages of unchanged packages with -incremental,
+// or files unknown to doc2go)
+// are handled according to leftovers.
+// dir is not modified until stageDir is ready.
+func (r *Generator) replaceDir(dir, stageDir string, leftovers leftoverMode) error {
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return errtrace.Wrap(os.Rename(stageDir, dir))
+		}
+		return errtrace.Wrap(err)
+	}
+
+	if leftovers != dropLeftovers {
+		if _, err := r.carryOver(dir, stageDir, leftovers == cleanLeftovers); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+
+	oldDir := stageDir + ".old"
+	if err := os.Rename(dir, oldDir); err != nil {
+		return errtrace.Wrap(err)
+	}
+	if err := os.Rename(stageDir, dir); err != nil {
+		// Put the old directory back.
+		return errtrace.Wrap(errors.Join(err, os.Rename(oldDir, dir)))
+	}
+
+	r.DebugLog.Printf("Moved %v into place", dir)
+	return errtrace.Wrap(os.RemoveAll(oldDir))
+}
+
+// carryOver links files from src into dst
+// if they don't already exist in dst.
+// Directories kept wholesale by keepDir are not carried over.
+//
+// If prune is set, files that weren't generated by this run
+// are left behind, and reported to Log with CleanDryRun.
+// Neither src nor its contents are modified.
+//
+// It reports whether dst has anything from this run in it.
+func (r *Generator) carryOver(src, dst string, prune bool) (kept bool, err error) {
+	ents, err := os.ReadDir(src)
+	if err != nil {
+		return false, errtrace.Wrap(err)
+	}
+
+	for _, ent := range ents {
+		srcPath := filepath.Join(src, ent.Name())
+		dstPath := filepath.Join(dst, ent.Name())
+		if slices.Contains(r.keepDirs, srcPath) {
+			kept = true
+			continue
+		}
+
+		if ent.IsDir() {
+			subPrune := prune && !r.isSiblingOutput(srcPath, true)
+			subKept, err := r.carryOver(srcPath, dstPath, subPrune)
+			if err != nil {
+				return false, errtrace.Wrap(err)
+			}
+			if subKept {
+				kept = true
+			} else if prune {
+				r.prune(srcPath)
+			}
+			continue
+		}
+
+		if _, err := os.Lstat(dstPath); err == nil {
+			kept = true
+			continue // regenerated
+		}
+
+		if _, ok := r.generated[srcPath]; ok || !prune || r.isSiblingOutput(srcPath, false) {
+			kept = true
+		} else if r.prune(srcPath) {
+			continue
+		}
+
+		if err := os.MkdirAll(dst, 0o1755); err != nil {
+			return false, errtrace.Wrap(err)
+		}
+		if err := linkOrCopy(srcPath, dstPath); err != nil {
+			return false, errtrace.Wrap(err)
+		}
+	}
+	return kept, nil
+}
+
+// linkOrCopy hard links src to dst,
+// falling back to copying it if hard links aren't supported.
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	bs, err := os.ReadFile(src)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	return errtrace.Wrap(os.WriteFile(dst, bs, 0o644))
+}
+
+// writeStatic writes the renderer's static files
+// to the StaticDir inside OutDir.
+func (r *Generator) writeStatic() error {
+	files, err := r.Renderer.StaticFiles()
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	dir := r.sharedStaticDir()
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := r.writeFile(path, files[name]); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// writeFile writes a file with the given contents
+// and records it as generated by this run.
+func (r *Generator) writeFile(path string, bs []byte) (err error) {
+	f, err := r.createFile(path)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer errdefer.Close(&err, f)
+
+	_, err = f.Write(bs)
+	return errtrace.Wrap(err)
+}
+
+// createFile creates a file for writing,
+// creating its parent directories if necessary.
+// The file is recorded as generated by this run.
+//
+// Files inside siteDir are written to the staging directory.
+func (r *Generator) createFile(path string) (io.WriteCloser, error) {
+	r.markWritten(path)
+	if r.Output != nil {
+		return errtrace.Wrap2(r.Output.Create(filepath.ToSlash(path)))
+	}
+
+	outPath := r.stagePath(path)
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o1755); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	r.markGenerated(path)
+	return f, nil
+}
+
+// markWritten records that the file at path
+// was written by this run for reporting in Result.
+func (r *Generator) markWritten(path string) {
+	path = r.outputPath(path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.written = append(r.written, path)
+}
+
+// outputPath returns the /-separated path of a file
+// relative to the root of the output.
+func (r *Generator) outputPath(path string) string {
+	if rel, err := filepath.Rel(r.rootDir, path); err == nil && r.rootDir != "" {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+// markGenerated records that the file at path
+// is part of the output of this run.
+func (r *Generator) markGenerated(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generated[filepath.Clean(path)] = struct{}{}
+}
+
+// keepDir records that the directory at path
+// and everything inside it is part of the output of this run.
+func (r *Generator) keepDir(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keepDirs = append(r.keepDirs, filepath.Clean(path))
+}
+
+// clean deletes files and directories inside dir
+// that weren't generated by this run.
+// With CleanDryRun, they're reported to Log instead.
+//
+// It reports whether dir was left empty.
+// dir itself is never deleted.
+func (r *Generator) clean(dir string) (empty bool, err error) {
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return false, errtrace.Wrap(err)
+	}
+
+	empty = true
+	for _, ent := range ents {
+		path := filepath.Join(dir, ent.Name())
+		if _, ok := r.generated[path]; ok || slices.Contains(r.keepDirs, path) {
+			empty = false
+			continue
+		}
+		if r.isSiblingOutput(path, ent.IsDir()) {
+			empty = false
+			continue
+		}
+
+		if ent.IsDir() {
+			subEmpty, err := r.clean(path)
+			if err != nil {
+				return false, errtrace.Wrap(err)
+			}
+			if !subEmpty {
+				empty = false
+				continue
+			}
+		}
+
+		if err := r.remove(path); err != nil {
+			return false, errtrace.Wrap(err)
+		}
+	}
+
+	return empty, nil
+}
+
+// isSiblingOutput reports whether path is a site generated with -subdir
+// into the output directory, its latest alias, or its versions manifest.
+//
+// These are never pruned by a run without a SubDir:
+// they belong to the sibling sites, not to this one.
+func (r *Generator) isSiblingOutput(path string, isDir bool) bool {
+	if r.SubDir != "" || filepath.Dir(path) != filepath.Clean(r.siteDir) {
+		return false
+	}
+
+	if !isDir {
+		return filepath.Base(path) == html.VersionsManifest
+	}
+	if isLatestAlias(path) {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(path, html.StaticDir, html.PagesManifest))
+	return err == nil
+}
+
+func (r *Generator) remove(path string) error {
+	if !r.prune(path) {
+		return nil
+	}
+	return errtrace.Wrap(os.Remove(path))
+}
+
+// prune reports that a file or directory that wasn't generated
+// by this run will be deleted.
+// It returns false with CleanDryRun, in which case it must be kept.
+func (r *Generator) prune(path string) bool {
+	if r.Clean == CleanDryRun {
+		r.Log.Printf("Would remove %v", path)
+		return false
+	}
+
+	r.DebugLog.Printf("Removing %v", path)
+	return true
+}
+
+// If a -subdir is specified, generate a listing of siblings
+// under the output directory.
+// This is useful for generating documentation for multiple versions
+// of the same package.
+func (r *Generator) generateSiblingIndex() (err error) {
+	if r.SubDir == "" {
+		return nil
+	}
+
+	sites, err := r.siblingSites()
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	versions := sortSites(sites)
+	idx := html.SiteIndex{
+		Path:        r.Home,
+		Releases:    versions.Releases,
+		PreReleases: versions.PreReleases,
+		Others:      versions.Others,
+		Latest:      versions.Latest,
+	}
+
+	// Don't clobber a site that's actually named "latest".
+	if versions.Latest != "" && !slices.Contains(sites, _latestAlias) {
+		if err := r.generateLatestAlias(versions.Latest); err != nil {
+			return errtrace.Wrap(fmt.Errorf("generate %v alias: %w", _latestAlias, err))
+		}
+		idx.LatestAlias = _latestAlias
+	}
+
+	if err := r.writeVersionsManifest(versions); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	if r.Sitemap != nil {
+		pages, err := r.sitemapPages(versions)
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("list pages for sitemap: %w", err))
+		}
+		if err := r.writeSitemap(pages); err != nil {
+			return errtrace.Wrap(fmt.Errorf("write sitemap: %w", err))
+		}
+	}
+
+	f, err := r.createFile(filepath.Join(r.rootDir, r.Basename))
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer errdefer.Close(&err, f)
+
+	if err := r.Renderer.RenderSiteIndex(f, &idx); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	return nil
+}
+
+// siblingSites returns the sorted names of sites next to SubDir,
+// including SubDir itself.
+//
+// If SubDir doesn't exist yet, it's still included.
+func (r *Generator) siblingSites() ([]string, error) {
+	sites := []string{r.SubDir}
+	if r.Output != nil {
+		// Custom outputs start out empty,
+		// so the only other sites are the ones we were told about.
+		sites = append(sites, r.Siblings...)
+	} else {
+		// "_site/v1.0.0" -> "_site"
+		//
+		// With the current restriction of SubDir not containing '/',
+		// this will always be OutDir,
+		// but we're defensively being explicit here for future-proofing.
+		siblingDir := filepath.Dir(r.siteDir)
+
+		entries, err := os.ReadDir(siblingDir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, errtrace.Wrap(err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() || entry.Name() == html.StaticDir {
+				continue
+			}
+			if isLatestAlias(filepath.Join(siblingDir, entry.Name())) {
+				continue
+			}
+			if strings.HasPrefix(entry.Name(), ".") {
+				// Staging directories of this or other runs.
+				continue
+			}
+
+			sites = append(sites, entry.Name())
+		}
+	}
+	slices.Sort(sites)
+	return slices.Compact(sites), nil
+}
+
+// versionsManifest is the format of [html.VersionsManifest].
+type versionsManifest struct {
+	// Latest is the newest stable release, if any.
+	Latest string `json:"latest,omitempty"`
+
+	// Versions in the same order as the site index.
+	Versions []versionsManifestEntry `json:"versions"`
+}
+
+type versionsManifestEntry struct {
+	// Name of the subdirectory holding this version.
+	Name string `json:"name"`
+}
+
+// writeVersionsManifest writes the list of sibling sites
+// next to the sibling index.
+// The version switcher on each page uses this to list other versions.
+func (r *Generator) writeVersionsManifest(versions *siteVersions) error {
+	sites := versions.All()
+	manifest := versionsManifest{
+		Latest:   versions.Latest,
+		Versions: make([]versionsManifestEntry, len(sites)),
+	}
+	for i, site := range sites {
+		manifest.Versions[i] = versionsManifestEntry{Name: site}
+	}
+	return errtrace.Wrap(r.writeJSON(filepath.Join(r.rootDir, html.VersionsManifest), manifest))
+}
+
+// pagesManifest is the format of [html.PagesManifest].
+type pagesManifest struct {
+	// Paths of pages relative to the root of the site.
+	// The root itself is "".
+	Pages []string `json:"pages"`
+}
+
+// writePagesManifest writes the list of pages in this site
+// if it's being generated into a subdirectory.
+// The version switcher uses this to decide whether
+// a page is present in another version.
+func (r *Generator) writePagesManifest() error {
+	if r.SubDir == "" {
+		return nil
+	}
+
+	manifest := pagesManifest{Pages: r.pagePaths()}
+
+	return errtrace.Wrap(r.writeJSON(filepath.Join(r.siteDir, html.StaticDir, html.PagesManifest), manifest))
+}
+
+// writeManifest writes the manifest of a [ManifestRenderer]
+// for the pages generated so far.
+func (r *Generator) writeManifest(mr ManifestRenderer) (err error) {
+	r.mu.Lock()
+	pages := slices.SortedFunc(slices.Values(r.pages), func(a, b PageResult) int {
+		return strings.Compare(a.ImportPath, b.ImportPath)
+	})
+	r.mu.Unlock()
+
+	f, err := r.createFile(filepath.Join(r.siteDir, mr.ManifestName()))
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer errdefer.Close(&err, f)
+
+	return errtrace.Wrap(mr.RenderManifest(f, pages))
+}
+
+func (r *Generator) writeJSON(path string, v any) error {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	return errtrace.Wrap(r.writeFile(path, append(bs, '\n')))
+}
+
+// recordPage records that a page was generated
+// for the package or directory at the given import path.
+func (r *Generator) recordPage(importPath, file string, kind PageKind, synopsis string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pages = append(r.pages, PageResult{
+		ImportPath: importPath,
+		File:       r.outputPath(file),
+		Kind:       kind,
+		Synopsis:   synopsis,
+	})
+}
+
+// pagePaths returns the sorted paths of pages generated so far
+// relative to siteDir.
+func (r *Generator) pagePaths() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths := make([]string, len(r.pages))
+	for i, page := range r.pages {
+		paths[i] = relative.Path(r.Home, page.ImportPath)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// addTiming adds the time since start to the given stage timing.
+func (r *Generator) addTiming(stage *time.Duration, start time.Time) {
+	d := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	*stage += d
+}
+
+// renderTrees renders the given trees concurrently.
+//
+// The returned packages are in the same order as the trees
+// regardless of the order in which they finished rendering.
+func (r *Generator) renderTrees(ctx context.Context, crumbs []html.Breadcrumb, trees []packageTree) ([]*renderedPackage, error) {
+	// Failed packages don't return errors with KeepGoing,
+	// so any error stops work on the other trees.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		wg      sync.WaitGroup
+		results = make([][]*renderedPackage, len(trees))
+	)
+	for i, t := range trees {
+		// Goroutines here only wait for their children.
+		// Actual work is limited by acquire.
+		wg.Go(func() {
+			var err error
+			results[i], err = r.renderTree(ctx, crumbs, t)
+			if err != nil {
+				cancel(err)
+			}
+		})
+	}
+	wg.Wait()
+
+	// Report the error that stopped the other trees,
+	// not the cancellation that it caused in each of them.
+	if err := context.Cause(ctx); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	return slices.Concat(results...), nil
+}
+
+func (r *Generator) renderTree(ctx context.Context, crumbs []html.Breadcrumb, t packageTree) ([]*renderedPackage, error) {
+	crumbs = appendBreadcrumb(crumbs, t)
+	if t.Value == nil {
+		return errtrace.Wrap2(r.renderPackageIndex(ctx, crumbs, t))
+	}
+	return errtrace.Wrap2(r.renderPackage(ctx, crumbs, t))
+}
+
+func (r *Generator) renderPackageIndex(ctx context.Context, crumbs []html.Breadcrumb, t packageTree) (_ []*renderedPackage, err error) {
+	subpkgs, err := r.renderTrees(ctx, crumbs, t.Children)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	release, err := r.acquire(ctx)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer release()
+
+	r.DebugLog.Printf("Rendering directory %v", t.Path)
+
+	dir := filepath.Join(r.siteDir, relative.Path(r.Home, t.Path))
+	outFile := filepath.Join(dir, r.Basename)
+	f, err := r.createFile(outFile)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer errdefer.Close(&err, f)
+
+	var subdirDepth int
+	if r.SubDir != "" {
+		subdirDepth = 1 + strings.Count(r.SubDir, "/")
+	}
+
+	idx := html.PackageIndex{
+		Path:            t.Path,
+		SubDirDepth:     subdirDepth,
+		SubDir:          r.SubDir,
+		CanonicalSubDir: r.canonicalSubDir(relative.Path(r.Home, t.Path)),
+		NumChildren:     len(t.Children),
+		Subpackages:     htmlSubpackages(t.Path, subpkgs),
+		Breadcrumbs:     crumbs,
+	}
+	start := time.Now()
+	err = r.Renderer.RenderPackageIndex(f, &idx)
+	r.addTiming(&r.timings.Render, start)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	r.recordPage(t.Path, outFile, DirectoryPage, "")
+
+	return subpkgs, nil
+}
+
+// appendBreadcrumb returns a copy of crumbs
+// with a breadcrumb for the given tree appended to it.
+//
+// crumbs is not modified, so it's safe to share between siblings.
+func appendBreadcrumb(crumbs []html.Breadcrumb, t packageTree) []html.Breadcrumb {
+	var crumbText string
+	if n := len(crumbs); n > 0 {
+		crumbText = relative.Path(crumbs[n-1].Path, t.Path)
+	} else {
+		crumbText = t.Path
+	}
+	if len(crumbText) == 0 {
+		return crumbs
+	}
+
+	// Clip so that append always copies.
+	return append(slices.Clip(crumbs), html.Breadcrumb{Text: crumbText, Path: t.Path})
+}
+
+type renderedPackage struct {
+	ImportPath string
+	Synopsis   string
+
+	Cached bool // rendered by a previous run
+	Stats  godoc.Stats
+}
+
+// renderPackage renders the package at the root of the given tree
+// and its descendants.
+//
+// It returns the package for listing in its parent.
+// If the package failed and KeepGoing is set,
+// its subpackages are returned in its place.
+func (r *Generator) renderPackage(ctx context.Context, crumbs []html.Breadcrumb, t packageTree) ([]*renderedPackage, error) {
+	subpkgs, err := r.renderTrees(ctx, crumbs, t.Children)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	release, err := r.acquire(ctx)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer release()
+
+	ref := *t.Value
+	rpkg, err := r.documentPackage(ref, crumbs, t, subpkgs)
+	r.recordPackage(ref.ImportPath, rpkg, err)
+	if err != nil {
+		if !r.KeepGoing || ctx.Err() != nil {
+			return nil, errtrace.Wrap(err)
+		}
+
+		// Subpackages of the failed package are still documented,
+		// so list them in its parent instead.
+		r.DebugLog.Printf("Skipping package %v: %v", t.Path, err)
+		return subpkgs, nil
+	}
+	return []*renderedPackage{rpkg}, nil
+}
+
+// documentPackage parses, assembles, and renders a single package.
+func (r *Generator) documentPackage(
+	ref *gosrc.PackageRef,
+	crumbs []html.Breadcrumb,
+	t packageTree,
+	subpkgs []*renderedPackage,
+) (_ *renderedPackage, err error) {
+	dir := filepath.Join(r.siteDir, relative.Path(r.Home, t.Path))
+	outFile := filepath.Join(dir, r.Basename)
+
+	var hash string
+	if r.Cache != nil {
+		hash, err = r.packageHash(ref, len(t.Children), subpkgs)
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("hash: %w", err))
+		}
+
+		if cached, ok := r.Cache.Lookup(ref.ImportPath, hash); ok {
+			if _, err := os.Stat(outFile); err == nil && r.reuseSymbols(ref.ImportPath) {
+				r.DebugLog.Printf("Skipping unchanged package %v", t.Path)
+				r.markGenerated(outFile)
+				r.recordPage(t.Path, outFile, pageKind(ref), cached.Synopsis)
+				return &renderedPackage{
+					ImportPath: ref.ImportPath,
+					Synopsis:   cached.Synopsis,
+					Cached:     true,
+					Stats: godoc.Stats{
+						Exported:   cached.Exported,
+						Documented: cached.Documented,
+					},
+				}, nil
+			}
+		}
+	}
+
+	r.DebugLog.Printf("Rendering package %v", t.Path)
+	start := time.Now()
+	bpkg, err := r.Parser.ParsePackage(ref)
+	r.addTiming(&r.timings.Parse, start)
+	if err != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("parse: %w", err))
+	}
+
+	start = time.Now()
+	dpkg, err := r.Assembler.Assemble(bpkg)
+	r.addTiming(&r.timings.Assemble, start)
+	if err != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("assemble: %w", err))
+	}
+
+	f, err := r.createFile(outFile)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer errdefer.Close(&err, f)
+
+	var subdirDepth int
+	if r.SubDir != "" {
+		subdirDepth = 1 + strings.Count(r.SubDir, "/")
+	}
+
+	info := html.PackageInfo{
+		Package:     dpkg,
+		NumChildren: len(t.Children),
+		Breadcrumbs: crumbs,
+		Subpackages: htmlSubpackages(dpkg.ImportPath, subpkgs),
+		DocPrinter:  newDocPrinter(r.DocLinker, dpkg.ImportPath),
+		SubDirDepth: subdirDepth,
+		PkgVersion:  r.PkgVersion,
+		SubDir:      r.SubDir,
+
+		CanonicalSubDir: r.canonicalSubDir(relative.Path(r.Home, t.Path)),
+	}
+	start = time.Now()
+	err = r.Renderer.RenderPackage(f, &info)
+	r.addTiming(&r.timings.Render, start)
+	if err != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("render: %w", err))
+	}
+	r.recordPage(t.Path, outFile, pageKind(ref), dpkg.Synopsis)
+	r.recordSymbols(dpkg)
+
+	stats := dpkg.Stats()
+	if r.Cache != nil {
+		r.Cache.Store(ref.ImportPath, hash, buildcache.Package{
+			Synopsis:   dpkg.Synopsis,
+			Exported:   stats.Exported,
+			Documented: stats.Documented,
+		})
+	}
+
+	return &renderedPackage{
+		ImportPath: ref.ImportPath,
+		Synopsis:   dpkg.Synopsis,
+		Stats:      stats,
+	}, nil
+}
+
+// pageKind returns the kind of page generated for a package.
+func pageKind(ref *gosrc.PackageRef) PageKind {
+	if ref.Name == "main" {
+		return CommandPage
+	}
+	return PackagePage
+}
+
+// recordPackage records the outcome of documenting a package.
+// rpkg is nil if err is non-nil.
+func (r *Generator) recordPackage(importPath string, rpkg *renderedPackage, err error) {
+	res := PackageResult{
+		ImportPath: importPath,
+		Err:        err,
+	}
+	if rpkg != nil {
+		res.Cached = rpkg.Cached
+		res.Stats = rpkg.Stats
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.packages = append(r.packages, res)
+}
+
+// packageHash computes a hash of all inputs
+// that affect the page generated for a package,
+// except global configuration, which the PackageCache accounts for.
+func (r *Generator) packageHash(ref *gosrc.PackageRef, numChildren int, subpkgs []*renderedPackage) (string, error) {
+	h := sha256.New()
+	h.Write(r.siteHash)
+	fmt.Fprintf(h, "package %q %q\n", ref.Name, ref.ImportPath)
+
+	for _, imp := range ref.Imports {
+		fmt.Fprintf(h, "import %q %q\n", imp.Name, imp.ImportPath)
+	}
+
+	// Module versions of dependencies affect links to them.
+	var gomod string
+	if ref.Module != nil {
+		gomod = ref.Module.GoMod
+	}
+
+	// Record file paths relative to the module root
+	// so that the same module checked out elsewhere
+	// (e.g. in a worktree for -refs) hits the cache.
+	root := filepath.Dir(gomod)
+	if gomod == "" && len(ref.Files) > 0 {
+		root = filepath.Dir(ref.Files[0])
+	}
+
+	for _, group := range [][]string{ref.Files, ref.TestFiles, {gomod}} {
+		for _, file := range group {
+			if file == "" {
+				continue
+			}
+
+			bs, err := os.ReadFile(file)
+			if err != nil {
+				return "", errtrace.Wrap(err)
+			}
+
+			name := file
+			if rel, err := filepath.Rel(root, file); err == nil {
+				name = filepath.ToSlash(rel)
+			}
+			fmt.Fprintf(h, "file %q %d\n", name, len(bs))
+			h.Write(bs)
+		}
+	}
+
+	// Pages link to their preferred versions.
+	fmt.Fprintf(h, "canonical %q\n", r.canonicalSubDir(relative.Path(r.Home, ref.ImportPath)))
+
+	// Subpackage listings are part of the package page.
+	fmt.Fprintf(h, "children %d\n", numChildren)
+	for _, sub := range subpkgs {
+		fmt.Fprintf(h, "subpackage %q %q\n", sub.ImportPath, sub.Synopsis)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashImportPaths hashes the set of import paths
+// of the given packages.
+func hashImportPaths(refs []*gosrc.PackageRef) []byte {
+	paths := sliceutil.Transform(refs, func(ref *gosrc.PackageRef) string {
+		return ref.ImportPath
+	})
+	slices.Sort(paths)
+
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "%q\n", p)
+	}
+	return h.Sum(nil)
+}
+
+// newDocPrinter builds a DocPrinter for the package at importPath
+// that resolves links with the given linker.
+func newDocPrinter(linker godoc.Linker, importPath string) html.DocPrinter {
+	return &html.CommentDocPrinter{
+		Printer: comment.Printer{
+			DocLinkURL: func(link *comment.DocLink) string {
+				return linker.DocLinkURL(importPath, link)
+			},
+		},
+	}
+}
+
+func htmlSubpackages(from string, rpkgs []*renderedPackage) []html.Subpackage {
+	return sliceutil.Transform(rpkgs, func(rpkg *renderedPackage) html.Subpackage {
+		// TODO: track this on packageTree?
+		relPath := relative.Path(from, rpkg.ImportPath)
+
+		return html.Subpackage{
+			RelativePath: relPath,
+			Synopsis:     rpkg.Synopsis,
+		}
+	})
+}
+
+type packageTree = pathtree.Snapshot[*gosrc.PackageRef]
+
+func buildTrees(refs []*gosrc.PackageRef) []packageTree {
+	var root pathtree.Root[*gosrc.PackageRef]
+	for _, ref := range refs {
+		root.Set(ref.ImportPath, ref)
+	}
+	return []packageTree{{Children: root.Snapshot()}}
+}
+
+func filterTrees(root string, is []packageTree) []packageTree {
+	var os []packageTree
+	for _, i := range is {
+		if pathx.Descends(root, i.Path) {
+			os = append(os, i)
+		} else {
+			os = append(os, filterTrees(root, i.Children)...)
+		}
+	}
+	return os
+}
@@ -0,0 +1,1490 @@
+package sitegen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/doc/comment"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"braces.dev/errtrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/buildcache"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/iotest"
+	"go.abhg.dev/doc2go/internal/output"
+	"go.abhg.dev/doc2go/internal/pagefind"
+)
+
+func TestGenerator_hierarchy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		packages []*fakePackage
+		home     string
+		wantPkgs map[string]*renderInfo // import path => info
+		wantDirs map[string]*renderInfo // dir path => info
+	}{
+		{
+			desc: "simple",
+			packages: []*fakePackage{
+				{
+					ImportPath: "example.com/foo/bar",
+					Synopsis:   "package bar does things.",
+				},
+			},
+			wantPkgs: map[string]*renderInfo{
+				"example.com/foo/bar": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "example.com", Path: "example.com"},
+						{Text: "foo", Path: "example.com/foo"},
+						{Text: "bar", Path: "example.com/foo/bar"},
+					},
+				},
+			},
+			wantDirs: map[string]*renderInfo{
+				"example.com/foo": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "example.com", Path: "example.com"},
+						{Text: "foo", Path: "example.com/foo"},
+					},
+					Subpackages: []html.Subpackage{
+						{RelativePath: "bar", Synopsis: "package bar does things."},
+					},
+				},
+				"example.com": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "example.com", Path: "example.com"},
+					},
+					Subpackages: []html.Subpackage{
+						{RelativePath: "foo/bar", Synopsis: "package bar does things."},
+					},
+				},
+				"": {
+					Subpackages: []html.Subpackage{
+						{RelativePath: "example.com/foo/bar", Synopsis: "package bar does things."},
+					},
+				},
+			},
+		},
+		{
+			desc: "interlinked",
+			packages: []*fakePackage{
+				{ImportPath: "a/b/c", Synopsis: "package c"},
+				{ImportPath: "a/d", Synopsis: "package d"},
+				{ImportPath: "a/b/e", Synopsis: "package e"},
+			},
+			wantPkgs: map[string]*renderInfo{
+				"a/d": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "a", Path: "a"},
+						{Text: "d", Path: "a/d"},
+					},
+				},
+				"a/b/c": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "a", Path: "a"},
+						{Text: "b", Path: "a/b"},
+						{Text: "c", Path: "a/b/c"},
+					},
+				},
+				"a/b/e": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "a", Path: "a"},
+						{Text: "b", Path: "a/b"},
+						{Text: "e", Path: "a/b/e"},
+					},
+				},
+			},
+			wantDirs: map[string]*renderInfo{
+				"": {
+					Subpackages: []html.Subpackage{
+						{RelativePath: "a/b/c", Synopsis: "package c"},
+						{RelativePath: "a/b/e", Synopsis: "package e"},
+						{RelativePath: "a/d", Synopsis: "package d"},
+					},
+				},
+				"a": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "a", Path: "a"},
+					},
+					Subpackages: []html.Subpackage{
+						{RelativePath: "b/c", Synopsis: "package c"},
+						{RelativePath: "b/e", Synopsis: "package e"},
+						{RelativePath: "d", Synopsis: "package d"},
+					},
+				},
+				"a/b": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "a", Path: "a"},
+						{Text: "b", Path: "a/b"},
+					},
+					Subpackages: []html.Subpackage{
+						{RelativePath: "c", Synopsis: "package c"},
+						{RelativePath: "e", Synopsis: "package e"},
+					},
+				},
+			},
+		},
+		{
+			desc: "home",
+			home: "example.com/foo/bar",
+			packages: []*fakePackage{
+				{
+					ImportPath: "example.com/foo/bar",
+					Synopsis:   "package bar does things.",
+				},
+				{
+					ImportPath: "example.com/foo/bar/baz",
+					Synopsis:   "package baz does other things.",
+				},
+			},
+			wantPkgs: map[string]*renderInfo{
+				"example.com/foo/bar": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "example.com/foo/bar", Path: "example.com/foo/bar"},
+					},
+					Subpackages: []html.Subpackage{
+						{RelativePath: "baz", Synopsis: "package baz does other things."},
+					},
+				},
+				"example.com/foo/bar/baz": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "example.com/foo/bar", Path: "example.com/foo/bar"},
+						{Text: "baz", Path: "example.com/foo/bar/baz"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			pkgmap := make(map[string]*fakePackage, len(tt.packages))
+			refs := make([]*gosrc.PackageRef, len(tt.packages))
+			wantImports := make([]string, len(tt.packages))
+			for i, pkg := range tt.packages {
+				pkgmap[pkg.ImportPath] = pkg
+				wantImports[i] = pkg.ImportPath
+				refs[i] = &gosrc.PackageRef{
+					Name:       filepath.Base(pkg.ImportPath),
+					ImportPath: pkg.ImportPath,
+				}
+			}
+
+			parser := fakeParser{t: t, packages: pkgmap}
+			defer func() {
+				assert.ElementsMatch(t, wantImports, parser.sawImports,
+					"Parser didn't see all packages")
+			}()
+
+			assembler := fakeAssembler{t: t, packages: pkgmap}
+			defer func() {
+				assert.ElementsMatch(t, wantImports, assembler.sawImports,
+					"Assembler didn't see all packages")
+			}()
+
+			renderer := fakeRenderer{
+				t:               t,
+				wantPackages:    tt.wantPkgs,
+				wantDirectories: tt.wantDirs,
+			}
+
+			g := Generator{
+				DebugLog:  log.New(iotest.Writer(t), "", 0),
+				Parser:    &parser,
+				Assembler: &assembler,
+				Renderer:  &renderer,
+				OutDir:    t.TempDir(),
+				Home:      tt.home,
+				DocLinker: new(nopDocLinker),
+			}
+			_, err := g.Generate(context.Background(), refs)
+			require.NoError(t, err)
+
+			for k := range tt.wantDirs {
+				t.Errorf("Missed directory: %q", k)
+			}
+			for k := range tt.wantPkgs {
+				t.Errorf("Missed package: %q", k)
+			}
+		})
+	}
+}
+
+func TestGenerator_basename(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{
+		"foo": {ImportPath: "foo"},
+	}
+	parser := fakeParser{
+		t:        t,
+		packages: pkgs,
+	}
+	assembler := fakeAssembler{t: t, packages: pkgs}
+
+	renderer := fakeRenderer{
+		t: t,
+		wantPackages: map[string]*renderInfo{
+			"foo": {
+				Breadcrumbs: []html.Breadcrumb{
+					{Text: "foo", Path: "foo"},
+				},
+			},
+		},
+		wantDirectories: map[string]*renderInfo{
+			"": {
+				Subpackages: []html.Subpackage{
+					{RelativePath: "foo"},
+				},
+			},
+		},
+	}
+
+	outDir := t.TempDir()
+	g := Generator{
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &parser,
+		Assembler: &assembler,
+		Renderer:  &renderer,
+		OutDir:    outDir,
+		Basename:  "_index.html",
+		DocLinker: new(nopDocLinker),
+	}
+	_, err := g.Generate(context.Background(), []*gosrc.PackageRef{
+		{
+			Name:       "foo",
+			ImportPath: "foo",
+		},
+	})
+	require.NoError(t, err)
+
+	indexPath := filepath.Join(outDir, "foo", "_index.html")
+	_, err = os.Stat(indexPath)
+	require.NoError(t, err, "file must exist: %v", indexPath)
+}
+
+func TestGenerator_pagefind(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{"foo": {ImportPath: "foo"}}
+	parser := fakeParser{t: t, packages: pkgs}
+	assembler := fakeAssembler{t: t, packages: pkgs}
+	renderer := fakeRenderer{
+		t: t,
+		wantPackages: map[string]*renderInfo{
+			"foo": {
+				Breadcrumbs: []html.Breadcrumb{
+					{Text: "foo", Path: "foo"},
+				},
+			},
+		},
+		wantDirectories: map[string]*renderInfo{
+			"": {
+				Subpackages: []html.Subpackage{
+					{RelativePath: "foo"},
+				},
+			},
+		},
+	}
+
+	outDir := t.TempDir()
+
+	var indexed bool
+	var indexer indexerFunc = func(req pagefind.IndexRequest) error {
+		indexed = true
+
+		// Pages are staged until generation succeeds,
+		// so pagefind must index the staged site.
+		assert.Equal(t, filepath.Dir(outDir), filepath.Dir(req.SiteDir))
+		assert.FileExists(t, filepath.Join(req.SiteDir, "foo", "index.html"))
+		return nil
+	}
+	defer func() {
+		assert.True(t, indexed, "pagefind.Index not called")
+	}()
+
+	g := Generator{
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &parser,
+		Assembler: &assembler,
+		Renderer:  &renderer,
+		Pagefind:  indexer,
+		OutDir:    outDir,
+		DocLinker: new(nopDocLinker),
+	}
+
+	_, err := g.Generate(context.Background(), []*gosrc.PackageRef{
+		{
+			Name:       "foo",
+			ImportPath: "foo",
+		},
+	})
+	require.NoError(t, err)
+}
+
+func TestGenerator_jobs(t *testing.T) {
+	t.Parallel()
+
+	pkgs := make(map[string]*fakePackage)
+	wantPkgs := make(map[string]*renderInfo)
+	var (
+		refs        []*gosrc.PackageRef
+		wantImports []string
+		rootSubpkgs []html.Subpackage
+	)
+	for i := range 20 {
+		name := fmt.Sprintf("pkg%02d", i)
+		pkgs[name] = &fakePackage{ImportPath: name, Synopsis: "package " + name}
+		wantPkgs[name] = &renderInfo{
+			Breadcrumbs: []html.Breadcrumb{{Text: name, Path: name}},
+		}
+		refs = append(refs, &gosrc.PackageRef{Name: name, ImportPath: name})
+		wantImports = append(wantImports, name)
+		rootSubpkgs = append(rootSubpkgs, html.Subpackage{
+			RelativePath: name,
+			Synopsis:     "package " + name,
+		})
+	}
+
+	parser := fakeParser{t: t, packages: pkgs}
+	assembler := fakeAssembler{t: t, packages: pkgs}
+	renderer := fakeRenderer{
+		t:            t,
+		wantPackages: wantPkgs,
+		wantDirectories: map[string]*renderInfo{
+			// Subpackages must be in import path order
+			// regardless of the order in which they were rendered.
+			"": {Subpackages: rootSubpkgs},
+		},
+	}
+
+	g := Generator{
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &parser,
+		Assembler: &assembler,
+		Renderer:  &renderer,
+		OutDir:    t.TempDir(),
+		DocLinker: new(nopDocLinker),
+		Jobs:      4,
+	}
+	_, err := g.Generate(context.Background(), refs)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, wantImports, parser.sawImports)
+	assert.ElementsMatch(t, wantImports, assembler.sawImports)
+	assert.Empty(t, renderer.wantPackages, "packages not rendered")
+	assert.Empty(t, renderer.wantDirectories, "directories not rendered")
+}
+
+func TestGenerator_cache(t *testing.T) {
+	t.Parallel()
+
+	srcDir := t.TempDir()
+	barFile := filepath.Join(srcDir, "bar.go")
+	require.NoError(t, os.WriteFile(barFile, []byte("package bar"), 0o644))
+
+	pkgs := map[string]*fakePackage{
+		"foo":     {ImportPath: "foo", Synopsis: "package foo"},
+		"foo/bar": {ImportPath: "foo/bar", Synopsis: "package bar"},
+	}
+	refs := []*gosrc.PackageRef{
+		{Name: "foo", ImportPath: "foo"},
+		{Name: "bar", ImportPath: "foo/bar", Files: []string{barFile}},
+	}
+
+	outDir := t.TempDir()
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+
+	// generate runs the generator
+	// and returns the list of packages that were parsed.
+	generate := func() []string {
+		cache, err := buildcache.Load(cachePath, "salt")
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, cache.Save(cachePath))
+		}()
+
+		parser := fakeParser{t: t, packages: pkgs}
+		g := Generator{
+			DebugLog:  log.New(iotest.Writer(t), "", 0),
+			Parser:    &parser,
+			Assembler: &fakeAssembler{t: t, packages: pkgs},
+			Renderer: &fakeRenderer{
+				t: t,
+				wantPackages: map[string]*renderInfo{
+					"foo": {
+						Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}},
+						Subpackages: []html.Subpackage{
+							{RelativePath: "bar", Synopsis: pkgs["foo/bar"].Synopsis},
+						},
+					},
+					"foo/bar": {
+						Breadcrumbs: []html.Breadcrumb{
+							{Text: "foo", Path: "foo"},
+							{Text: "bar", Path: "foo/bar"},
+						},
+					},
+				},
+				wantDirectories: map[string]*renderInfo{
+					"": {
+						Subpackages: []html.Subpackage{
+							{RelativePath: "foo", Synopsis: "package foo"},
+						},
+					},
+				},
+			},
+			OutDir:    outDir,
+			DocLinker: new(nopDocLinker),
+			Cache:     cache,
+		}
+		_, err = g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+		return parser.sawImports
+	}
+
+	assert.ElementsMatch(t, []string{"foo", "foo/bar"}, generate(),
+		"first run must parse everything")
+	assert.Empty(t, generate(),
+		"second run must not parse anything")
+	assert.FileExists(t, filepath.Join(outDir, "foo", "bar", "index.html"),
+		"skipped package must be kept")
+
+	// Changing bar changes its synopsis,
+	// which must also invalidate foo's subpackage listing.
+	require.NoError(t, os.WriteFile(barFile, []byte("package bar // changed"), 0o644))
+	pkgs["foo/bar"].Synopsis = "package bar changed"
+	assert.ElementsMatch(t, []string{"foo", "foo/bar"}, generate(),
+		"change must re-render package and parent")
+
+	// Deleted output must be regenerated.
+	require.NoError(t, os.Remove(filepath.Join(outDir, "foo", "index.html")))
+	assert.ElementsMatch(t, []string{"foo"}, generate(),
+		"missing output must be re-rendered")
+}
+
+func TestGenerator_packageHashRelocated(t *testing.T) {
+	t.Parallel()
+
+	// ref builds a reference to the same package
+	// in a module checked out at a new location.
+	ref := func() *gosrc.PackageRef {
+		dir := t.TempDir()
+		gomod := filepath.Join(dir, "go.mod")
+		file := filepath.Join(dir, "foo", "foo.go")
+		require.NoError(t, os.WriteFile(gomod, []byte("module example.com"), 0o644))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		require.NoError(t, os.WriteFile(file, []byte("package foo"), 0o644))
+		return &gosrc.PackageRef{
+			Name:       "foo",
+			ImportPath: "example.com/foo",
+			Files:      []string{file},
+			Module:     &gosrc.ModuleRef{Path: "example.com", GoMod: gomod},
+		}
+	}
+
+	var g Generator
+	want, err := g.packageHash(ref(), 0, nil)
+	require.NoError(t, err)
+
+	got, err := g.packageHash(ref(), 0, nil)
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestGenerator_clean(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc   string
+		subDir string
+		mode   CleanMode
+
+		// Files relative to the output directory.
+		wantKept    []string
+		wantRemoved []string
+	}{
+		{
+			desc: "clean",
+			mode: CleanEnabled,
+			wantKept: []string{
+				"index.html",
+				"foo/index.html",
+				"_/css/main.css",
+				"_/pagefind/pagefind.js",
+				"cache.json",
+			},
+			wantRemoved: []string{
+				"old.txt",
+				"_/css/old.css",
+				"bar/index.html",
+				"bar/baz/index.html",
+				"bar",
+				"_/pagefind/old.js",
+			},
+		},
+		{
+			desc: "dry run",
+			mode: CleanDryRun,
+			wantKept: []string{
+				"foo/index.html",
+				"old.txt",
+				"_/css/old.css",
+				"bar/baz/index.html",
+				"cache.json",
+			},
+		},
+		{
+			desc:   "subdir",
+			subDir: "v2",
+			mode:   CleanEnabled,
+			wantKept: []string{
+				"index.html",
+				"_/css/main.css",
+				"_/css/old.css", // not under subdir
+				"v1/foo/index.html",
+				"v1/old.txt",
+				"v2/foo/index.html",
+				"v2/_/pagefind/pagefind.js",
+				"v2/cache.json",
+			},
+			wantRemoved: []string{
+				"v2/old.txt",
+				"v2/bar",
+				"v2/_/pagefind/old.js",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			outDir := t.TempDir()
+			siteDir := filepath.Join(outDir, tt.subDir)
+			for _, name := range []string{
+				"old.txt",
+				"foo/index.html",
+				"bar/index.html",
+				"bar/baz/index.html",
+				"_/pagefind/old.js",
+				"cache.json",
+			} {
+				writeTestFile(t, filepath.Join(siteDir, name))
+			}
+			writeTestFile(t, filepath.Join(outDir, "_/css/old.css"))
+			if tt.subDir != "" {
+				writeTestFile(t, filepath.Join(outDir, "v1/old.txt"))
+				writeTestFile(t, filepath.Join(outDir, "v1/foo/index.html"))
+			}
+
+			pkgs := map[string]*fakePackage{"foo": {ImportPath: "foo"}}
+			var logs strings.Builder
+			g := Generator{
+				Log:       log.New(&logs, "", 0),
+				DebugLog:  log.New(iotest.Writer(t), "", 0),
+				Parser:    &fakeParser{t: t, packages: pkgs},
+				Assembler: &fakeAssembler{t: t, packages: pkgs},
+				Renderer: &fakeRenderer{
+					t: t,
+					wantPackages: map[string]*renderInfo{
+						"foo": {
+							Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}},
+						},
+					},
+					wantDirectories: map[string]*renderInfo{
+						"": {
+							Subpackages: []html.Subpackage{{RelativePath: "foo"}},
+						},
+					},
+					static: map[string][]byte{
+						"css/main.css": []byte("body {}"),
+					},
+				},
+				Pagefind: indexerFunc(func(req pagefind.IndexRequest) error {
+					writeTestFile(t, filepath.Join(req.SiteDir, req.AssetSubdir, "pagefind.js"))
+					return nil
+				}),
+				OutDir:    outDir,
+				SubDir:    tt.subDir,
+				DocLinker: new(nopDocLinker),
+				Clean:     tt.mode,
+				Preserve:  []string{filepath.Join(siteDir, "cache.json")},
+			}
+			_, err := g.Generate(context.Background(), []*gosrc.PackageRef{
+				{Name: "foo", ImportPath: "foo"},
+			})
+			require.NoError(t, err)
+
+			for _, name := range tt.wantKept {
+				assert.FileExists(t, filepath.Join(outDir, name))
+			}
+			for _, name := range tt.wantRemoved {
+				assert.NoFileExists(t, filepath.Join(outDir, name))
+				assert.NoDirExists(t, filepath.Join(outDir, name))
+			}
+
+			if tt.mode == CleanDryRun {
+				assert.Contains(t, logs.String(), "Would remove "+filepath.Join(outDir, "old.txt"))
+				assert.Contains(t, logs.String(), "Would remove "+filepath.Join(outDir, "bar"))
+				assert.NotContains(t, logs.String(), "foo")
+			}
+		})
+	}
+}
+
+func TestGenerator_cleanKeepsSiblingSites(t *testing.T) {
+	t.Parallel()
+
+	// Files relative to the output directory
+	// left behind by runs with -subdir.
+	siblings := []string{
+		"v1.0.0/_/pages.json",
+		"v1.0.0/foo/index.html",
+		"v1.1.0/_/pages.json",
+		"v1.1.0/bar/index.html",
+		"latest/_/alias.json",
+		"latest/foo/index.html",
+		"versions.json",
+	}
+	setup := func(t *testing.T) string {
+		outDir := t.TempDir()
+		for _, name := range siblings {
+			writeTestFile(t, filepath.Join(outDir, name))
+		}
+		writeTestFile(t, filepath.Join(outDir, "old.txt"))
+		writeTestFile(t, filepath.Join(outDir, "v0/index.html")) // not a site
+		return outDir
+	}
+
+	for desc, mode := range map[string]CleanMode{
+		"clean":   CleanEnabled,
+		"dry run": CleanDryRun,
+	} {
+		t.Run(desc, func(t *testing.T) {
+			t.Parallel()
+
+			outDir := setup(t)
+			pkgs := map[string]*fakePackage{"foo": {ImportPath: "foo"}}
+			var logs strings.Builder
+			g := Generator{
+				Log:       log.New(&logs, "", 0),
+				DebugLog:  log.New(iotest.Writer(t), "", 0),
+				Parser:    &fakeParser{t: t, packages: pkgs},
+				Assembler: &fakeAssembler{t: t, packages: pkgs},
+				Renderer: &fakeRenderer{
+					t: t,
+					wantPackages: map[string]*renderInfo{
+						"foo": {
+							Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}},
+						},
+					},
+					wantDirectories: map[string]*renderInfo{
+						"": {
+							Subpackages: []html.Subpackage{{RelativePath: "foo"}},
+						},
+					},
+				},
+				OutDir:    outDir,
+				DocLinker: new(nopDocLinker),
+				Clean:     mode,
+			}
+			_, err := g.Generate(context.Background(), []*gosrc.PackageRef{
+				{Name: "foo", ImportPath: "foo"},
+			})
+			require.NoError(t, err)
+
+			for _, name := range siblings {
+				assert.FileExists(t, filepath.Join(outDir, name), "sibling sites must be kept")
+			}
+			for _, name := range []string{"v1.0.0", "v1.1.0", "latest", "versions.json"} {
+				assert.NotContains(t, logs.String(), filepath.Join(outDir, name))
+			}
+
+			if mode == CleanDryRun {
+				assert.Contains(t, logs.String(), "Would remove "+filepath.Join(outDir, "old.txt"))
+				assert.Contains(t, logs.String(), "Would remove "+filepath.Join(outDir, "v0"))
+			} else {
+				assert.NoFileExists(t, filepath.Join(outDir, "old.txt"))
+				assert.NoDirExists(t, filepath.Join(outDir, "v0"))
+			}
+		})
+	}
+
+	// Output directories that contain the working directory
+	// are cleaned in place.
+	t.Run("in place", func(t *testing.T) {
+		t.Parallel()
+
+		outDir := setup(t)
+		g := Generator{
+			DebugLog: log.New(iotest.Writer(t), "", 0),
+			OutDir:   outDir,
+			Clean:    CleanEnabled,
+		}
+		g.init()
+		g.rootDir, g.siteDir = outDir, outDir
+
+		_, err := g.clean(outDir)
+		require.NoError(t, err)
+
+		for _, name := range siblings {
+			assert.FileExists(t, filepath.Join(outDir, name), "sibling sites must be kept")
+		}
+		assert.NoFileExists(t, filepath.Join(outDir, "old.txt"))
+		assert.NoDirExists(t, filepath.Join(outDir, "v0"))
+	})
+}
+
+func TestGenerator_carryOverPrune(t *testing.T) {
+	t.Parallel()
+
+	src, dst := t.TempDir(), t.TempDir()
+	for _, name := range []string{"old.txt", "foo/index.html", "bar/index.html", "cache.json"} {
+		writeTestFile(t, filepath.Join(src, name))
+	}
+	writeTestFile(t, filepath.Join(dst, "foo/index.html"))
+
+	g := Generator{
+		DebugLog: log.New(iotest.Writer(t), "", 0),
+		Clean:    CleanEnabled,
+		Preserve: []string{filepath.Join(src, "cache.json")},
+	}
+	g.init()
+
+	kept, err := g.carryOver(src, dst, true)
+	require.NoError(t, err)
+	assert.True(t, kept)
+
+	assert.FileExists(t, filepath.Join(dst, "cache.json"))
+	assert.NoFileExists(t, filepath.Join(dst, "old.txt"))
+	assert.NoDirExists(t, filepath.Join(dst, "bar"))
+
+	for _, name := range []string{"old.txt", "foo/index.html", "bar/index.html", "cache.json"} {
+		assert.FileExists(t, filepath.Join(src, name), "source must not be modified")
+	}
+}
+
+func TestContainsWorkDir(t *testing.T) {
+	t.Parallel()
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tests := []struct {
+		desc string
+		give string
+		want bool
+	}{
+		{desc: "working directory", give: ".", want: true},
+		{desc: "absolute", give: wd, want: true},
+		{desc: "parent", give: "..", want: true},
+		{desc: "child", give: "testdata"},
+		{desc: "elsewhere", give: t.TempDir()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := containsWorkDir(tt.give)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, path string) {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o1755))
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0o644))
+}
+
+func TestGenerator_failureKeepsSite(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc     string
+		subDir   string
+		clean    CleanMode
+		ctx      func() context.Context
+		pagefind indexerFunc
+		wantErr  error
+	}{
+		{
+			desc: "error",
+			ctx:  context.Background,
+			pagefind: func(pagefind.IndexRequest) error {
+				return errtrace.Wrap(errors.New("great sadness"))
+			},
+		},
+		{
+			desc:   "subdir",
+			subDir: "v2",
+			clean:  CleanEnabled,
+			ctx:    context.Background,
+			pagefind: func(pagefind.IndexRequest) error {
+				return errtrace.Wrap(errors.New("great sadness"))
+			},
+		},
+		{
+			desc: "cancelled",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			wantErr: context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			parent := t.TempDir()
+			outDir := filepath.Join(parent, "site")
+			siteDir := filepath.Join(outDir, tt.subDir)
+			oldPage := filepath.Join(siteDir, "foo", "index.html")
+			oldFile := filepath.Join(siteDir, "old.txt")
+			oldCSS := filepath.Join(outDir, "_", "css", "main.css")
+			for _, f := range []string{oldPage, oldFile, oldCSS} {
+				writeTestFile(t, f)
+			}
+
+			pkgs := map[string]*fakePackage{"foo": {ImportPath: "foo"}}
+			g := Generator{
+				DebugLog:  log.New(iotest.Writer(t), "", 0),
+				Parser:    &fakeParser{t: t, packages: pkgs},
+				Assembler: &fakeAssembler{t: t, packages: pkgs},
+				Renderer: &fakeRenderer{
+					t: t,
+					wantPackages: map[string]*renderInfo{
+						"foo": {
+							Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}},
+						},
+					},
+					wantDirectories: map[string]*renderInfo{
+						"": {
+							Subpackages: []html.Subpackage{{RelativePath: "foo"}},
+						},
+					},
+					static: map[string][]byte{
+						"css/main.css": []byte("body {}"),
+					},
+				},
+				OutDir:    outDir,
+				SubDir:    tt.subDir,
+				DocLinker: new(nopDocLinker),
+				Clean:     tt.clean,
+			}
+			if tt.pagefind != nil {
+				g.Pagefind = tt.pagefind
+			}
+
+			_, err := g.Generate(tt.ctx(), []*gosrc.PackageRef{
+				{Name: "foo", ImportPath: "foo"},
+			})
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+
+			for _, f := range []string{oldPage, oldFile, oldCSS} {
+				bs, err := os.ReadFile(f)
+				require.NoError(t, err)
+				assert.Equal(t, "old", string(bs), "existing file must not be modified: %v", f)
+			}
+			assert.NoFileExists(t, filepath.Join(siteDir, "index.html"),
+				"new pages must not be published")
+
+			ents, err := os.ReadDir(parent)
+			require.NoError(t, err)
+			if assert.Len(t, ents, 1, "staging directory must be removed") {
+				assert.Equal(t, "site", ents[0].Name())
+			}
+
+			if tt.subDir != "" {
+				ents, err := os.ReadDir(outDir)
+				require.NoError(t, err)
+				var names []string
+				for _, ent := range ents {
+					names = append(names, ent.Name())
+				}
+				assert.Equal(t, []string{"_", tt.subDir}, names,
+					"static staging directory must be removed")
+			}
+		})
+	}
+}
+
+func TestGenerator_output(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{"foo": {ImportPath: "foo"}}
+	newGenerator := func(out output.FS) *Generator {
+		return &Generator{
+			DebugLog:  log.New(iotest.Writer(t), "", 0),
+			Parser:    &fakeParser{t: t, packages: pkgs},
+			Assembler: &fakeAssembler{t: t, packages: pkgs},
+			Renderer: &fakeRenderer{
+				t: t,
+				wantPackages: map[string]*renderInfo{
+					"foo": {
+						Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}},
+					},
+				},
+				wantDirectories: map[string]*renderInfo{
+					"": {
+						Subpackages: []html.Subpackage{{RelativePath: "foo"}},
+					},
+				},
+				static: map[string][]byte{
+					"css/main.css": []byte("body {}"),
+				},
+			},
+			Output:    out,
+			SubDir:    "v1",
+			DocLinker: new(nopDocLinker),
+		}
+	}
+	refs := []*gosrc.PackageRef{{Name: "foo", ImportPath: "foo"}}
+
+	t.Run("memory", func(t *testing.T) {
+		t.Parallel()
+
+		var mem output.Memory
+		_, err := newGenerator(&mem).Generate(context.Background(), refs)
+		require.NoError(t, err)
+
+		files := mem.Files()
+		assert.ElementsMatch(t, []string{
+			"index.html",
+			"versions.json",
+			"_/css/main.css",
+			"v1/index.html",
+			"v1/foo/index.html",
+			"v1/_/pages.json",
+			"latest/index.html",
+			"latest/foo/index.html",
+			"latest/_/alias.json",
+		}, slices.Collect(maps.Keys(files)))
+		assert.Equal(t, "body {}", string(files["_/css/main.css"]))
+		assert.JSONEq(t,
+			`{"latest": "v1", "versions": [{"name": "v1"}]}`,
+			string(files["versions.json"]))
+		assert.JSONEq(t, `{"pages": ["", "foo"]}`, string(files["v1/_/pages.json"]))
+		assert.Equal(t, "v1", string(files["latest/index.html"]))
+		assert.Equal(t, "v1/foo", string(files["latest/foo/index.html"]))
+	})
+
+	t.Run("siblings", func(t *testing.T) {
+		t.Parallel()
+
+		var mem output.Memory
+		g := newGenerator(&mem)
+		g.Siblings = []string{"v2.0.0", "v0.1.0", "v3.0.0-rc.1", "main"}
+		g.SubDir = "v1.0.0"
+		// Only the root of v2.0.0 is known,
+		// so only the root page prefers it.
+		g.Renderer.(*fakeRenderer).wantDirectories[""].CanonicalSubDir = "v2.0.0"
+		_, err := g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+
+		renderer := g.Renderer.(*fakeRenderer)
+		require.NotNil(t, renderer.siteIndex)
+		assert.Equal(t, &html.SiteIndex{
+			Releases:    []string{"v2.0.0", "v1.0.0", "v0.1.0"},
+			PreReleases: []string{"v3.0.0-rc.1"},
+			Others:      []string{"main"},
+			Latest:      "v2.0.0",
+			LatestAlias: "latest",
+		}, renderer.siteIndex)
+		assert.JSONEq(t, `{
+			"latest": "v2.0.0",
+			"versions": [
+				{"name": "v2.0.0"},
+				{"name": "v1.0.0"},
+				{"name": "v0.1.0"},
+				{"name": "v3.0.0-rc.1"},
+				{"name": "main"}
+			]
+		}`, string(mem.Files()["versions.json"]))
+
+		// v2.0.0 isn't in this output, so only its root is aliased.
+		assert.Equal(t, "v2.0.0", string(mem.Files()["latest/index.html"]))
+		assert.NotContains(t, mem.Files(), "latest/foo/index.html")
+	})
+
+	t.Run("skip sibling index", func(t *testing.T) {
+		t.Parallel()
+
+		var mem output.Memory
+		g := newGenerator(&mem)
+		g.SkipSiblingIndex = true
+		_, err := g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+
+		assert.NotContains(t, mem.Files(), "index.html")
+		assert.NotContains(t, mem.Files(), "versions.json")
+		assert.Contains(t, mem.Files(), "v1/index.html")
+		assert.Contains(t, mem.Files(), "v1/_/pages.json")
+	})
+
+	t.Run("pagefind", func(t *testing.T) {
+		t.Parallel()
+
+		g := newGenerator(new(output.Memory))
+		g.Pagefind = indexerFunc(func(pagefind.IndexRequest) error {
+			t.Error("pagefind must not be run")
+			return nil
+		})
+		_, err := g.Generate(context.Background(), refs)
+		assert.ErrorContains(t, err, "require an output directory")
+	})
+}
+
+func TestGenerator_result(t *testing.T) {
+	t.Parallel()
+
+	parseErr := errors.New("great sadness")
+	pkgs := map[string]*fakePackage{
+		"foo":     {ImportPath: "foo"},
+		"bar":     {ImportPath: "bar"},
+		"baz/qux": {ImportPath: "baz/qux", ParseErr: parseErr},
+	}
+
+	// The failure stops packages that haven't started yet,
+	// so fail only after the others have started.
+	var started sync.WaitGroup
+	started.Add(2)
+	parser := fakeParser{
+		t:        t,
+		packages: pkgs,
+		onParse: func(importPath string) {
+			if importPath == "baz/qux" {
+				started.Wait()
+			} else {
+				started.Done()
+			}
+		},
+	}
+
+	var mem output.Memory
+	g := Generator{
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &parser,
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer: &fakeRenderer{
+			t: t,
+			wantPackages: map[string]*renderInfo{
+				"foo": {Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}}},
+				"bar": {Breadcrumbs: []html.Breadcrumb{{Text: "bar", Path: "bar"}}},
+			},
+			static: map[string][]byte{"css/main.css": nil},
+		},
+		Output:    &mem,
+		DocLinker: new(nopDocLinker),
+		Jobs:      len(pkgs),
+	}
+
+	res, err := g.Generate(context.Background(), []*gosrc.PackageRef{
+		{Name: "foo", ImportPath: "foo"},
+		{Name: "main", ImportPath: "bar"},
+		{Name: "qux", ImportPath: "baz/qux"},
+	})
+	require.ErrorIs(t, err, parseErr)
+	require.NotNil(t, res)
+
+	assert.Equal(t, []string{"bar/index.html", "foo/index.html"}, res.Files)
+	assert.Equal(t, []PageResult{
+		{ImportPath: "bar", File: "bar/index.html", Kind: CommandPage},
+		{ImportPath: "foo", File: "foo/index.html", Kind: PackagePage},
+	}, res.Pages)
+	assert.Zero(t, res.Timings.Pagefind)
+	require.Len(t, res.Packages, 3)
+	assert.Equal(t, PackageResult{ImportPath: "bar"}, res.Packages[0])
+	assert.Equal(t, "baz/qux", res.Packages[1].ImportPath)
+	assert.ErrorIs(t, res.Packages[1].Err, parseErr)
+	assert.Equal(t, PackageResult{ImportPath: "foo"}, res.Packages[2])
+}
+
+func TestGenerator_failureStopsSiblings(t *testing.T) {
+	t.Parallel()
+
+	parseErr := errors.New("great sadness")
+	pkgs := make(map[string]*fakePackage)
+	var refs []*gosrc.PackageRef
+	for i := range 20 {
+		name := fmt.Sprintf("pkg%02d", i)
+		pkgs[name] = &fakePackage{ImportPath: name, ParseErr: parseErr}
+		refs = append(refs, &gosrc.PackageRef{Name: name, ImportPath: name})
+	}
+
+	parser := fakeParser{t: t, packages: pkgs}
+	g := Generator{
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &parser,
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer:  &fakeRenderer{t: t},
+		Output:    new(output.Memory),
+		DocLinker: new(nopDocLinker),
+		Jobs:      1,
+	}
+
+	_, err := g.Generate(context.Background(), refs)
+	require.ErrorIs(t, err, parseErr)
+	assert.NotErrorIs(t, err, context.Canceled,
+		"must report the failure, not the cancellation it caused")
+	assert.Less(t, len(parser.sawImports), len(refs),
+		"packages waiting their turn must not be parsed after a failure")
+}
+
+func TestGenerator_keepGoing(t *testing.T) {
+	t.Parallel()
+
+	parseErr := errors.New("great sadness")
+	assembleErr := errors.New("much confusion")
+	pkgs := map[string]*fakePackage{
+		"foo":     {ImportPath: "foo"},
+		"bar":     {ImportPath: "bar", ParseErr: parseErr},
+		"bar/baz": {ImportPath: "bar/baz"},
+		"qux":     {ImportPath: "qux", AssembleErr: assembleErr},
+	}
+
+	var mem output.Memory
+	g := Generator{
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &fakeParser{t: t, packages: pkgs},
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer: &fakeRenderer{
+			t: t,
+			wantPackages: map[string]*renderInfo{
+				"foo": {Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}}},
+				"bar/baz": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "bar", Path: "bar"},
+						{Text: "baz", Path: "bar/baz"},
+					},
+				},
+			},
+			wantDirectories: map[string]*renderInfo{
+				"": {
+					// bar failed, so its subpackage is listed in its place.
+					Subpackages: []html.Subpackage{
+						{RelativePath: "bar/baz"},
+						{RelativePath: "foo"},
+					},
+				},
+			},
+		},
+		Output:    &mem,
+		DocLinker: new(nopDocLinker),
+		KeepGoing: true,
+	}
+
+	res, err := g.Generate(context.Background(), []*gosrc.PackageRef{
+		{Name: "foo", ImportPath: "foo"},
+		{Name: "bar", ImportPath: "bar"},
+		{Name: "baz", ImportPath: "bar/baz"},
+		{Name: "qux", ImportPath: "qux"},
+	})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, parseErr)
+	assert.ErrorIs(t, err, assembleErr)
+
+	var pkgsErr *PackagesError
+	require.ErrorAs(t, err, &pkgsErr)
+	if assert.Len(t, pkgsErr.Packages, 2) {
+		assert.Equal(t, "bar", pkgsErr.Packages[0].ImportPath)
+		assert.Equal(t, "qux", pkgsErr.Packages[1].ImportPath)
+	}
+	assert.Equal(t, "2 packages failed:\n"+
+		"  bar: parse: great sadness\n"+
+		"  qux: assemble: much confusion", err.Error())
+
+	assert.Equal(t, []string{
+		"bar/baz/index.html",
+		"foo/index.html",
+		"index.html",
+	}, res.Files)
+}
+
+type indexerFunc func(pagefind.IndexRequest) error
+
+func (f indexerFunc) Index(_ context.Context, req pagefind.IndexRequest) error {
+	return errtrace.Wrap(f(req))
+}
+
+func TestGenerator_manifest(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{
+		"foo/bar": {ImportPath: "foo/bar", Synopsis: "package bar"},
+		"foo/cmd": {ImportPath: "foo/cmd"},
+	}
+
+	var mem output.Memory
+	renderer := &fakeManifestRenderer{
+		fakeRenderer: &fakeRenderer{
+			t: t,
+			wantPackages: map[string]*renderInfo{
+				"foo/bar": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "foo", Path: "foo"},
+						{Text: "bar", Path: "foo/bar"},
+					},
+				},
+				"foo/cmd": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "foo", Path: "foo"},
+						{Text: "cmd", Path: "foo/cmd"},
+					},
+				},
+			},
+			wantDirectories: map[string]*renderInfo{
+				"": {
+					Subpackages: []html.Subpackage{
+						{RelativePath: "foo/bar", Synopsis: "package bar"},
+						{RelativePath: "foo/cmd"},
+					},
+				},
+				"foo": {
+					Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}},
+					Subpackages: []html.Subpackage{
+						{RelativePath: "bar", Synopsis: "package bar"},
+						{RelativePath: "cmd"},
+					},
+				},
+			},
+		},
+	}
+	g := Generator{
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &fakeParser{t: t, packages: pkgs},
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer:  renderer,
+		Output:    &mem,
+		SubDir:    "v1",
+		DocLinker: new(nopDocLinker),
+	}
+
+	_, err := g.Generate(context.Background(), []*gosrc.PackageRef{
+		{Name: "bar", ImportPath: "foo/bar"},
+		{Name: "main", ImportPath: "foo/cmd"},
+	})
+	require.NoError(t, err)
+
+	bs, err := mem.ReadFile("v1/manifest.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "\tdirectory\t\n"+
+		"foo\tdirectory\t\n"+
+		"foo/bar\tpackage\tpackage bar\n"+
+		"foo/cmd\tcommand\t\n", string(bs))
+}
+
+type fakeManifestRenderer struct {
+	*fakeRenderer
+}
+
+var _ ManifestRenderer = (*fakeManifestRenderer)(nil)
+
+func (*fakeManifestRenderer) ManifestName() string { return "manifest.txt" }
+
+func (*fakeManifestRenderer) RenderManifest(w io.Writer, pages []PageResult) error {
+	for _, page := range pages {
+		if _, err := fmt.Fprintf(w, "%v\t%v\t%v\n", page.ImportPath, page.Kind, page.Synopsis); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+type fakePackage struct {
+	ImportPath string
+	Synopsis   string
+
+	// Doc, if set, is returned when assembling the package
+	// instead of a package with only a synopsis.
+	Doc *godoc.Package
+
+	// ParseErr, if set, is returned when parsing the package.
+	ParseErr error
+
+	// AssembleErr, if set, is returned when assembling the package.
+	AssembleErr error
+}
+
+type fakeParser struct {
+	t          *testing.T
+	packages   map[string]*fakePackage // import path => package
+	mu         sync.Mutex
+	sawImports []string
+	sawFiles   [][]string // files of each parsed package
+
+	// Import paths of packages whose synopses were read.
+	sawSynopses []string
+
+	// onParse, if set, is called before parsing each package.
+	onParse func(importPath string)
+}
+
+var _ ServerParser = (*fakeParser)(nil)
+
+func (p *fakeParser) ParsePackage(ref *gosrc.PackageRef) (*gosrc.Package, error) {
+	if p.onParse != nil {
+		p.onParse(ref.ImportPath)
+	}
+
+	p.mu.Lock()
+	p.sawImports = append(p.sawImports, ref.ImportPath)
+	p.sawFiles = append(p.sawFiles, ref.Files)
+	p.mu.Unlock()
+
+	pkg, ok := p.packages[ref.ImportPath]
+	if !ok {
+		return nil, errtrace.Wrap(fmt.Errorf("unexpected package %q", ref.ImportPath))
+	}
+	if pkg.ParseErr != nil {
+		return nil, errtrace.Wrap(pkg.ParseErr)
+	}
+	return &gosrc.Package{
+		Name:       ref.Name,
+		ImportPath: pkg.ImportPath,
+	}, nil
+}
+
+func (p *fakeParser) ParseSynopsis(ref *gosrc.PackageRef) (string, error) {
+	p.mu.Lock()
+	p.sawSynopses = append(p.sawSynopses, ref.ImportPath)
+	p.mu.Unlock()
+
+	pkg, ok := p.packages[ref.ImportPath]
+	if !ok {
+		return "", errtrace.Wrap(fmt.Errorf("unexpected package %q", ref.ImportPath))
+	}
+	if pkg.ParseErr != nil {
+		return "", errtrace.Wrap(pkg.ParseErr)
+	}
+	return pkg.Synopsis, nil
+}
+
+type fakeAssembler struct {
+	t          *testing.T
+	packages   map[string]*fakePackage // import path => package
+	mu         sync.Mutex
+	sawImports []string
+}
+
+var _ Assembler = (*fakeAssembler)(nil)
+
+func (as *fakeAssembler) Assemble(bpkg *gosrc.Package) (*godoc.Package, error) {
+	as.mu.Lock()
+	as.sawImports = append(as.sawImports, bpkg.ImportPath)
+	as.mu.Unlock()
+
+	pkg, ok := as.packages[bpkg.ImportPath]
+	if !ok {
+		return nil, errtrace.Wrap(fmt.Errorf("unexpected package %q", bpkg.ImportPath))
+	}
+	if pkg.AssembleErr != nil {
+		return nil, errtrace.Wrap(pkg.AssembleErr)
+	}
+	if pkg.Doc != nil {
+		return pkg.Doc, nil
+	}
+	return &godoc.Package{
+		Name:       bpkg.Name,
+		ImportPath: pkg.ImportPath,
+		Synopsis:   pkg.Synopsis,
+	}, nil
+}
+
+type renderInfo struct {
+	Breadcrumbs     []html.Breadcrumb
+	Subpackages     []html.Subpackage
+	CanonicalSubDir string
+}
+
+type fakeRenderer struct {
+	t               *testing.T
+	mu              sync.Mutex
+	wantPackages    map[string]*renderInfo
+	wantDirectories map[string]*renderInfo
+	static          map[string][]byte
+	siteIndex       *html.SiteIndex // last rendered site index
+	siteIndexErr    error           // returned by RenderSiteIndex
+}
+
+var _ Renderer = (*fakeRenderer)(nil)
+
+func (r *fakeRenderer) StaticFiles() (map[string][]byte, error) { return r.static, nil }
+
+func (r *fakeRenderer) RenderPackage(_ io.Writer, pkgInfo *html.PackageInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	imppath := pkgInfo.ImportPath
+	want, ok := r.wantPackages[imppath]
+	if !ok {
+		return errtrace.Wrap(fmt.Errorf("unexpected package %q", imppath))
+	}
+	delete(r.wantPackages, imppath)
+
+	assert.Equal(r.t, want.Breadcrumbs, pkgInfo.Breadcrumbs, "breadcrumbs for %q", imppath)
+	assert.Equal(r.t, want.Subpackages, pkgInfo.Subpackages, "subpackages for %q", imppath)
+	assert.Equal(r.t, want.CanonicalSubDir, pkgInfo.CanonicalSubDir, "canonical subdir for %q", imppath)
+	return nil
+}
+
+func (r *fakeRenderer) RenderPackageIndex(_ io.Writer, idx *html.PackageIndex) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := idx.Path
+	want, ok := r.wantDirectories[path]
+	if !ok {
+		return errtrace.Wrap(fmt.Errorf("unexpected directory %q", path))
+	}
+	delete(r.wantDirectories, path)
+
+	assert.Equal(r.t, want.Breadcrumbs, idx.Breadcrumbs, "breadcrumbs for %q", path)
+	assert.Equal(r.t, want.Subpackages, idx.Subpackages, "subpackages for %q", path)
+	assert.Equal(r.t, want.CanonicalSubDir, idx.CanonicalSubDir, "canonical subdir for %q", path)
+	return nil
+}
+
+func (r *fakeRenderer) RenderSiteIndex(_ io.Writer, idx *html.SiteIndex) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.siteIndex = idx
+	return errtrace.Wrap(r.siteIndexErr)
+}
+
+func (r *fakeRenderer) RenderRedirect(w io.Writer, redirect *html.Redirect) error {
+	_, err := io.WriteString(w, redirect.To)
+	return errtrace.Wrap(err)
+}
+
+type nopDocLinker struct{}
+
+func (n *nopDocLinker) DocLinkURL(string, *comment.DocLink) string {
+	return ""
+}
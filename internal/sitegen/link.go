@@ -1,4 +1,4 @@
-package main
+package sitegen
 
 import (
 	"bytes"
@@ -25,12 +25,20 @@ type ModuleLookuper interface {
 
 var _ ModuleLookuper = (*gomod.Tree)(nil)
 
-type docLinker struct {
+// DocLinker generates links to the documentation of packages.
+//
+// Links to local packages are relative.
+// Other packages link to the first matching template,
+// or pkg.go.dev if no template matches.
+//
+// The zero value is ready to use.
+type DocLinker struct {
 	knownImports map[string]struct{}
 	templates    pathtree.Root[*template.Template]
 
-	RelLinkStyle relLinkStyle
-	Basename     string
+	// NormalizeRelativePath is an optional function that
+	// normalizes relative links between local packages.
+	NormalizeRelativePath func(string) string
 
 	// ModuleTree provides module version information for external dependencies.
 	// If nil, external links are generated without version information.
@@ -41,7 +49,7 @@ type docLinker struct {
 //
 // A local package is part of the current documentation generation scope,
 // so links to these packages will be relative.
-func (rl *docLinker) LocalPackage(importPath string) {
+func (rl *DocLinker) LocalPackage(importPath string) {
 	if rl.knownImports == nil {
 		rl.knownImports = make(map[string]struct{})
 	}
@@ -50,7 +58,7 @@ func (rl *docLinker) LocalPackage(importPath string) {
 
 // Template specifies a package documentation template
 // for packages at this import path and its descendants.
-func (rl *docLinker) Template(path string, tmpl *template.Template) {
+func (rl *DocLinker) Template(path string, tmpl *template.Template) {
 	rl.templates.Set(path, tmpl)
 }
 
@@ -76,9 +84,13 @@ type packageDocTemplateModuleData struct {
 	Subpath string
 }
 
-func (rl *docLinker) packageDocURL(fromPkg, pkg string) string {
+func (rl *DocLinker) packageDocURL(fromPkg, pkg string) string {
 	if _, ok := rl.knownImports[pkg]; ok {
-		return rl.RelLinkStyle.Normalize(relative.Path(fromPkg, pkg), rl.Basename)
+		link := relative.Path(fromPkg, pkg)
+		if rl.NormalizeRelativePath != nil {
+			link = rl.NormalizeRelativePath(link)
+		}
+		return link
 	}
 
 	var (
@@ -134,7 +146,9 @@ func (rl *docLinker) packageDocURL(fromPkg, pkg string) string {
 	return link.String()
 }
 
-func (rl *docLinker) DocLinkURL(fromPkg string, l *comment.DocLink) string {
+// DocLinkURL returns the URL for the given documentation link
+// found in the documentation of fromPkg.
+func (rl *DocLinker) DocLinkURL(fromPkg string, l *comment.DocLink) string {
 	var sb strings.Builder
 	if l.ImportPath != "" {
 		sb.WriteString(rl.packageDocURL(fromPkg, l.ImportPath))
@@ -1,4 +1,4 @@
-package main
+package sitegen
 
 import (
 	"go/doc/comment"
@@ -13,7 +13,7 @@ import (
 func TestDocLinker(t *testing.T) {
 	t.Parallel()
 
-	var linker docLinker
+	var linker DocLinker
 	linker.LocalPackage("example.com/foo")
 	linker.LocalPackage("example.com/bar")
 	linker.Template("foo.whatever/baz/qux",
@@ -131,7 +131,7 @@ func TestDocLinker_templateModuleData(t *testing.T) {
 		{Path: "github.com/stretchr/testify", Version: "v1.8.4"},
 	})
 
-	var linker docLinker
+	var linker DocLinker
 	linker.ModuleTree = &tree
 	linker.LocalPackage("example.com/myproject")
 
@@ -200,7 +200,7 @@ func TestDocLinker_versionedLinks(t *testing.T) {
 		{Path: "github.com/stretchr/testify", Version: "v1.8.4"},
 	})
 
-	var linker docLinker
+	var linker DocLinker
 	linker.ModuleTree = &tree
 	linker.LocalPackage("example.com/myproject")
 	linker.LocalPackage("example.com/myproject/foo")
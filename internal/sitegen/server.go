@@ -0,0 +1,447 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/pathx"
+)
+
+// ServerRenderer renders pages served by a [Server].
+type ServerRenderer interface {
+	StaticFiles() (map[string][]byte, error)
+	RenderPackage(io.Writer, *html.PackageInfo) error
+	RenderPackageIndex(io.Writer, *html.PackageIndex) error
+}
+
+var _ ServerRenderer = (*html.Renderer)(nil)
+
+// ServerParser parses packages served by a [Server].
+type ServerParser interface {
+	Parser
+
+	// ParseSynopsis reads only the synopsis of a package.
+	// It's used for packages that are only listed as subpackages.
+	ParseSynopsis(*gosrc.PackageRef) (string, error)
+}
+
+var _ ServerParser = (*gosrc.Parser)(nil)
+
+// Finder finds packages matching import path patterns.
+type Finder interface {
+	FindPackages(patterns ...string) ([]*gosrc.PackageRef, error)
+}
+
+var _ Finder = (*gosrc.Finder)(nil)
+
+// Server serves documentation for Go packages over HTTP.
+//
+// Unlike Generator, Server renders pages on demand.
+// Parsed packages are cached in memory,
+// and re-parsed when their source files change.
+// Packages that are only listed as subpackages
+// have just their synopses read.
+type Server struct {
+	// Log receives messages about packages that failed to load.
+	Log *log.Logger // required
+
+	// Logger for debug messages.
+	DebugLog *log.Logger
+
+	Parser    ServerParser   // required
+	Assembler Assembler      // required
+	Renderer  ServerRenderer // required
+
+	DocLinker godoc.Linker // required
+
+	// Packages to serve documentation for.
+	//
+	// Packages added to the filesystem after the server starts
+	// will not be picked up.
+	Packages []*gosrc.PackageRef
+
+	// Finder, if set, is used to find a package again
+	// when Go files are added to or removed from its directory.
+	// Without it, only the files the package had at startup
+	// are documented.
+	Finder Finder
+
+	// Basename of pages.
+	// Requests for DIR/Basename are served the page for DIR.
+	//
+	// Defaults to index.html.
+	Basename string
+
+	// Home page of the documentation.
+	// Anything not under this path will not be served.
+	Home string
+
+	PkgVersion string
+
+	once    sync.Once
+	initErr error
+	trees   []packageTree
+	static  map[string][]byte // path relative to html.StaticDir => contents
+
+	// Not modified after init.
+	// Each package guards its own state.
+	pkgs map[string]*servedPackage // import path => package
+}
+
+// servedPackage is a package that the server can load.
+type servedPackage struct {
+	// mu is held while the package is being loaded
+	// so that concurrent requests for it share a single load.
+	mu sync.Mutex
+
+	// Reference that the package was loaded from.
+	ref *gosrc.PackageRef
+
+	// Names of Go files in the package directory
+	// at the time the package was found.
+	files string
+
+	// Fingerprint of the package's files
+	// at the time the fields below were loaded.
+	fingerprint string
+
+	loaded   bool // whether synopsis is set
+	synopsis string
+	pkg      *godoc.Package // nil until fully loaded
+}
+
+var _ http.Handler = (*Server)(nil)
+
+func (s *Server) init() error {
+	s.once.Do(func() {
+		if s.DebugLog == nil {
+			s.DebugLog = log.New(io.Discard, "", 0)
+		}
+		if s.Basename == "" {
+			s.Basename = "index.html"
+		}
+
+		s.trees = buildTrees(s.Packages)
+		if s.Home != "" {
+			s.trees = filterTrees(s.Home, s.trees)
+		}
+		s.pkgs = make(map[string]*servedPackage)
+		for _, ref := range s.Packages {
+			// Remember the files that each package started with
+			// so that we can tell when files are added or removed.
+			files, _, err := scanPackageDir(ref)
+			if err != nil {
+				s.DebugLog.Printf("[%v] %v", ref.ImportPath, err)
+			}
+			s.pkgs[ref.ImportPath] = &servedPackage{ref: ref, files: files}
+		}
+		s.static, s.initErr = s.Renderer.StaticFiles()
+	})
+	return errtrace.Wrap(s.initErr)
+}
+
+// ServeHTTP serves a documentation page or static asset.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if err := s.init(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	urlPath := strings.TrimPrefix(req.URL.Path, "/")
+	if name, ok := strings.CutPrefix(urlPath, html.StaticDir+"/"); ok {
+		s.serveStatic(w, req, name)
+		return
+	}
+
+	var dir string
+	switch {
+	case urlPath == "":
+		// Root of the site.
+	case path.Base(urlPath) == s.Basename:
+		dir = strings.TrimSuffix(path.Dir(urlPath), ".")
+	case strings.HasSuffix(urlPath, "/"):
+		dir = strings.TrimSuffix(urlPath, "/")
+	default:
+		// Relative links in pages expect the page to be a directory.
+		target := *req.URL
+		target.Path += "/"
+		http.Redirect(w, req, target.String(), http.StatusMovedPermanently)
+		return
+	}
+
+	importPath := path.Join(s.Home, dir)
+	t, crumbs, ok := s.find(importPath)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	var (
+		buff bytes.Buffer
+		err  error
+	)
+	if t.Value == nil {
+		err = s.renderPackageIndex(&buff, crumbs, t)
+	} else {
+		err = s.renderPackage(&buff, crumbs, t)
+	}
+	if err != nil {
+		s.Log.Printf("[%v] %+v", importPath, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buff.WriteTo(w)
+}
+
+func (s *Server) serveStatic(w http.ResponseWriter, req *http.Request, name string) {
+	bs, ok := s.static[name]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	http.ServeContent(w, req, name, time.Time{}, bytes.NewReader(bs))
+}
+
+// find locates the tree for the given import path,
+// and returns it with breadcrumbs leading up to it.
+func (s *Server) find(importPath string) (packageTree, []html.Breadcrumb, bool) {
+	var crumbs []html.Breadcrumb
+	trees := s.trees
+	for len(trees) > 0 {
+		var next []packageTree
+		for _, t := range trees {
+			if t.Path == importPath {
+				return t, appendBreadcrumb(crumbs, t), true
+			}
+
+			if t.Path == "" || pathx.Descends(t.Path, importPath) {
+				crumbs = appendBreadcrumb(crumbs, t)
+				next = t.Children
+				break
+			}
+		}
+		trees = next
+	}
+	return packageTree{}, nil, false
+}
+
+func (s *Server) renderPackageIndex(w io.Writer, crumbs []html.Breadcrumb, t packageTree) error {
+	s.DebugLog.Printf("Rendering directory %v", t.Path)
+
+	idx := html.PackageIndex{
+		Path:        t.Path,
+		NumChildren: len(t.Children),
+		Subpackages: htmlSubpackages(t.Path, s.subpackages(t.Children)),
+		Breadcrumbs: crumbs,
+	}
+	return errtrace.Wrap(s.Renderer.RenderPackageIndex(w, &idx))
+}
+
+func (s *Server) renderPackage(w io.Writer, crumbs []html.Breadcrumb, t packageTree) error {
+	s.DebugLog.Printf("Rendering package %v", t.Path)
+
+	dpkg, err := s.load(*t.Value)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	info := html.PackageInfo{
+		Package:     dpkg,
+		NumChildren: len(t.Children),
+		Breadcrumbs: crumbs,
+		Subpackages: htmlSubpackages(dpkg.ImportPath, s.subpackages(t.Children)),
+		DocPrinter:  newDocPrinter(s.DocLinker, dpkg.ImportPath),
+		PkgVersion:  s.PkgVersion,
+	}
+	if err := s.Renderer.RenderPackage(w, &info); err != nil {
+		return errtrace.Wrap(fmt.Errorf("render: %w", err))
+	}
+	return nil
+}
+
+// subpackages returns the closest packages inside the given trees.
+//
+// Packages that fail to load are logged and omitted.
+func (s *Server) subpackages(trees []packageTree) []*renderedPackage {
+	var pkgs []*renderedPackage
+	for _, t := range trees {
+		if t.Value == nil {
+			pkgs = append(pkgs, s.subpackages(t.Children)...)
+			continue
+		}
+
+		synopsis, err := s.loadSynopsis(*t.Value)
+		if err != nil {
+			s.Log.Printf("[%v] %+v", t.Path, err)
+			continue
+		}
+		pkgs = append(pkgs, &renderedPackage{
+			ImportPath: t.Path,
+			Synopsis:   synopsis,
+		})
+	}
+	return pkgs
+}
+
+// load parses and assembles the given package,
+// re-using a previously loaded copy if its files haven't changed.
+func (s *Server) load(ref *gosrc.PackageRef) (*godoc.Package, error) {
+	sp, err := s.lockPackage(ref)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	defer sp.mu.Unlock()
+
+	if sp.pkg != nil {
+		return sp.pkg, nil
+	}
+
+	s.DebugLog.Printf("Loading package %v", sp.ref.ImportPath)
+	bpkg, err := s.Parser.ParsePackage(sp.ref)
+	if err != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("parse: %w", err))
+	}
+
+	dpkg, err := s.Assembler.Assemble(bpkg)
+	if err != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("assemble: %w", err))
+	}
+
+	sp.loaded = true
+	sp.synopsis = dpkg.Synopsis
+	sp.pkg = dpkg
+	return dpkg, nil
+}
+
+// loadSynopsis reads the synopsis of the given package,
+// re-using a previously loaded copy if its files haven't changed.
+//
+// Unlike load, this doesn't parse the rest of the package.
+func (s *Server) loadSynopsis(ref *gosrc.PackageRef) (string, error) {
+	sp, err := s.lockPackage(ref)
+	if err != nil {
+		return "", errtrace.Wrap(err)
+	}
+	defer sp.mu.Unlock()
+
+	if sp.loaded {
+		return sp.synopsis, nil
+	}
+
+	s.DebugLog.Printf("Loading synopsis of %v", sp.ref.ImportPath)
+	synopsis, err := s.Parser.ParseSynopsis(sp.ref)
+	if err != nil {
+		return "", errtrace.Wrap(fmt.Errorf("parse: %w", err))
+	}
+
+	sp.loaded = true
+	sp.synopsis = synopsis
+	return synopsis, nil
+}
+
+// lockPackage locks the state of the given package,
+// and discards what was loaded for it if its files have changed.
+// If files were added or removed, the package is found again.
+//
+// The caller must unlock the package when it's done with it.
+func (s *Server) lockPackage(ref *gosrc.PackageRef) (*servedPackage, error) {
+	sp, ok := s.pkgs[ref.ImportPath]
+	if !ok {
+		return nil, errtrace.Wrap(fmt.Errorf("package %v is not served", ref.ImportPath))
+	}
+
+	sp.mu.Lock()
+	files, fingerprint, err := scanPackageDir(sp.ref)
+	if err != nil {
+		sp.mu.Unlock()
+		return nil, errtrace.Wrap(err)
+	}
+	if sp.loaded && sp.fingerprint == fingerprint {
+		return sp, nil
+	}
+
+	if sp.files != files && s.Finder != nil {
+		ref, err := s.refind(sp.ref)
+		if err != nil {
+			sp.mu.Unlock()
+			return nil, errtrace.Wrap(fmt.Errorf("find: %w", err))
+		}
+		sp.ref = ref
+		sp.files = files
+	}
+
+	sp.fingerprint = fingerprint
+	sp.loaded = false
+	sp.synopsis = ""
+	sp.pkg = nil
+	return sp, nil
+}
+
+// refind finds the given package again
+// to pick up files added to or removed from its directory.
+func (s *Server) refind(ref *gosrc.PackageRef) (*gosrc.PackageRef, error) {
+	s.DebugLog.Printf("Finding package %v", ref.ImportPath)
+	refs, err := s.Finder.FindPackages(filepath.Dir(ref.Files[0]))
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	for _, r := range refs {
+		if r.ImportPath == ref.ImportPath {
+			return r, nil
+		}
+	}
+	return nil, errtrace.Wrap(fmt.Errorf("package %v not found", ref.ImportPath))
+}
+
+// scanPackageDir lists the Go files in the package's directory,
+// including those it doesn't use, like the Finder does,
+// and builds a fingerprint that changes if any of them are modified.
+//
+// Both are empty if the package doesn't have files.
+func scanPackageDir(ref *gosrc.PackageRef) (files, fingerprint string, err error) {
+	if len(ref.Files) == 0 {
+		return "", "", nil
+	}
+
+	dir := filepath.Dir(ref.Files[0])
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", errtrace.Wrap(err)
+	}
+
+	var names, sb strings.Builder
+	for _, ent := range ents {
+		if ent.IsDir() || !strings.HasSuffix(ent.Name(), ".go") {
+			continue
+		}
+
+		info, err := ent.Info()
+		if err != nil {
+			return "", "", errtrace.Wrap(err)
+		}
+		fmt.Fprintf(&names, "%s\n", ent.Name())
+		fmt.Fprintf(&sb, "%s %d %d\n", ent.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return names.String(), sb.String(), nil
+}
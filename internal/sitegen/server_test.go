@@ -0,0 +1,350 @@
+package sitegen
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/highlight"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/iotest"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	srcDir := t.TempDir()
+	barFile := filepath.Join(srcDir, "bar.go")
+	require.NoError(t, os.WriteFile(barFile, []byte("package bar"), 0o644))
+
+	pkgs := map[string]*fakePackage{
+		"example.com/foo":     {ImportPath: "example.com/foo", Synopsis: "Package foo does things."},
+		"example.com/foo/bar": {ImportPath: "example.com/foo/bar", Synopsis: "Package bar does other things."},
+	}
+	parser := fakeParser{t: t, packages: pkgs}
+
+	srv := httptest.NewServer(&Server{
+		Log:       log.New(iotest.Writer(t), "", 0),
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &parser,
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer: &html.Renderer{
+			Highlighter: &highlight.Highlighter{
+				Style:      highlight.PlainStyle,
+				UseClasses: true,
+			},
+		},
+		DocLinker: new(nopDocLinker),
+		Packages: []*gosrc.PackageRef{
+			{Name: "foo", ImportPath: "example.com/foo"},
+			{Name: "bar", ImportPath: "example.com/foo/bar", Files: []string{barFile}},
+		},
+	})
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse //errtrace:skip // compared with ==
+	}
+
+	get := func(path string) (*http.Response, string) {
+		res, err := client.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, res.Body.Close())
+		}()
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+
+	t.Run("root", func(t *testing.T) {
+		res, body := get("/")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, "Package foo does things.")
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		res, body := get("/example.com/")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, `href="foo"`)
+	})
+
+	t.Run("package", func(t *testing.T) {
+		res, body := get("/example.com/foo/")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, "package foo")
+		assert.Contains(t, body, "Package bar does other things.")
+	})
+
+	t.Run("index file", func(t *testing.T) {
+		res, body := get("/example.com/foo/bar/index.html")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, body, "package bar")
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		res, _ := get("/example.com/foo")
+		assert.Equal(t, http.StatusMovedPermanently, res.StatusCode)
+		assert.Equal(t, "/example.com/foo/", res.Header.Get("Location"))
+	})
+
+	t.Run("static", func(t *testing.T) {
+		res, body := get("/_/css/main.css")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, res.Header.Get("Content-Type"), "text/css")
+		assert.NotEmpty(t, body)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		res, _ := get("/example.com/baz/")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		res, _ = get("/_/does-not-exist.css")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("reload on change", func(t *testing.T) {
+		countParses := func() (n int) {
+			parser.mu.Lock()
+			defer parser.mu.Unlock()
+			for _, imp := range parser.sawImports {
+				if imp == "example.com/foo/bar" {
+					n++
+				}
+			}
+			return n
+		}
+
+		get("/example.com/foo/bar/")
+		before := countParses()
+
+		get("/example.com/foo/bar/")
+		assert.Equal(t, before, countParses(), "unchanged package must not be re-parsed")
+
+		future := time.Now().Add(time.Hour)
+		require.NoError(t, os.Chtimes(barFile, future, future))
+		get("/example.com/foo/bar/")
+		assert.Equal(t, before+1, countParses(), "changed package must be re-parsed")
+	})
+}
+
+func TestServer_newFile(t *testing.T) {
+	t.Parallel()
+
+	srcDir := t.TempDir()
+	fooFile := filepath.Join(srcDir, "foo.go")
+	require.NoError(t, os.WriteFile(fooFile, []byte("package foo"), 0o644))
+
+	pkgs := map[string]*fakePackage{
+		"example.com/foo": {ImportPath: "example.com/foo"},
+	}
+	parser := fakeParser{t: t, packages: pkgs}
+	finder := fakeFinder{
+		refs: []*gosrc.PackageRef{
+			{Name: "foo", ImportPath: "example.com/foo", Files: []string{fooFile}},
+		},
+	}
+	srv := &Server{
+		Log:       log.New(iotest.Writer(t), "", 0),
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &parser,
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer: &html.Renderer{
+			Highlighter: &highlight.Highlighter{
+				Style:      highlight.PlainStyle,
+				UseClasses: true,
+			},
+		},
+		DocLinker: new(nopDocLinker),
+		Packages:  finder.refs,
+		Finder:    &finder,
+	}
+
+	get := func() {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/example.com/foo/", nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	get()
+	assert.Empty(t, finder.patterns, "unchanged package must not be found again")
+
+	// Modifying a file re-parses the package,
+	// but its list of files is unchanged.
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(fooFile, future, future))
+	get()
+	assert.Empty(t, finder.patterns, "modified package must not be found again")
+
+	// Adding a file finds the package again
+	// and parses it with the new file.
+	barFile := filepath.Join(srcDir, "bar.go")
+	require.NoError(t, os.WriteFile(barFile, []byte("package foo"), 0o644))
+	finder.refs = []*gosrc.PackageRef{
+		{Name: "foo", ImportPath: "example.com/foo", Files: []string{barFile, fooFile}},
+	}
+	get()
+	assert.Equal(t, []string{srcDir}, finder.patterns)
+	require.NotEmpty(t, parser.sawFiles)
+	assert.Equal(t, []string{barFile, fooFile}, parser.sawFiles[len(parser.sawFiles)-1])
+
+	// So does removing one.
+	require.NoError(t, os.Remove(barFile))
+	finder.refs = []*gosrc.PackageRef{
+		{Name: "foo", ImportPath: "example.com/foo", Files: []string{fooFile}},
+	}
+	get()
+	assert.Equal(t, []string{srcDir, srcDir}, finder.patterns)
+	assert.Equal(t, []string{fooFile}, parser.sawFiles[len(parser.sawFiles)-1])
+}
+
+func TestServer_subpackageSynopses(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{
+		"example.com/foo":     {ImportPath: "example.com/foo"},
+		"example.com/foo/bar": {ImportPath: "example.com/foo/bar", Synopsis: "Package bar does things."},
+		"example.com/foo/baz": {ImportPath: "example.com/foo/baz", Synopsis: "Package baz does things."},
+	}
+	parser := fakeParser{t: t, packages: pkgs}
+	srv := &Server{
+		Log:       log.New(iotest.Writer(t), "", 0),
+		Parser:    &parser,
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer: &html.Renderer{
+			Highlighter: &highlight.Highlighter{Style: highlight.PlainStyle},
+		},
+		DocLinker: new(nopDocLinker),
+		Packages: []*gosrc.PackageRef{
+			{Name: "foo", ImportPath: "example.com/foo"},
+			{Name: "bar", ImportPath: "example.com/foo/bar"},
+			{Name: "baz", ImportPath: "example.com/foo/baz"},
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/example.com/foo/", nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), "Package bar does things.")
+	assert.Contains(t, rec.Body.String(), "Package baz does things.")
+
+	assert.Equal(t, []string{"example.com/foo"}, parser.sawImports,
+		"subpackages must not be parsed")
+	assert.ElementsMatch(t, []string{"example.com/foo/bar", "example.com/foo/baz"}, parser.sawSynopses)
+}
+
+func TestServer_concurrentRequests(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{
+		"example.com/foo": {ImportPath: "example.com/foo"},
+	}
+	parser := fakeParser{
+		t:        t,
+		packages: pkgs,
+		// Slow down parsing so that requests overlap.
+		onParse: func(string) { time.Sleep(10 * time.Millisecond) },
+	}
+	srv := &Server{
+		Log:       log.New(iotest.Writer(t), "", 0),
+		Parser:    &parser,
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer: &html.Renderer{
+			Highlighter: &highlight.Highlighter{Style: highlight.PlainStyle},
+		},
+		DocLinker: new(nopDocLinker),
+		Packages: []*gosrc.PackageRef{
+			{Name: "foo", ImportPath: "example.com/foo"},
+		},
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/example.com/foo/", nil))
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{"example.com/foo"}, parser.sawImports,
+		"package must be loaded once")
+}
+
+type fakeFinder struct {
+	refs     []*gosrc.PackageRef
+	patterns []string
+}
+
+var _ Finder = (*fakeFinder)(nil)
+
+func (f *fakeFinder) FindPackages(patterns ...string) ([]*gosrc.PackageRef, error) {
+	f.patterns = append(f.patterns, patterns...)
+	return f.refs, nil
+}
+
+func TestServer_home(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{
+		"example.com/foo":     {ImportPath: "example.com/foo"},
+		"example.com/foo/bar": {ImportPath: "example.com/foo/bar"},
+		"example.com/baz":     {ImportPath: "example.com/baz"},
+	}
+	srv := httptest.NewServer(&Server{
+		Log:       log.New(iotest.Writer(t), "", 0),
+		Parser:    &fakeParser{t: t, packages: pkgs},
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer: &html.Renderer{
+			Home:        "example.com/foo",
+			Highlighter: &highlight.Highlighter{Style: highlight.PlainStyle},
+		},
+		DocLinker: new(nopDocLinker),
+		Home:      "example.com/foo",
+		Packages: []*gosrc.PackageRef{
+			{Name: "foo", ImportPath: "example.com/foo"},
+			{Name: "bar", ImportPath: "example.com/foo/bar"},
+			{Name: "baz", ImportPath: "example.com/baz"},
+		},
+	})
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		path     string
+		want     int
+		contains string
+	}{
+		{path: "/", want: http.StatusOK, contains: "package foo"},
+		{path: "/bar/", want: http.StatusOK, contains: "package bar"},
+		{path: "/example.com/baz/", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res, err := srv.Client().Get(srv.URL + tt.path)
+			require.NoError(t, err)
+			defer func() {
+				assert.NoError(t, res.Body.Close())
+			}()
+
+			assert.Equal(t, tt.want, res.StatusCode)
+			body, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.contains)
+		})
+	}
+}
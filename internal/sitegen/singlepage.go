@@ -0,0 +1,89 @@
+package sitegen
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/html"
+)
+
+// SinglePageRenderer renders the documentation for many packages
+// into a single page.
+type SinglePageRenderer interface {
+	RenderSinglePage(io.Writer, *html.SinglePage) error
+}
+
+var _ SinglePageRenderer = (*html.Renderer)(nil)
+
+// SinglePageGenerator generates documentation for Go packages
+// into a single, self-contained HTML document.
+//
+// Unlike Generator, it doesn't write a website.
+type SinglePageGenerator struct {
+	DebugLog *log.Logger
+
+	Parser    Parser             // required
+	Assembler Assembler          // required
+	Renderer  SinglePageRenderer // required
+
+	DocLinker godoc.Linker // required
+
+	// Title of the document.
+	Title string
+
+	PkgVersion string
+}
+
+// Generate renders documentation for the given packages into w,
+// ordered by import path.
+func (g *SinglePageGenerator) Generate(ctx context.Context, w io.Writer, pkgRefs []*gosrc.PackageRef) error {
+	debugLog := g.DebugLog
+	if debugLog == nil {
+		debugLog = log.New(io.Discard, "", 0)
+	}
+
+	pkgRefs = slices.SortedFunc(slices.Values(pkgRefs), func(a, b *gosrc.PackageRef) int {
+		return cmp.Compare(a.ImportPath, b.ImportPath)
+	})
+
+	infos := make([]*html.PackageInfo, 0, len(pkgRefs))
+	for _, ref := range pkgRefs {
+		if err := ctx.Err(); err != nil {
+			return errtrace.Wrap(err)
+		}
+
+		debugLog.Printf("Adding %v", ref.ImportPath)
+		bpkg, err := g.Parser.ParsePackage(ref)
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("%v: parse: %w", ref.ImportPath, err))
+		}
+
+		dpkg, err := g.Assembler.Assemble(bpkg)
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("%v: assemble: %w", ref.ImportPath, err))
+		}
+
+		infos = append(infos, &html.PackageInfo{
+			Package:    dpkg,
+			DocPrinter: newDocPrinter(g.DocLinker, dpkg.ImportPath),
+			PkgVersion: g.PkgVersion,
+		})
+	}
+
+	err := g.Renderer.RenderSinglePage(w, &html.SinglePage{
+		Title:      g.Title,
+		PkgVersion: g.PkgVersion,
+		Packages:   infos,
+	})
+	if err != nil {
+		return errtrace.Wrap(fmt.Errorf("render: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package sitegen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+
+	"braces.dev/errtrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/iotest"
+)
+
+func TestSinglePageGenerator(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{
+		"foo":     {ImportPath: "foo", Synopsis: "package foo"},
+		"foo/bar": {ImportPath: "foo/bar", Synopsis: "package bar"},
+		"foo/cmd": {ImportPath: "foo/cmd"},
+	}
+
+	var buf bytes.Buffer
+	err := (&SinglePageGenerator{
+		DebugLog:   log.New(iotest.Writer(t), "", 0),
+		Parser:     &fakeParser{t: t, packages: pkgs},
+		Assembler:  &fakeAssembler{t: t, packages: pkgs},
+		Renderer:   new(fakeSinglePageRenderer),
+		DocLinker:  new(nopDocLinker),
+		Title:      "My docs",
+		PkgVersion: "v1.2.3",
+	}).Generate(context.Background(), &buf, []*gosrc.PackageRef{
+		{Name: "main", ImportPath: "foo/cmd"},
+		{Name: "foo", ImportPath: "foo"},
+		{Name: "bar", ImportPath: "foo/bar"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "My docs v1.2.3\n"+
+		"foo\tpackage foo\tv1.2.3\n"+
+		"foo/bar\tpackage bar\tv1.2.3\n"+
+		"foo/cmd\t\tv1.2.3\n", buf.String())
+}
+
+func TestSinglePageGenerator_parseError(t *testing.T) {
+	t.Parallel()
+
+	giveErr := errors.New("great sadness")
+	pkgs := map[string]*fakePackage{
+		"foo": {ImportPath: "foo", ParseErr: giveErr},
+	}
+
+	err := (&SinglePageGenerator{
+		Parser:    &fakeParser{t: t, packages: pkgs},
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer:  new(fakeSinglePageRenderer),
+		DocLinker: new(nopDocLinker),
+	}).Generate(context.Background(), io.Discard, []*gosrc.PackageRef{
+		{Name: "foo", ImportPath: "foo"},
+	})
+	assert.ErrorIs(t, err, giveErr)
+	assert.ErrorContains(t, err, "foo: parse")
+}
+
+type fakeSinglePageRenderer struct{}
+
+var _ SinglePageRenderer = (*fakeSinglePageRenderer)(nil)
+
+func (*fakeSinglePageRenderer) RenderSinglePage(w io.Writer, page *html.SinglePage) error {
+	if _, err := fmt.Fprintln(w, page.Title, page.PkgVersion); err != nil {
+		return errtrace.Wrap(err)
+	}
+	for _, info := range page.Packages {
+		if info.DocPrinter == nil {
+			return errtrace.Wrap(errors.New("missing DocPrinter"))
+		}
+		if _, err := fmt.Fprintf(w, "%v\t%v\t%v\n", info.ImportPath, info.Synopsis, info.PkgVersion); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package sitegen
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/siteurl"
+)
+
+const (
+	// SitemapFile is the name of the sitemap
+	// written to the root of the output directory.
+	SitemapFile = "sitemap.xml"
+
+	// RobotsFile is the name of the robots.txt file
+	// written to the root of the output directory
+	// if it's served from the root of the host.
+	RobotsFile = "robots.txt"
+)
+
+// Sitemap specifies how to generate a sitemap and robots.txt
+// for a website.
+//
+// Search engines only read robots.txt from the root of a host,
+// so it's written only if BaseURL is the root of its host.
+// Otherwise, the sitemap's URL and the rules that would have gone
+// into robots.txt are logged so that they can be registered by hand.
+type Sitemap struct {
+	// BaseURL is the absolute URL
+	// that the root of the output directory is served from.
+	BaseURL *url.URL // required
+
+	// LatestOnly specifies that when generating into a SubDir,
+	// only pages of the newest stable release should be listed.
+	// Otherwise, pages of other versions are listed
+	// if the newest stable release doesn't have them.
+	//
+	// All versions are listed if there are no stable releases.
+	LatestOnly bool
+
+	// Internal specifies whether internal packages should be listed.
+	// If false, they're left out of the sitemap,
+	// and robots.txt asks crawlers not to visit them.
+	Internal bool
+
+	// NormalizeRelativePath is an optional function that
+	// normalizes paths to pages, e.g. to add a trailing '/'.
+	//
+	// If unset, paths to pages get a trailing '/'.
+	NormalizeRelativePath func(string) string
+}
+
+// sitemapURLSet is the root element of a sitemap.
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+// writeSitemap writes the sitemap and robots.txt for the given pages
+// into the root of the output directory.
+// robots.txt is left out if the output directory isn't served
+// from the root of its host.
+//
+// Pages are specified relative to the root of the output directory.
+func (r *Generator) writeSitemap(pages []string) error {
+	s := r.Sitemap
+
+	var (
+		urlset   sitemapURLSet
+		disallow []string // URL paths of internal directories
+	)
+	for _, page := range pages {
+		if !s.Internal {
+			if dir, ok := internalRoot(page); ok {
+				disallow = append(disallow, siteurl.DirPath(s.BaseURL, dir))
+				continue
+			}
+		}
+
+		urlset.URLs = append(urlset.URLs, sitemapURL{
+			Loc: siteurl.Page(s.BaseURL, page, s.NormalizeRelativePath),
+		})
+	}
+
+	bs, err := xml.MarshalIndent(urlset, "", "  ")
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	bs = append([]byte(xml.Header), bs...)
+	bs = append(bs, '\n')
+	if err := r.writeFile(filepath.Join(r.rootDir, SitemapFile), bs); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	slices.Sort(disallow)
+	disallow = slices.Compact(disallow)
+
+	var robots strings.Builder
+	robots.WriteString("User-agent: *\n")
+	if len(disallow) == 0 {
+		robots.WriteString("Disallow:\n")
+	}
+	for _, p := range disallow {
+		fmt.Fprintf(&robots, "Disallow: %v\n", p)
+	}
+	sitemapURL := s.BaseURL.JoinPath(SitemapFile)
+	fmt.Fprintf(&robots, "\nSitemap: %v\n", sitemapURL)
+
+	r.DebugLog.Printf("Wrote %v with %d pages", SitemapFile, len(urlset.URLs))
+	if p := s.BaseURL.Path; p != "" && p != "/" {
+		robotsURL := s.BaseURL.ResolveReference(&url.URL{Path: "/" + RobotsFile})
+		r.Log.Printf("Not writing %v: search engines only read it from %v.\n"+
+			"Submit %v to search engines, or add the following to %v:\n\n%v",
+			RobotsFile, robotsURL, sitemapURL, robotsURL, robots.String())
+		return nil
+	}
+	return errtrace.Wrap(r.writeFile(filepath.Join(r.rootDir, RobotsFile), []byte(robots.String())))
+}
+
+// sitemapPages returns the pages of the given sibling sites
+// that should be listed in the sitemap,
+// relative to the root of the output directory.
+//
+// Only pages that are their own canonical versions are listed:
+// pages that also exist in the newest stable release
+// are listed only for that release.
+// See findCanonicalSite.
+func (r *Generator) sitemapPages(versions *siteVersions) ([]string, error) {
+	pages := []string{""} // sibling index
+
+	var latestPages map[string]struct{}
+	if latest := versions.Latest; latest != "" {
+		sitePages, err := r.sitePages(latest)
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("%v: %w", latest, err))
+		}
+		latestPages = make(map[string]struct{}, len(sitePages))
+		for _, page := range sitePages {
+			latestPages[page] = struct{}{}
+			pages = append(pages, path.Join(latest, page))
+		}
+
+		if r.Sitemap.LatestOnly {
+			return pages, nil
+		}
+	}
+
+	for _, site := range versions.All() {
+		if site == versions.Latest {
+			continue
+		}
+
+		sitePages, err := r.sitePages(site)
+		if err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("%v: %w", site, err))
+		}
+		for _, page := range sitePages {
+			if _, ok := latestPages[page]; ok {
+				continue // canonical version is in the latest release
+			}
+			pages = append(pages, path.Join(site, page))
+		}
+	}
+	return pages, nil
+}
+
+// internalRoot reports whether the given /-separated path
+// is for an internal package or directory,
+// and if so, returns the path of the "internal" directory it's inside.
+func internalRoot(p string) (string, bool) {
+	parts := strings.Split(p, "/")
+	idx := slices.Index(parts, "internal")
+	if idx < 0 {
+		return "", false
+	}
+	return strings.Join(parts[:idx+1], "/"), true
+}
@@ -0,0 +1,257 @@
+package sitegen
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/iotest"
+	"go.abhg.dev/doc2go/internal/output"
+)
+
+func TestGenerator_sitemap(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{
+		"foo":          {ImportPath: "foo"},
+		"foo/internal": {ImportPath: "foo/internal"},
+	}
+	refs := []*gosrc.PackageRef{
+		{Name: "foo", ImportPath: "foo"},
+		{Name: "internal", ImportPath: "foo/internal"},
+	}
+
+	newGenerator := func(t *testing.T, out output.FS, sitemap *Sitemap) *Generator {
+		return &Generator{
+			DebugLog:  log.New(iotest.Writer(t), "", 0),
+			Parser:    &fakeParser{t: t, packages: pkgs},
+			Assembler: &fakeAssembler{t: t, packages: pkgs},
+			Renderer: &fakeRenderer{
+				t: t,
+				wantPackages: map[string]*renderInfo{
+					"foo": {
+						Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}},
+						Subpackages: []html.Subpackage{{RelativePath: "internal"}},
+					},
+					"foo/internal": {
+						Breadcrumbs: []html.Breadcrumb{
+							{Text: "foo", Path: "foo"},
+							{Text: "internal", Path: "foo/internal"},
+						},
+					},
+				},
+				wantDirectories: map[string]*renderInfo{
+					"": {
+						Subpackages: []html.Subpackage{{RelativePath: "foo"}},
+					},
+				},
+			},
+			Output:    out,
+			DocLinker: new(nopDocLinker),
+			Sitemap:   sitemap,
+		}
+	}
+
+	baseURL, err := url.Parse("https://example.com/docs")
+	require.NoError(t, err)
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			mem  output.Memory
+			logw bytes.Buffer
+		)
+		g := newGenerator(t, &mem, &Sitemap{BaseURL: baseURL})
+		g.Log = log.New(&logw, "", 0)
+		_, err := g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+
+		files := mem.Files()
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/docs/</loc>
+  </url>
+  <url>
+    <loc>https://example.com/docs/foo/</loc>
+  </url>
+</urlset>
+`, string(files[SitemapFile]))
+
+		// robots.txt is only read from the root of the host.
+		assert.NotContains(t, files, RobotsFile)
+		assert.Contains(t, logw.String(),
+			"Submit https://example.com/docs/sitemap.xml to search engines, "+
+				"or add the following to https://example.com/robots.txt:")
+		assert.Contains(t, logw.String(), "User-agent: *\n"+
+			"Disallow: /docs/foo/internal/\n"+
+			"\n"+
+			"Sitemap: https://example.com/docs/sitemap.xml\n")
+	})
+
+	t.Run("host root", func(t *testing.T) {
+		t.Parallel()
+
+		rootURL, err := url.Parse("https://example.com/")
+		require.NoError(t, err)
+
+		var (
+			mem  output.Memory
+			logw bytes.Buffer
+		)
+		g := newGenerator(t, &mem, &Sitemap{BaseURL: rootURL})
+		g.Log = log.New(&logw, "", 0)
+		_, err = g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+
+		files := mem.Files()
+		assert.Equal(t, []string{
+			"https://example.com/",
+			"https://example.com/foo/",
+		}, sitemapLocs(t, files[SitemapFile]))
+		assert.Equal(t, "User-agent: *\n"+
+			"Disallow: /foo/internal/\n"+
+			"\n"+
+			"Sitemap: https://example.com/sitemap.xml\n",
+			string(files[RobotsFile]))
+		assert.Empty(t, logw.String())
+	})
+
+	t.Run("internal", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			mem  output.Memory
+			logw bytes.Buffer
+		)
+		g := newGenerator(t, &mem, &Sitemap{
+			BaseURL:  baseURL,
+			Internal: true,
+			NormalizeRelativePath: func(s string) string {
+				return strings.TrimSuffix(s, "/") + "/index.html"
+			},
+		})
+		g.Log = log.New(&logw, "", 0)
+		_, err := g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+
+		files := mem.Files()
+		assert.Contains(t, string(files[SitemapFile]),
+			"<loc>https://example.com/docs/index.html</loc>")
+		assert.Contains(t, string(files[SitemapFile]),
+			"<loc>https://example.com/docs/foo/internal/index.html</loc>")
+		assert.Contains(t, logw.String(), "User-agent: *\n"+
+			"Disallow:\n"+
+			"\n"+
+			"Sitemap: https://example.com/docs/sitemap.xml\n")
+	})
+
+	t.Run("subdir", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			mem  output.Memory
+			logw bytes.Buffer
+		)
+		g := newGenerator(t, &mem, &Sitemap{BaseURL: baseURL})
+		g.Log = log.New(&logw, "", 0)
+		g.SubDir = "v1.0.0"
+		g.Siblings = []string{"v0.1.0", "main"}
+
+		// Pages that the latest release doesn't have
+		// are listed for the versions that have them.
+		writeMemoryFile(t, &mem, "v0.1.0/_/pages.json", `{"pages": ["", "foo", "old"]}`)
+
+		_, err := g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+
+		// Other versions of pages in the latest release
+		// name it as canonical, so they're left out.
+		files := mem.Files()
+		assert.Equal(t, []string{
+			"https://example.com/docs/",
+			"https://example.com/docs/v1.0.0/",
+			"https://example.com/docs/v1.0.0/foo/",
+			"https://example.com/docs/v0.1.0/old/",
+		}, sitemapLocs(t, files[SitemapFile]))
+		assert.Contains(t, logw.String(), "Disallow: /docs/v1.0.0/foo/internal/\n")
+		assert.NotContains(t, files, "v1.0.0/"+SitemapFile)
+	})
+
+	t.Run("subdir latest only", func(t *testing.T) {
+		t.Parallel()
+
+		var mem output.Memory
+		g := newGenerator(t, &mem, &Sitemap{BaseURL: baseURL, LatestOnly: true})
+		g.SubDir = "v0.1.0"
+		g.Siblings = []string{"v1.0.0", "main"}
+		g.Renderer.(*fakeRenderer).wantDirectories[""].CanonicalSubDir = "v1.0.0"
+		_, err := g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			"https://example.com/docs/",
+			"https://example.com/docs/v1.0.0/",
+		}, sitemapLocs(t, mem.Files()[SitemapFile]))
+	})
+}
+
+func TestInternalRoot(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		give     string
+		want     string
+		internal bool
+	}{
+		{give: ""},
+		{give: "foo"},
+		{give: "foo/internalx"},
+		{give: "internal", want: "internal", internal: true},
+		{give: "foo/internal", want: "foo/internal", internal: true},
+		{give: "v1/foo/internal/bar/internal", want: "v1/foo/internal", internal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := internalRoot(tt.give)
+			assert.Equal(t, tt.internal, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func writeMemoryFile(t *testing.T, mem *output.Memory, name, body string) {
+	t.Helper()
+
+	w, err := mem.Create(name)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, body)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func sitemapLocs(t *testing.T, bs []byte) []string {
+	t.Helper()
+
+	var urlset sitemapURLSet
+	require.NoError(t, xml.Unmarshal(bs, &urlset), "invalid sitemap:\n%s", bs)
+
+	locs := make([]string, len(urlset.URLs))
+	for i, u := range urlset.URLs {
+		locs[i] = u.Loc
+	}
+	return locs
+}
@@ -0,0 +1,240 @@
+package sitegen
+
+import (
+	"encoding/json"
+	"errors"
+	"go/doc"
+	"go/doc/comment"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/relative"
+)
+
+// SymbolIndex specifies how to generate the index of packages and symbols
+// used by the search box of [html.Renderer.SymbolSearch].
+type SymbolIndex struct {
+	// NormalizeRelativePath is an optional function that
+	// normalizes paths to pages, e.g. to add a trailing '/'.
+	//
+	// If unset, paths to pages get a trailing '/'.
+	NormalizeRelativePath func(string) string
+}
+
+// symbolIndex is the format of [html.SymbolIndex].
+//
+// It's kept compact: pages are listed once per package,
+// and symbols only record the anchor on that page.
+type symbolIndex struct {
+	Packages []*symbolPackage `json:"packages"`
+}
+
+type symbolPackage struct {
+	// Path is the import path of the package.
+	Path string `json:"path"`
+
+	// Name is the name of the package,
+	// or of the binary for commands.
+	Name string `json:"name"`
+
+	// Kind is "package" or "command".
+	Kind string `json:"kind"`
+
+	// URL is the path to the page for the package
+	// relative to the root of the site.
+	URL string `json:"url"`
+
+	Synopsis string          `json:"synopsis,omitempty"`
+	Symbols  []*symbolSymbol `json:"symbols,omitempty"`
+}
+
+type symbolSymbol struct {
+	// Name of the symbol.
+	// Methods are qualified with their receiver type: "Type.Method".
+	Name string `json:"name"`
+
+	// Kind is one of "const", "var", "type", "func", and "method".
+	Kind string `json:"kind"`
+
+	// ID is the anchor for the symbol on the package page.
+	ID string `json:"id"`
+
+	Synopsis string `json:"synopsis,omitempty"`
+}
+
+// recordSymbols adds the symbols of a package to the symbol index.
+func (r *Generator) recordSymbols(pkg *godoc.Package) {
+	if r.SymbolIndex == nil {
+		return
+	}
+
+	spkg := &symbolPackage{
+		Path:     pkg.ImportPath,
+		Name:     pkg.Name,
+		Kind:     "package",
+		URL:      r.symbolPageURL(pkg.ImportPath),
+		Synopsis: pkg.Synopsis,
+	}
+	if pkg.BinName != "" {
+		spkg.Name = pkg.BinName
+		spkg.Kind = "command"
+	}
+
+	// Constants and variables don't have anchors of their own,
+	// so they link to the section or type they're listed under.
+	addValues := func(kind, id string, vals []*godoc.Value) {
+		for _, v := range vals {
+			synopsis := docSynopsis(v.Doc)
+			for _, name := range v.Names {
+				spkg.Symbols = append(spkg.Symbols, &symbolSymbol{
+					Name:     name,
+					Kind:     kind,
+					ID:       id,
+					Synopsis: synopsis,
+				})
+			}
+		}
+	}
+	addFuncs := func(fns []*godoc.Function) {
+		for _, fn := range fns {
+			sym := &symbolSymbol{
+				Name:     fn.Name,
+				Kind:     "func",
+				ID:       fn.Name,
+				Synopsis: docSynopsis(fn.Doc),
+			}
+			if fn.RecvType != "" {
+				sym.Name = fn.RecvType + "." + fn.Name
+				sym.Kind = "method"
+				sym.ID = sym.Name
+			}
+			spkg.Symbols = append(spkg.Symbols, sym)
+		}
+	}
+
+	addValues("const", "pkg-constants", pkg.Constants)
+	addValues("var", "pkg-variables", pkg.Variables)
+	addFuncs(pkg.Functions)
+	for _, t := range pkg.Types {
+		spkg.Symbols = append(spkg.Symbols, &symbolSymbol{
+			Name:     t.Name,
+			Kind:     "type",
+			ID:       t.Name,
+			Synopsis: docSynopsis(t.Doc),
+		})
+		addValues("const", t.Name, t.Constants)
+		addValues("var", t.Name, t.Variables)
+		addFuncs(t.Functions)
+		addFuncs(t.Methods)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.symbols[pkg.ImportPath] = spkg
+}
+
+// reuseSymbols copies the symbols of a package
+// from the symbol index written by a previous run,
+// and reports whether it had them.
+func (r *Generator) reuseSymbols(importPath string) bool {
+	if r.SymbolIndex == nil {
+		return true
+	}
+
+	spkg, ok := r.prevSymbols[importPath]
+	if !ok {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.symbols[importPath] = spkg
+	return true
+}
+
+// loadPrevSymbols reads the symbol index written by a previous run, if any,
+// so that packages skipped by the Cache keep their symbols.
+func (r *Generator) loadPrevSymbols() error {
+	if r.SymbolIndex == nil || r.Cache == nil {
+		return nil
+	}
+
+	name := filepath.Join(r.siteDir, html.StaticDir, html.SymbolIndex)
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return errtrace.Wrap(err)
+	}
+
+	var idx symbolIndex
+	if err := json.Unmarshal(bs, &idx); err != nil {
+		// Regenerate the packages instead of failing.
+		r.DebugLog.Printf("Ignoring bad symbol index %v: %v", name, err)
+		return nil
+	}
+
+	r.prevSymbols = make(map[string]*symbolPackage, len(idx.Packages))
+	for _, spkg := range idx.Packages {
+		r.prevSymbols[spkg.Path] = spkg
+	}
+	return nil
+}
+
+// writeSymbolIndex writes the symbol index for all packages recorded so far
+// into the StaticDir of the site.
+func (r *Generator) writeSymbolIndex() error {
+	r.mu.Lock()
+	idx := symbolIndex{
+		Packages: make([]*symbolPackage, 0, len(r.symbols)),
+	}
+	for _, spkg := range r.symbols {
+		idx.Packages = append(idx.Packages, spkg)
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(idx.Packages, func(a, b *symbolPackage) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	bs, err := json.Marshal(idx)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	r.DebugLog.Printf("Indexed symbols of %d packages", len(idx.Packages))
+	return errtrace.Wrap(r.writeFile(filepath.Join(r.siteDir, html.StaticDir, html.SymbolIndex), bs))
+}
+
+// symbolPageURL returns the path to the page for the given import path
+// relative to the root of the site.
+func (r *Generator) symbolPageURL(importPath string) string {
+	page := relative.Path(r.Home, importPath)
+	if page == "" {
+		page = "."
+	}
+	if f := r.SymbolIndex.NormalizeRelativePath; f != nil {
+		return f(page)
+	}
+	if !strings.HasSuffix(page, "/") {
+		page += "/"
+	}
+	return page
+}
+
+// docSynopsis returns the first sentence of a doc comment,
+// or an empty string if there isn't one.
+func docSynopsis(d *comment.Doc) string {
+	if d == nil {
+		return ""
+	}
+	text := string(new(comment.Printer).Text(d))
+	return new(doc.Package).Synopsis(text)
+}
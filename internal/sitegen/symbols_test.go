@@ -0,0 +1,262 @@
+package sitegen
+
+import (
+	"context"
+	"go/doc/comment"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/buildcache"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/iotest"
+	"go.abhg.dev/doc2go/internal/output"
+)
+
+func TestGenerator_symbolIndex(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{
+		"example.com/foo": {
+			ImportPath: "example.com/foo",
+			Doc: &godoc.Package{
+				Name:       "foo",
+				ImportPath: "example.com/foo",
+				Synopsis:   "Package foo does things.",
+				Constants: []*godoc.Value{
+					{Names: []string{"A", "B"}, Doc: parseDoc("Letters. More letters.")},
+				},
+				Variables: []*godoc.Value{
+					{Names: []string{"ErrFoo"}},
+				},
+				Functions: []*godoc.Function{
+					{Name: "Run", Doc: parseDoc("Run runs things.")},
+				},
+				Types: []*godoc.Type{
+					{
+						Name: "Client",
+						Doc:  parseDoc("Client talks to the server."),
+						Constants: []*godoc.Value{
+							{Names: []string{"DefaultClient"}},
+						},
+						Functions: []*godoc.Function{
+							{Name: "NewClient"},
+						},
+						Methods: []*godoc.Function{
+							{
+								Name:     "Close",
+								Recv:     "*Client",
+								RecvType: "Client",
+								Doc:      parseDoc("Close stops the client."),
+							},
+						},
+					},
+				},
+			},
+		},
+		"example.com/bar": {
+			ImportPath: "example.com/bar",
+			Doc: &godoc.Package{
+				Name:       "main",
+				BinName:    "bar",
+				ImportPath: "example.com/bar",
+				Synopsis:   "bar runs things.",
+			},
+		},
+	}
+
+	var mem output.Memory
+	_, err := (&Generator{
+		DebugLog:  log.New(iotest.Writer(t), "", 0),
+		Parser:    &fakeParser{t: t, packages: pkgs},
+		Assembler: &fakeAssembler{t: t, packages: pkgs},
+		Renderer: &fakeRenderer{
+			t: t,
+			wantPackages: map[string]*renderInfo{
+				"example.com/foo": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "example.com", Path: "example.com"},
+						{Text: "foo", Path: "example.com/foo"},
+					},
+				},
+				"example.com/bar": {
+					Breadcrumbs: []html.Breadcrumb{
+						{Text: "example.com", Path: "example.com"},
+						{Text: "bar", Path: "example.com/bar"},
+					},
+				},
+			},
+			wantDirectories: map[string]*renderInfo{
+				"example.com": {
+					Breadcrumbs: []html.Breadcrumb{{Text: "example.com", Path: "example.com"}},
+					Subpackages: []html.Subpackage{
+						{RelativePath: "bar", Synopsis: "bar runs things."},
+						{RelativePath: "foo", Synopsis: "Package foo does things."},
+					},
+				},
+			},
+		},
+		Output:    &mem,
+		Home:      "example.com",
+		DocLinker: new(nopDocLinker),
+		SymbolIndex: &SymbolIndex{
+			NormalizeRelativePath: func(s string) string {
+				return strings.TrimSuffix(s, "/") + "/index.html"
+			},
+		},
+	}).Generate(context.Background(), []*gosrc.PackageRef{
+		{Name: "foo", ImportPath: "example.com/foo"},
+		{Name: "main", ImportPath: "example.com/bar"},
+	})
+	require.NoError(t, err)
+
+	got, ok := mem.Files()["_/"+html.SymbolIndex]
+	require.True(t, ok, "symbol index must be written")
+	assert.NotContains(t, string(got), "\n", "symbol index must be compact")
+	assert.JSONEq(t, `{"packages": [
+		{
+			"path": "example.com/bar",
+			"name": "bar",
+			"kind": "command",
+			"url": "bar/index.html",
+			"synopsis": "bar runs things."
+		},
+		{
+			"path": "example.com/foo",
+			"name": "foo",
+			"kind": "package",
+			"url": "foo/index.html",
+			"synopsis": "Package foo does things.",
+			"symbols": [
+				{"name": "A", "kind": "const", "id": "pkg-constants", "synopsis": "Letters."},
+				{"name": "B", "kind": "const", "id": "pkg-constants", "synopsis": "Letters."},
+				{"name": "ErrFoo", "kind": "var", "id": "pkg-variables"},
+				{"name": "Run", "kind": "func", "id": "Run", "synopsis": "Run runs things."},
+				{"name": "Client", "kind": "type", "id": "Client", "synopsis": "Client talks to the server."},
+				{"name": "DefaultClient", "kind": "const", "id": "Client"},
+				{"name": "NewClient", "kind": "func", "id": "NewClient"},
+				{"name": "Client.Close", "kind": "method", "id": "Client.Close", "synopsis": "Close stops the client."}
+			]
+		}
+	]}`, string(got))
+}
+
+func TestGenerator_symbolIndexCache(t *testing.T) {
+	t.Parallel()
+
+	pkgs := map[string]*fakePackage{
+		"foo": {
+			ImportPath: "foo",
+			Doc: &godoc.Package{
+				Name:       "foo",
+				ImportPath: "foo",
+				Functions:  []*godoc.Function{{Name: "Foo"}},
+			},
+		},
+	}
+	refs := []*gosrc.PackageRef{{Name: "foo", ImportPath: "foo"}}
+
+	outDir := t.TempDir()
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	indexPath := filepath.Join(outDir, html.StaticDir, html.SymbolIndex)
+
+	// generate runs the generator
+	// and returns the list of packages that were parsed.
+	generate := func() []string {
+		cache, err := buildcache.Load(cachePath, "salt")
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, cache.Save(cachePath))
+		}()
+
+		parser := fakeParser{t: t, packages: pkgs}
+		g := Generator{
+			DebugLog:  log.New(iotest.Writer(t), "", 0),
+			Parser:    &parser,
+			Assembler: &fakeAssembler{t: t, packages: pkgs},
+			Renderer: &fakeRenderer{
+				t: t,
+				wantPackages: map[string]*renderInfo{
+					"foo": {Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}}},
+				},
+				wantDirectories: map[string]*renderInfo{
+					"": {Subpackages: []html.Subpackage{{RelativePath: "foo"}}},
+				},
+			},
+			OutDir:      outDir,
+			DocLinker:   new(nopDocLinker),
+			Cache:       cache,
+			SymbolIndex: new(SymbolIndex),
+		}
+		_, err = g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+		return parser.sawImports
+	}
+
+	readIndex := func() string {
+		bs, err := os.ReadFile(indexPath)
+		require.NoError(t, err)
+		return string(bs)
+	}
+
+	want := `{"packages": [{
+		"path": "foo",
+		"name": "foo",
+		"kind": "package",
+		"url": "foo/",
+		"symbols": [{"name": "Foo", "kind": "func", "id": "Foo"}]
+	}]}`
+
+	assert.Equal(t, []string{"foo"}, generate(), "first run must parse everything")
+	assert.JSONEq(t, want, readIndex())
+
+	assert.Empty(t, generate(), "second run must not parse anything")
+	assert.JSONEq(t, want, readIndex(), "skipped package must keep its symbols")
+
+	require.NoError(t, os.Remove(indexPath))
+	assert.Equal(t, []string{"foo"}, generate(),
+		"packages missing from the index must be re-rendered")
+	assert.JSONEq(t, want, readIndex())
+}
+
+func TestDocSynopsis(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc string
+		give *comment.Doc
+		want string
+	}{
+		{desc: "nil"},
+		{
+			desc: "first sentence",
+			give: parseDoc("Foo does things. It does them well."),
+			want: "Foo does things.",
+		},
+		{
+			desc: "doc links",
+			give: (&comment.Parser{
+				LookupSym: func(recv, name string) bool { return true },
+			}).Parse("Foo wraps [Bar]\nin a [Baz]."),
+			want: "Foo wraps Bar in a Baz.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, docSynopsis(tt.give))
+		})
+	}
+}
+
+func parseDoc(s string) *comment.Doc {
+	return new(comment.Parser).Parse(s)
+}
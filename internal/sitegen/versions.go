@@ -0,0 +1,262 @@
+package sitegen
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/errdefer"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/output"
+	"golang.org/x/mod/semver"
+)
+
+// _latestAlias is the name of the directory next to versioned sites
+// that redirects to the newest stable release.
+const _latestAlias = "latest"
+
+// _aliasManifest is the name of the file inside the StaticDir
+// of the latest alias that marks it as generated by doc2go.
+const _aliasManifest = "alias.json"
+
+// siteVersions is a list of sibling sites
+// grouped and sorted by their semantic versions.
+type siteVersions struct {
+	Releases    []string // stable versions, newest first
+	PreReleases []string // pre-release versions, newest first
+	Others      []string // not semantic versions, sorted by name
+
+	// Latest is the newest stable release, if any.
+	Latest string
+}
+
+// sortSites groups the names of sibling sites
+// by whether they're stable semantic versions, pre-releases, or neither,
+// and sorts them newest first.
+//
+// The leading "v" of a semantic version is optional.
+func sortSites(sites []string) *siteVersions {
+	var versions siteVersions
+	for _, site := range sites {
+		v := siteSemver(site)
+		switch {
+		case v == "":
+			versions.Others = append(versions.Others, site)
+		case semver.Prerelease(v) != "":
+			versions.PreReleases = append(versions.PreReleases, site)
+		default:
+			versions.Releases = append(versions.Releases, site)
+		}
+	}
+
+	newestFirst := func(a, b string) int {
+		if c := semver.Compare(siteSemver(b), siteSemver(a)); c != 0 {
+			return c
+		}
+		// Equivalent versions (e.g. v1.2 and v1.2.0) by name.
+		return cmp.Compare(a, b)
+	}
+	slices.SortFunc(versions.Releases, newestFirst)
+	slices.SortFunc(versions.PreReleases, newestFirst)
+	slices.Sort(versions.Others)
+
+	if len(versions.Releases) > 0 {
+		versions.Latest = versions.Releases[0]
+	}
+	return &versions
+}
+
+// LatestRelease returns the name of the site
+// named after the newest stable release,
+// or an empty string if none of them are.
+//
+// This is the site that the latest alias redirects to,
+// and that other versions name as canonical.
+func LatestRelease(sites []string) string {
+	return sortSites(sites).Latest
+}
+
+// All returns all sites in the order they're listed in the site index.
+func (v *siteVersions) All() []string {
+	return slices.Concat(v.Releases, v.PreReleases, v.Others)
+}
+
+// siteSemver returns the semantic version that a site is named after,
+// or an empty string if the name isn't a semantic version.
+func siteSemver(name string) string {
+	v := name
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	if !semver.IsValid(v) {
+		return ""
+	}
+	return v
+}
+
+// aliasManifest is the contents of _aliasManifest.
+type aliasManifest struct {
+	// Target is the site that the alias redirects to.
+	Target string `json:"target"`
+}
+
+// isLatestAlias reports whether dir is a latest alias
+// generated by a previous run.
+func isLatestAlias(dir string) bool {
+	if filepath.Base(dir) != _latestAlias {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, html.StaticDir, _aliasManifest))
+	return err == nil
+}
+
+// generateLatestAlias generates a redirect page in the latest alias
+// for every page in the given site.
+func (r *Generator) generateLatestAlias(latest string) error {
+	pages, err := r.sitePages(latest)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	aliasDir := r.latestAliasDir()
+	switch {
+	case r.Output != nil:
+		// Nothing to drop.
+
+	case r.stageDir != "":
+		// Replaced wholesale by commit to drop redirects
+		// for pages that are gone from the latest version.
+		r.aliasStageDir, err = r.newStageDir(aliasDir)
+		if err != nil {
+			return errtrace.Wrap(err)
+		}
+
+	default:
+		// Writing in place.
+		if err := os.RemoveAll(aliasDir); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+
+	for _, page := range pages {
+		redirect := html.Redirect{
+			From: path.Join(_latestAlias, page),
+			To:   path.Join(latest, page),
+		}
+		file := filepath.Join(aliasDir, filepath.FromSlash(page), r.Basename)
+		if err := r.writeRedirect(file, &redirect); err != nil {
+			return errtrace.Wrap(err)
+		}
+	}
+
+	manifestPath := filepath.Join(aliasDir, html.StaticDir, _aliasManifest)
+	return errtrace.Wrap(r.writeJSON(manifestPath, aliasManifest{Target: latest}))
+}
+
+func (r *Generator) writeRedirect(file string, redirect *html.Redirect) (err error) {
+	f, err := r.createFile(file)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer errdefer.Close(&err, f)
+
+	return errtrace.Wrap(r.Renderer.RenderRedirect(f, redirect))
+}
+
+// sitePages returns the pages in the given sibling site.
+//
+// If the site doesn't have a pages manifest,
+// only its root page is returned.
+func (r *Generator) sitePages(site string) ([]string, error) {
+	if site == r.SubDir {
+		return r.pagePaths(), nil
+	}
+
+	name := filepath.Join(r.rootDir, site, html.StaticDir, html.PagesManifest)
+	var (
+		bs  []byte
+		err error
+	)
+	if r.Output != nil {
+		if rfs, ok := r.Output.(output.ReadFS); ok {
+			bs, err = rfs.ReadFile(filepath.ToSlash(name))
+		} else {
+			err = fs.ErrNotExist
+		}
+	} else {
+		bs, err = os.ReadFile(name)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			r.DebugLog.Printf("No list of pages for %v. Redirecting only its root.", site)
+			return []string{""}, nil
+		}
+		return nil, errtrace.Wrap(err)
+	}
+
+	var manifest pagesManifest
+	if err := json.Unmarshal(bs, &manifest); err != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("%v: %w", name, err))
+	}
+	return manifest.Pages, nil
+}
+
+// findCanonicalSite looks for the newest stable release
+// among the siblings of SubDir.
+// Pages that also exist in it will name it as their preferred version.
+//
+// Pages name the release itself rather than the latest alias:
+// the alias only holds redirects back to the release,
+// and crawlers ignore canonical URLs that redirect.
+// Sites generated before a newer release was published
+// keep naming themselves until they're regenerated.
+func (r *Generator) findCanonicalSite() error {
+	if r.SubDir == "" {
+		return nil
+	}
+
+	sites, err := r.siblingSites()
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	latest := sortSites(sites).Latest
+	if latest == "" || latest == r.SubDir {
+		return nil
+	}
+	r.canonicalSite = latest
+
+	pages, err := r.sitePages(latest)
+	if err != nil {
+		return errtrace.Wrap(fmt.Errorf("%v: %w", latest, err))
+	}
+
+	r.canonicalPages = make(map[string]struct{}, len(pages))
+	for _, page := range pages {
+		r.canonicalPages[page] = struct{}{}
+	}
+	return nil
+}
+
+// canonicalSubDir returns the sibling site
+// holding the preferred version of the given page,
+// or an empty string if that's this site.
+//
+// page is the path to the page relative to siteDir.
+func (r *Generator) canonicalSubDir(page string) string {
+	if r.canonicalPages == nil {
+		return r.canonicalSite
+	}
+	if _, ok := r.canonicalPages[page]; ok {
+		return r.canonicalSite
+	}
+	return ""
+}
@@ -0,0 +1,330 @@
+package sitegen
+
+import (
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"braces.dev/errtrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/iotest"
+	"go.abhg.dev/doc2go/internal/output"
+)
+
+func TestSortSites(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc  string
+		sites []string
+		want  *siteVersions
+	}{
+		{desc: "empty", want: &siteVersions{}},
+		{
+			desc:  "releases",
+			sites: []string{"v1.2.0", "v1.10.0", "v1.9.3", "v0.1.0"},
+			want: &siteVersions{
+				Releases: []string{"v1.10.0", "v1.9.3", "v1.2.0", "v0.1.0"},
+				Latest:   "v1.10.0",
+			},
+		},
+		{
+			desc:  "missing v prefix",
+			sites: []string{"1.2.0", "v1.3.0", "1.0"},
+			want: &siteVersions{
+				Releases: []string{"v1.3.0", "1.2.0", "1.0"},
+				Latest:   "v1.3.0",
+			},
+		},
+		{
+			desc:  "pre-releases",
+			sites: []string{"v2.0.0-rc.1", "v1.0.0", "v2.0.0-beta.2", "v2.0.0-rc.2"},
+			want: &siteVersions{
+				Releases:    []string{"v1.0.0"},
+				PreReleases: []string{"v2.0.0-rc.2", "v2.0.0-rc.1", "v2.0.0-beta.2"},
+				Latest:      "v1.0.0",
+			},
+		},
+		{
+			desc:  "only pre-releases",
+			sites: []string{"v1.0.0-alpha", "v1.0.0-beta"},
+			want: &siteVersions{
+				PreReleases: []string{"v1.0.0-beta", "v1.0.0-alpha"},
+			},
+		},
+		{
+			desc:  "others",
+			sites: []string{"main", "v1.0.0", "dev", "vNext"},
+			want: &siteVersions{
+				Releases: []string{"v1.0.0"},
+				Others:   []string{"dev", "main", "vNext"},
+				Latest:   "v1.0.0",
+			},
+		},
+		{
+			desc:  "equivalent versions",
+			sites: []string{"v1.2.0", "v1.2"},
+			want: &siteVersions{
+				Releases: []string{"v1.2", "v1.2.0"},
+				Latest:   "v1.2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, sortSites(tt.sites))
+		})
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "v1.10.0",
+		LatestRelease([]string{"v1.9.0", "main", "v1.10.0", "v2.0.0-rc.1"}))
+	assert.Equal(t, "1.2.0", LatestRelease([]string{"v1.1.0", "1.2.0"}),
+		"leading v must be optional")
+	assert.Empty(t, LatestRelease([]string{"main", "v2.0.0-rc.1"}))
+}
+
+func TestGenerator_latestAlias(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	// want maps the packages to generate to their canonical sites.
+	generate := func(t *testing.T, subDir string, want map[string]string) {
+		t.Helper()
+
+		packages := make(map[string]*fakePackage)
+		wantPkgs := make(map[string]*renderInfo)
+		var (
+			refs        []*gosrc.PackageRef
+			subpackages []html.Subpackage
+		)
+		for _, pkg := range slices.Sorted(maps.Keys(want)) {
+			if pkg == "" {
+				continue
+			}
+			packages[pkg] = &fakePackage{ImportPath: pkg}
+			wantPkgs[pkg] = &renderInfo{
+				Breadcrumbs:     []html.Breadcrumb{{Text: pkg, Path: pkg}},
+				CanonicalSubDir: want[pkg],
+			}
+			refs = append(refs, &gosrc.PackageRef{Name: pkg, ImportPath: pkg})
+			subpackages = append(subpackages, html.Subpackage{RelativePath: pkg})
+		}
+
+		g := Generator{
+			DebugLog:  log.New(iotest.Writer(t), "", 0),
+			Parser:    &fakeParser{t: t, packages: packages},
+			Assembler: &fakeAssembler{t: t, packages: packages},
+			Renderer: &fakeRenderer{
+				t:            t,
+				wantPackages: wantPkgs,
+				wantDirectories: map[string]*renderInfo{
+					"": {Subpackages: subpackages, CanonicalSubDir: want[""]},
+				},
+			},
+			OutDir:    outDir,
+			SubDir:    subDir,
+			DocLinker: new(nopDocLinker),
+		}
+		_, err := g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+	}
+
+	readFile := func(t *testing.T, path string) string {
+		t.Helper()
+
+		bs, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(path)))
+		require.NoError(t, err)
+		return string(bs)
+	}
+
+	generate(t, "v1.0.0", map[string]string{"": "", "bar": "", "foo": ""})
+	assert.Equal(t, "v1.0.0", readFile(t, "latest/index.html"))
+	assert.Equal(t, "v1.0.0/foo", readFile(t, "latest/foo/index.html"))
+	assert.Equal(t, "v1.0.0/bar", readFile(t, "latest/bar/index.html"))
+
+	// A newer release takes over the alias,
+	// and pages that no longer exist are dropped from it.
+	generate(t, "v2.0.0", map[string]string{"": "", "foo": ""})
+	assert.Equal(t, "v2.0.0", readFile(t, "latest/index.html"))
+	assert.Equal(t, "v2.0.0/foo", readFile(t, "latest/foo/index.html"))
+	assert.NoFileExists(t, filepath.Join(outDir, "latest", "bar", "index.html"))
+
+	// Pre-releases don't affect the alias,
+	// but its pages are still read from the pages manifest.
+	// Pages they share with the latest release prefer it.
+	generate(t, "v3.0.0-rc.1", map[string]string{"": "v2.0.0", "baz": ""})
+	assert.Equal(t, "v2.0.0", readFile(t, "latest/index.html"))
+	assert.Equal(t, "v2.0.0/foo", readFile(t, "latest/foo/index.html"))
+	assert.NoFileExists(t, filepath.Join(outDir, "latest", "baz", "index.html"))
+
+	assert.JSONEq(t, `{
+		"latest": "v2.0.0",
+		"versions": [
+			{"name": "v2.0.0"},
+			{"name": "v1.0.0"},
+			{"name": "v3.0.0-rc.1"}
+		]
+	}`, readFile(t, "versions.json"), "alias must not be listed as a version")
+}
+
+func TestGenerator_siblingIndexFailureKeepsSite(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	generate := func(t *testing.T, subDir string, siteIndexErr error) error {
+		t.Helper()
+
+		pkgs := map[string]*fakePackage{"foo": {ImportPath: "foo"}}
+		g := Generator{
+			DebugLog:  log.New(iotest.Writer(t), "", 0),
+			Parser:    &fakeParser{t: t, packages: pkgs},
+			Assembler: &fakeAssembler{t: t, packages: pkgs},
+			Renderer: &fakeRenderer{
+				t: t,
+				wantPackages: map[string]*renderInfo{
+					"foo": {
+						Breadcrumbs: []html.Breadcrumb{{Text: "foo", Path: "foo"}},
+					},
+				},
+				wantDirectories: map[string]*renderInfo{
+					"": {
+						Subpackages: []html.Subpackage{{RelativePath: "foo"}},
+					},
+				},
+				siteIndexErr: siteIndexErr,
+			},
+			OutDir:    outDir,
+			SubDir:    subDir,
+			DocLinker: new(nopDocLinker),
+		}
+		_, err := g.Generate(context.Background(), []*gosrc.PackageRef{
+			{Name: "foo", ImportPath: "foo"},
+		})
+		return errtrace.Wrap(err)
+	}
+
+	readFile := func(t *testing.T, path string) string {
+		t.Helper()
+
+		bs, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(path)))
+		require.NoError(t, err)
+		return string(bs)
+	}
+
+	require.NoError(t, generate(t, "v1.0.0", nil))
+	versions := readFile(t, "versions.json")
+
+	err := generate(t, "v2.0.0", errors.New("great sadness"))
+	require.ErrorContains(t, err, "great sadness")
+
+	assert.NoDirExists(t, filepath.Join(outDir, "v2.0.0"))
+	assert.Equal(t, "v1.0.0/foo", readFile(t, "latest/foo/index.html"))
+	assert.Equal(t, versions, readFile(t, "versions.json"))
+
+	ents, err := os.ReadDir(outDir)
+	require.NoError(t, err)
+	var names []string
+	for _, ent := range ents {
+		names = append(names, ent.Name())
+	}
+	assert.ElementsMatch(t,
+		[]string{"_", "index.html", "latest", "v1.0.0", "versions.json"}, names,
+		"staging directories must be removed")
+}
+
+func TestGenerator_canonicalSubDir(t *testing.T) {
+	t.Parallel()
+
+	var mem output.Memory
+	generate := func(t *testing.T, subDir string, siblings []string, want map[string]string) {
+		t.Helper()
+
+		packages := make(map[string]*fakePackage)
+		wantPkgs := make(map[string]*renderInfo)
+		var (
+			refs        []*gosrc.PackageRef
+			subpackages []html.Subpackage
+		)
+		for _, pkg := range slices.Sorted(maps.Keys(want)) {
+			if pkg == "" {
+				continue
+			}
+			packages[pkg] = &fakePackage{ImportPath: pkg}
+			wantPkgs[pkg] = &renderInfo{
+				Breadcrumbs:     []html.Breadcrumb{{Text: pkg, Path: pkg}},
+				CanonicalSubDir: want[pkg],
+			}
+			refs = append(refs, &gosrc.PackageRef{Name: pkg, ImportPath: pkg})
+			subpackages = append(subpackages, html.Subpackage{RelativePath: pkg})
+		}
+
+		g := Generator{
+			DebugLog:  log.New(iotest.Writer(t), "", 0),
+			Parser:    &fakeParser{t: t, packages: packages},
+			Assembler: &fakeAssembler{t: t, packages: packages},
+			Renderer: &fakeRenderer{
+				t:            t,
+				wantPackages: wantPkgs,
+				wantDirectories: map[string]*renderInfo{
+					"": {Subpackages: subpackages, CanonicalSubDir: want[""]},
+				},
+			},
+			Output:    &mem,
+			SubDir:    subDir,
+			Siblings:  siblings,
+			DocLinker: new(nopDocLinker),
+		}
+		_, err := g.Generate(context.Background(), refs)
+		require.NoError(t, err)
+	}
+
+	// Pages of the latest release are their own preferred versions.
+	generate(t, "v2.0.0", nil, map[string]string{
+		"":    "",
+		"foo": "",
+		"bar": "",
+	})
+
+	// Older versions point to it for pages the latest release has.
+	// They name the release itself, not the latest alias,
+	// because the alias only redirects back to the release.
+	generate(t, "v1.0.0", []string{"v2.0.0"}, map[string]string{
+		"":    "v2.0.0",
+		"foo": "v2.0.0",
+		"baz": "",
+	})
+
+	// So do sites that aren't releases.
+	generate(t, "main", []string{"v2.0.0", "v1.0.0"}, map[string]string{
+		"":    "v2.0.0",
+		"bar": "v2.0.0",
+		"qux": "",
+	})
+
+	// A site that's actually named "latest" doesn't change that.
+	generate(t, "v1.0.0", []string{"v2.0.0", "latest"}, map[string]string{
+		"":    "v2.0.0",
+		"foo": "v2.0.0",
+		"baz": "",
+	})
+	generate(t, "v2.0.0", []string{"latest"}, map[string]string{
+		"":    "",
+		"foo": "",
+		"bar": "",
+	})
+}
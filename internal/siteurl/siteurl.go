@@ -0,0 +1,48 @@
+// Package siteurl builds absolute URLs for pages of a website
+// published at a known base URL.
+package siteurl
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Page returns the absolute URL of a page
+// given the URL that the root of the output directory is served from,
+// and the /-separated path to the page from that root.
+// The root page itself is "".
+//
+// normalize is an optional function that normalizes the path to the page,
+// e.g. to add a trailing '/' or an index file.
+// If it's nil, the path gets a trailing '/'.
+func Page(base *url.URL, page string, normalize func(string) string) string {
+	root := *base
+	if !strings.HasSuffix(root.Path, "/") {
+		root.Path += "/"
+		root.RawPath = ""
+	}
+
+	if page == "" {
+		page = "."
+	}
+	if normalize != nil {
+		page = normalize(page)
+	} else if !strings.HasSuffix(page, "/") {
+		page += "/"
+	}
+	return root.ResolveReference(&url.URL{Path: page}).String()
+}
+
+// DirPath returns the escaped URL path of a directory
+// given the URL that the root of the output directory is served from,
+// and the /-separated path to the directory from that root.
+//
+// The returned path always ends with '/'.
+func DirPath(base *url.URL, dir string) string {
+	p := path.Join("/", base.Path, dir)
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return (&url.URL{Path: p}).EscapedPath()
+}
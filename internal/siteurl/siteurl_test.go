@@ -0,0 +1,104 @@
+package siteurl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPage(t *testing.T) {
+	t.Parallel()
+
+	index := func(s string) string {
+		return strings.TrimSuffix(s, "/") + "/index.html"
+	}
+
+	tests := []struct {
+		desc      string
+		base      string
+		page      string
+		normalize func(string) string
+		want      string
+	}{
+		{
+			desc: "root",
+			base: "https://example.com",
+			want: "https://example.com/",
+		},
+		{
+			desc: "root with path",
+			base: "https://example.com/docs",
+			want: "https://example.com/docs/",
+		},
+		{
+			desc: "page",
+			base: "https://example.com/docs/",
+			page: "foo/bar",
+			want: "https://example.com/docs/foo/bar/",
+		},
+		{
+			desc:      "plain",
+			base:      "https://example.com/docs",
+			page:      "foo/bar",
+			normalize: func(s string) string { return strings.TrimSuffix(s, "/") },
+			want:      "https://example.com/docs/foo/bar",
+		},
+		{
+			desc:      "index root",
+			base:      "https://example.com/docs",
+			normalize: index,
+			want:      "https://example.com/docs/index.html",
+		},
+		{
+			desc:      "index",
+			base:      "https://example.com/docs",
+			page:      "v1.0.0/foo",
+			normalize: index,
+			want:      "https://example.com/docs/v1.0.0/foo/index.html",
+		},
+		{
+			desc: "escaped",
+			base: "https://example.com/my%20docs",
+			page: "foo bar",
+			want: "https://example.com/my%20docs/foo%20bar/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			base, err := url.Parse(tt.base)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, Page(base, tt.page, tt.normalize))
+		})
+	}
+}
+
+func TestDirPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		base string
+		dir  string
+		want string
+	}{
+		{"https://example.com", "", "/"},
+		{"https://example.com", "foo/internal", "/foo/internal/"},
+		{"https://example.com/docs/", "foo/internal", "/docs/foo/internal/"},
+		{"https://example.com/my%20docs", "internal", "/my%20docs/internal/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.base+" "+tt.dir, func(t *testing.T) {
+			t.Parallel()
+
+			base, err := url.Parse(tt.base)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, DirPath(base, tt.dir))
+		})
+	}
+}
@@ -0,0 +1,223 @@
+// Package watch detects changes to files and directories.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"braces.dev/errtrace"
+)
+
+// _defaultInterval is the default polling interval for Poller.
+const _defaultInterval = 500 * time.Millisecond
+
+// Poller detects changes to files and directories
+// by periodically checking their modification times.
+//
+// For directories, Poller detects files being added to,
+// removed from, or modified inside the directory.
+// It does not descend into subdirectories.
+//
+// The zero value is ready to use.
+type Poller struct {
+	// Interval between checks.
+	//
+	// Defaults to 500 milliseconds.
+	Interval time.Duration
+
+	// Ignore lists files and directories whose changes aren't reported,
+	// e.g. because they're written by the program doing the watching.
+	// Changes to files inside ignored directories aren't reported either.
+	Ignore []string
+}
+
+// fileState is the state of a file at a point in time.
+type fileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Snapshot is the state of a set of paths at a point in time.
+//
+// A nil Snapshot is valid and empty.
+type Snapshot struct {
+	// States of each watched path and its immediate contents,
+	// keyed by the watched path.
+	roots map[string]map[string]fileState
+}
+
+// NewSnapshot records the current state of the given paths,
+// and the immediate contents of any directories among them.
+func NewSnapshot(paths []string) *Snapshot {
+	snap := Snapshot{
+		roots: make(map[string]map[string]fileState, len(paths)),
+	}
+	for _, path := range paths {
+		snap.roots[path] = snapshotPath(path)
+	}
+	return &snap
+}
+
+// Add records the current state of the given paths
+// if they aren't already in the snapshot.
+func (s *Snapshot) Add(paths []string) {
+	for _, path := range paths {
+		if _, ok := s.roots[path]; !ok {
+			s.roots[path] = snapshotPath(path)
+		}
+	}
+}
+
+// Wait blocks until one of the given paths changes
+// from its state in prev, and returns the paths that changed.
+//
+// Take prev before doing work that depends on the paths
+// to see changes made while the work was in progress.
+// Paths missing from prev are compared against
+// their state when Wait is called.
+//
+// Paths that don't exist yet are watched for their creation.
+// Wait returns an error if the context is cancelled.
+func (p *Poller) Wait(ctx context.Context, paths []string, prev *Snapshot) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = _defaultInterval
+	}
+
+	ignore := make([]string, 0, len(p.Ignore))
+	for _, path := range p.Ignore {
+		if abs, err := filepath.Abs(path); err == nil {
+			ignore = append(ignore, abs)
+		}
+	}
+
+	base := make([]map[string]fileState, len(paths))
+	for i, path := range paths {
+		states, ok := prev.root(path)
+		if !ok {
+			states = snapshotPath(path)
+		}
+		base[i] = states
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var changed []string
+		for i, path := range paths {
+			for _, c := range diff(base[i], snapshotPath(path)) {
+				if !isIgnored(ignore, c) {
+					changed = append(changed, c)
+				}
+			}
+		}
+		if len(changed) > 0 {
+			slices.Sort(changed)
+			return slices.Compact(changed), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errtrace.Wrap(ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// isIgnored reports whether path is one of the given absolute paths,
+// or is inside one of them.
+func isIgnored(ignore []string, path string) bool {
+	if len(ignore) == 0 {
+		return false
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, ig := range ignore {
+		if path == ig || strings.HasPrefix(path, ig+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Snapshot) root(path string) (map[string]fileState, bool) {
+	if s == nil {
+		return nil, false
+	}
+	states, ok := s.roots[path]
+	return states, ok
+}
+
+// snapshotPath records the state of path,
+// and the immediate contents of it if it's a directory.
+//
+// The result is empty if path cannot be accessed.
+func snapshotPath(path string) map[string]fileState {
+	states := make(map[string]fileState)
+	info, err := os.Stat(path)
+	if err != nil {
+		return states
+	}
+
+	if !info.IsDir() {
+		states[path] = stateOf(info)
+		return states
+	}
+
+	ents, err := os.ReadDir(path)
+	if err != nil {
+		return states
+	}
+	for _, ent := range ents {
+		if ent.IsDir() {
+			// Report new or deleted subdirectories,
+			// but not changes inside them.
+			states[filepath.Join(path, ent.Name())] = fileState{}
+			continue
+		}
+
+		info, err := ent.Info()
+		if err != nil {
+			continue
+		}
+		states[filepath.Join(path, ent.Name())] = stateOf(info)
+	}
+	return states
+}
+
+func stateOf(info fs.FileInfo) fileState {
+	return fileState{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+}
+
+// diff returns a sorted list of paths that were added, removed,
+// or modified between the two snapshots.
+func diff(prev, next map[string]fileState) []string {
+	var changed []string
+	for path, n := range next {
+		if p, ok := prev[path]; !ok || p.Size != n.Size || !p.ModTime.Equal(n.ModTime) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range prev {
+		if _, ok := next[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
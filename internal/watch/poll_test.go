@@ -0,0 +1,180 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoller_Wait(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		desc   string
+		change func(t *testing.T, dir string)
+		want   []string // relative to dir
+	}{
+		{
+			desc: "modify file",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "a.go"), "package a // changed")
+			},
+			want: []string{"a.go"},
+		},
+		{
+			desc: "add file",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "b.go"), "package a")
+			},
+			want: []string{"b.go"},
+		},
+		{
+			desc: "remove file",
+			change: func(t *testing.T, dir string) {
+				require.NoError(t, os.Remove(filepath.Join(dir, "a.go")))
+			},
+			want: []string{"a.go"},
+		},
+		{
+			desc: "create watched file",
+			change: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "missing.txt"), "hello")
+			},
+			want: []string{"missing.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeFile(t, filepath.Join(dir, "a.go"), "package a")
+
+			paths := []string{dir, filepath.Join(dir, "missing.txt")}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			type result struct {
+				changed []string
+				err     error
+			}
+			done := make(chan result, 1)
+			ready := make(chan struct{})
+			go func() {
+				close(ready)
+				changed, err := (&Poller{Interval: 10 * time.Millisecond}).
+					Wait(ctx, paths, nil)
+				done <- result{changed, err}
+			}()
+			<-ready
+
+			// Give the poller time to take its first snapshot.
+			time.Sleep(50 * time.Millisecond)
+			tt.change(t, dir)
+
+			res := <-done
+			require.NoError(t, res.err)
+
+			want := make([]string, len(tt.want))
+			for i, p := range tt.want {
+				want[i] = filepath.Join(dir, p)
+			}
+			assert.Equal(t, want, res.changed)
+		})
+	}
+}
+
+func TestPoller_Wait_snapshot(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.go")
+	writeFile(t, file, "package a")
+
+	snap := NewSnapshot(nil)
+	snap.Add([]string{file})
+	writeFile(t, file, "package a // changed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	changed, err := (&Poller{Interval: 10 * time.Millisecond}).
+		Wait(ctx, []string{file, dir}, snap)
+	require.NoError(t, err)
+	assert.Equal(t, []string{file}, changed,
+		"change made after the snapshot must be reported")
+}
+
+func TestPoller_Wait_futureModTime(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "a.go")
+	writeFile(t, file, "package a")
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(file, future, future))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := (&Poller{Interval: time.Millisecond}).
+		Wait(ctx, []string{file}, NewSnapshot([]string{file}))
+	assert.ErrorIs(t, err, context.DeadlineExceeded,
+		"unchanged file must not be reported")
+}
+
+func TestPoller_Wait_ignore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.go"), "package a")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "out"), 0o755))
+	snap := NewSnapshot([]string{dir})
+
+	writeFile(t, filepath.Join(dir, "report.json"), "{}")
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "out")))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "out2"), 0o755))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	poller := Poller{
+		Interval: 10 * time.Millisecond,
+		Ignore: []string{
+			filepath.Join(dir, "report.json"),
+			filepath.Join(dir, "out"),
+		},
+	}
+	changed, err := poller.Wait(ctx, []string{dir}, snap)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "out2")}, changed,
+		"changes to ignored paths must not be reported")
+}
+
+func TestPoller_Wait_cancel(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	snap := NewSnapshot([]string{dir})
+	writeFile(t, filepath.Join(dir, "a.go"), "package a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := (&Poller{Interval: time.Millisecond}).
+		Wait(ctx, []string{dir}, snap)
+	assert.ErrorIs(t, err, context.Canceled,
+		"cancellation must take precedence over changes")
+}
+
+func writeFile(t *testing.T, path, body string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+}
@@ -5,24 +5,40 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"text/template"
+	"time"
 
 	"braces.dev/errtrace"
 	"github.com/alecthomas/chroma/v2/styles"
+	"go.abhg.dev/doc2go/internal/buildcache"
+	"go.abhg.dev/doc2go/internal/errdefer"
 	"go.abhg.dev/doc2go/internal/godoc"
 	"go.abhg.dev/doc2go/internal/gomod"
 	"go.abhg.dev/doc2go/internal/gosrc"
 	"go.abhg.dev/doc2go/internal/highlight"
 	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/jsondoc"
+	"go.abhg.dev/doc2go/internal/markdown"
+	"go.abhg.dev/doc2go/internal/output"
 	"go.abhg.dev/doc2go/internal/pagefind"
 	"go.abhg.dev/doc2go/internal/pathx"
+	"go.abhg.dev/doc2go/internal/sitegen"
+	"go.abhg.dev/doc2go/internal/watch"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -52,11 +68,27 @@ type mainCmd struct {
 	debug    bool
 
 	packagesConfig *packages.Config
+
+	// Paths to watch for changes with -watch.
+	// Populated by run.
+	watchPaths []string
+
+	// State of the watched paths before generation started.
+	// run adds watchPaths to it if set.
+	watchSnapshot *watch.Snapshot
 }
 
 func (cmd *mainCmd) Run(args []string) (exitCode int) {
 	cmd.log = log.New(cmd.Stderr, "", 0)
 
+	// Working directory before -C is applied.
+	// -watch uses this to reload configuration.
+	wd, err := os.Getwd()
+	if err != nil {
+		cmd.log.Printf("doc2go: %+v", err)
+		return 1
+	}
+
 	opts, err := (&cliParser{
 		Stdout: cmd.Stdout,
 		Stderr: cmd.Stderr,
@@ -92,17 +124,68 @@ func (cmd *mainCmd) Run(args []string) (exitCode int) {
 		}
 	}
 
-	// TODO:
-	// In the future, tie this context to a signal handler.
-	ctx := context.Background()
-	if err := cmd.run(ctx, opts); err != nil {
-		cmd.log.Printf("doc2go: %+v", err)
+	// Stop on Ctrl-C or when asked to terminate.
+	// Output is staged until generation succeeds,
+	// so an interrupted run leaves the previous output intact.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch {
+	case opts.Watch:
+		err = cmd.watch(ctx, wd, args, opts)
+	case opts.Refs != "":
+		err = cmd.runRefs(ctx, opts)
+	default:
+		err = cmd.run(ctx, opts)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			cmd.log.Printf("doc2go: interrupted")
+			cmd.debugLog.Printf("%+v", err)
+		} else {
+			cmd.logError(err)
+		}
 		return 1
 	}
 	return 0
 }
 
+// logError reports an error that stopped doc2go.
+//
+// Package failures collected by -keep-going are reported as a summary,
+// with their stack traces relegated to the debug log.
+func (cmd *mainCmd) logError(err error) {
+	var pkgsErr *sitegen.PackagesError
+	if errors.As(err, &pkgsErr) {
+		cmd.log.Printf("doc2go: %v", pkgsErr)
+		cmd.debugLog.Printf("%+v", err)
+		return
+	}
+	cmd.log.Printf("doc2go: %+v", err)
+}
+
 func (cmd *mainCmd) run(ctx context.Context, opts *params) error {
+	return errtrace.Wrap(cmd.generate(ctx, opts, siteTarget{}))
+}
+
+// siteTarget overrides where a single run of the generator
+// loads packages from and writes pages to.
+// -refs uses this to generate each ref into its own subdirectory.
+type siteTarget struct {
+	// Dir is the directory to load packages from.
+	// Defaults to the working directory.
+	Dir string
+
+	// Output, if set, receives generated files
+	// instead of the -out directory.
+	Output output.FS
+
+	// Passed to sitegen.Generator as-is.
+	SkipSiblingIndex bool
+	Siblings         []string
+}
+
+func (cmd *mainCmd) generate(ctx context.Context, opts *params, target siteTarget) error {
 	if opts.HighlightListThemes {
 		for _, name := range styles.Names() {
 			fmt.Fprintln(cmd.Stdout, name)
@@ -130,6 +213,19 @@ func (cmd *mainCmd) run(ctx context.Context, opts *params) error {
 		}
 		highlighter.Style = style
 	}
+	if theme := opts.Highlight.DarkTheme; len(theme) > 0 {
+		if style := styles.Get(theme); style != nil {
+			highlighter.DarkStyle = style
+
+			// Switching themes needs a stylesheet,
+			// so a dark theme implies classes even in embedded mode.
+			highlighter.UseClasses = true
+		} else if opts.HighlightPrintCSS {
+			return errtrace.Wrap(fmt.Errorf("unknown theme %q", theme))
+		} else {
+			cmd.log.Printf("Unknown theme %q. Not using a dark theme.", theme)
+		}
+	}
 	if mode := opts.Highlight.Mode; mode != highlightModeAuto {
 		highlighter.UseClasses = mode == highlightModeClasses
 	}
@@ -138,25 +234,41 @@ func (cmd *mainCmd) run(ctx context.Context, opts *params) error {
 		return errtrace.Wrap(highlighter.WriteCSS(cmd.Stdout))
 	}
 
+	// Stop 'go list' if we're interrupted.
+	var packagesConfig packages.Config
+	if cmd.packagesConfig != nil {
+		packagesConfig = *cmd.packagesConfig
+	}
+	packagesConfig.Context = ctx
+	if target.Dir != "" {
+		packagesConfig.Dir = target.Dir
+	}
+
+	report := newBuildReport()
 	finder := gosrc.Finder{
 		Tags:           strings.Split(opts.Tags, ","),
 		Log:            cmd.log,
-		PackagesConfig: cmd.packagesConfig,
+		PackagesConfig: &packagesConfig,
+		OnSkip:         report.skip,
+		OnWarning:      report.warn,
 	}
 	if cmd.debug {
 		finder.DebugLog = cmd.debugLog
 	}
 
+	findStart := time.Now()
 	pkgRefs, err := finder.FindPackages(opts.Patterns...)
 	if err != nil {
 		return errtrace.Wrap(fmt.Errorf("find packages: %w", err))
 	}
+	report.setFindTime(time.Since(findStart))
 
 	if home := opts.Home; home != "" {
 		refs := pkgRefs[:0]
 		for _, r := range pkgRefs {
 			if !pathx.Descends(home, r.ImportPath) {
 				cmd.log.Printf("[%s] Not rooted under %v. Skipping.", r.ImportPath, home)
+				report.skip(gosrc.SkippedPackage{ImportPath: r.ImportPath, Reason: _skipOutsideHome})
 				continue
 			}
 			refs = append(refs, r)
@@ -164,10 +276,35 @@ func (cmd *mainCmd) run(ctx context.Context, opts *params) error {
 		pkgRefs = refs
 	}
 
+	if opts.Watch {
+		cmd.watchPaths = watchPaths(opts, pkgRefs)
+		if cmd.watchSnapshot != nil {
+			cmd.watchSnapshot.Add(cmd.watchPaths)
+		}
+	}
+
+	pkgVersion := opts.PkgVersion
+	if pkgVersion == "" {
+		pkgVersion = moduleVersion(pkgRefs)
+	}
+
 	// Build module dependency tree for versioned external links.
-	linker := docLinker{
-		RelLinkStyle: opts.RelLinkStyle,
-		Basename:     opts.Basename,
+	basename, linkStyle := opts.Basename, opts.RelLinkStyle
+	if opts.Format != formatHTML {
+		if basename == "" {
+			basename = opts.Format.DefaultBasename()
+		}
+
+		// Outside a web server, links to directories don't resolve,
+		// so they must point to the generated file.
+		linkStyle = relLinkStyleIndex
+	}
+	normalizeRelativePath := func(s string) string {
+		return linkStyle.Normalize(s, basename)
+	}
+
+	linker := sitegen.DocLinker{
+		NormalizeRelativePath: normalizeRelativePath,
 	}
 	if !opts.NoModuleVersions && len(pkgRefs) > 0 {
 		builder := &gomod.Builder{Logger: cmd.log}
@@ -186,12 +323,16 @@ func (cmd *mainCmd) run(ctx context.Context, opts *params) error {
 		linker.LocalPackage(ref.ImportPath)
 	}
 
-	var frontmatter *template.Template
+	var (
+		frontmatter    *template.Template
+		frontmatterSrc []byte
+	)
 	if path := opts.FrontMatter; len(path) > 0 {
 		bs, err := os.ReadFile(path)
 		if err != nil {
 			return errtrace.Wrap(fmt.Errorf("-frontmatter: %w", err))
 		}
+		frontmatterSrc = bs
 
 		frontmatter, err = template.New(path).Parse(string(bs))
 		if err != nil {
@@ -199,14 +340,129 @@ func (cmd *mainCmd) run(ctx context.Context, opts *params) error {
 		}
 	}
 
-	var indexer PageIndexer
-	if p := opts.Pagefind; p.Mode != pagefindDisabled {
+	var (
+		templates    *html.Templates
+		templatesSrc []byte
+	)
+	if dir := opts.TemplateDir; len(dir) > 0 {
+		if _, err := os.Stat(dir); err != nil {
+			return errtrace.Wrap(fmt.Errorf("-template-dir: %w", err))
+		}
+
+		fsys := os.DirFS(dir)
+		templates, err = html.ParseTemplates(fsys)
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("bad templates in %v: %w", dir, err))
+		}
+
+		for _, name := range html.TemplateFiles {
+			bs, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				continue // not overridden or already reported
+			}
+			templatesSrc = fmt.Appendf(templatesSrc, "%s\n%s\n", name, bs)
+		}
+	}
+
+	parser := gosrc.Parser{
+		Logger: cmd.log,
+	}
+	assembler := godoc.Assembler{
+		Linker: &linker,
+		Lexer:  highlight.GoLexer,
+		Logger: cmd.log,
+	}
+	renderer := html.Renderer{
+		Home:                  opts.Home,
+		Embedded:              opts.Embed,
+		Internal:              opts.Internal,
+		FrontMatter:           frontmatter,
+		Highlighter:           &highlighter,
+		NormalizeRelativePath: normalizeRelativePath,
+		BaseURL:               opts.BaseURL.URL,
+		DarkMode:              highlighter.DarkStyle != nil,
+		SymbolSearch:          opts.SymbolSearch,
+		Templates:             templates,
+	}
+	if opts.StaticDir != "" || len(opts.ExtraCSS) > 0 || len(opts.ExtraJS) > 0 {
+		assets, err := loadExtraAssets(opts.StaticDir, opts.ExtraCSS, opts.ExtraJS)
+		if err != nil {
+			return errtrace.Wrap(err)
+		}
+		renderer.ExtraStatic = assets.Files
+		renderer.ExtraCSS = assets.CSS
+		renderer.ExtraJS = assets.JS
+	}
+	for _, gi := range opts.GoImports {
+		renderer.GoImports = append(renderer.GoImports, &html.GoImport{
+			Module:  gi.Module,
+			VCS:     gi.VCS,
+			RepoURL: gi.RepoURL,
+			Subdir:  gi.Subdir,
+		})
+	}
+
+	manPages := manPageWriter{
+		Parser:     &parser,
+		Assembler:  &assembler,
+		PkgVersion: pkgVersion,
+		DebugLog:   cmd.debugLog,
+	}
+	if opts.Man != "" {
+		if err := manPages.WriteAll(ctx, output.Dir(opts.Man), pkgRefs); err != nil {
+			return errtrace.Wrap(fmt.Errorf("write man pages: %w", err))
+		}
+	}
+
+	if opts.Serve {
+		return errtrace.Wrap(cmd.serve(ctx, opts.HTTP, &sitegen.Server{
+			Log:        cmd.log,
+			DebugLog:   cmd.debugLog,
+			Parser:     &parser,
+			Assembler:  &assembler,
+			Renderer:   &renderer,
+			DocLinker:  &linker,
+			Packages:   pkgRefs,
+			Finder:     &finder,
+			Basename:   opts.Basename,
+			Home:       opts.Home,
+			PkgVersion: pkgVersion,
+		}))
+	}
+
+	if path := opts.SinglePage; path != "" {
+		return errtrace.Wrap(writeSinglePage(ctx, path, pkgRefs, &sitegen.SinglePageGenerator{
+			DebugLog:   cmd.debugLog,
+			Parser:     &parser,
+			Assembler:  &assembler,
+			Renderer:   &renderer,
+			DocLinker:  &linker,
+			Title:      singlePageTitle(opts.Home, pkgRefs),
+			PkgVersion: pkgVersion,
+		}))
+	}
+
+	archiveFormat, isArchive := output.ArchiveFormatOf(opts.OutputDir)
+
+	// With '-format man', only man pages are generated.
+	if opts.Format == formatMan {
+		if isArchive {
+			return errtrace.Wrap(writeArchive(opts.OutputDir, archiveFormat, func(out output.FS) error {
+				return errtrace.Wrap(manPages.WriteAll(ctx, out, pkgRefs))
+			}))
+		}
+		return errtrace.Wrap(manPages.WriteAll(ctx, output.Dir(opts.OutputDir), pkgRefs))
+	}
+
+	var indexer sitegen.PageIndexer
+	if p := opts.Pagefind; p.Mode != pagefindDisabled && !opts.SymbolSearch {
 		enable := p.Mode == pagefindEnabled
 
 		// If no explicitly enabled, and not in embed mode,
+		// writing an archive, or generating non-HTML output,
 		// enable only if the pagefind binary is available.
 		pagefindPath := p.Path
-		if !enable && p.Mode == pagefindAuto && !opts.Embed {
+		if !enable && p.Mode == pagefindAuto && !opts.Embed && !isArchive && opts.Format == formatHTML {
 			pagefindPath, err = exec.LookPath("pagefind")
 			if err == nil {
 				enable = true
@@ -220,38 +476,321 @@ func (cmd *mainCmd) run(ctx context.Context, opts *params) error {
 			}
 		}
 	}
+	renderer.Pagefind = indexer != nil
 
-	parser := gosrc.Parser{
-		Logger: cmd.log,
-	}
-
-	g := Generator{
-		Home:     opts.Home,
-		DebugLog: cmd.debugLog,
-		Parser:   &parser,
-		Assembler: &godoc.Assembler{
-			Linker: &linker,
-			Lexer:  highlight.GoLexer,
-			Logger: cmd.log,
-		},
-		Pagefind: indexer,
-		Renderer: &html.Renderer{
-			Home:        opts.Home,
-			Embedded:    opts.Embed,
-			Internal:    opts.Internal,
-			FrontMatter: frontmatter,
-			Highlighter: &highlighter,
-			NormalizeRelativePath: func(s string) string {
-				return opts.RelLinkStyle.Normalize(s, opts.Basename)
-			},
-			Pagefind: indexer != nil,
-		},
+	g := sitegen.Generator{
+		Home:       opts.Home,
+		Log:        cmd.log,
+		DebugLog:   cmd.debugLog,
+		Parser:     &parser,
+		Assembler:  &assembler,
+		Pagefind:   indexer,
+		Renderer:   &renderer,
 		OutDir:     opts.OutputDir,
 		SubDir:     opts.SubDir,
-		PkgVersion: opts.PkgVersion,
-		Basename:   opts.Basename,
+		PkgVersion: pkgVersion,
+		Basename:   basename,
 		DocLinker:  &linker,
+		Jobs:       opts.Jobs,
+		Clean:      sitegen.CleanMode(opts.Clean),
+		KeepGoing:  opts.KeepGoing,
+
+		// Other tools watching the output (e.g. 'hugo server')
+		// lose track of it if it's replaced on every change.
+		InPlace: opts.Watch,
+
+		SkipSiblingIndex: target.SkipSiblingIndex,
+		Siblings:         target.Siblings,
+	}
+
+	if u := opts.BaseURL.URL; u != nil {
+		g.Sitemap = &sitegen.Sitemap{
+			BaseURL:               u,
+			LatestOnly:            opts.SitemapLatest,
+			Internal:              opts.Internal,
+			NormalizeRelativePath: normalizeRelativePath,
+		}
 	}
 
-	return errtrace.Wrap(g.Generate(ctx, pkgRefs))
+	if opts.SymbolSearch {
+		g.SymbolIndex = &sitegen.SymbolIndex{
+			NormalizeRelativePath: normalizeRelativePath,
+		}
+	}
+
+	switch opts.Format {
+	case formatMarkdown:
+		g.Renderer = &markdown.Renderer{
+			Home:                  opts.Home,
+			Internal:              opts.Internal,
+			FrontMatter:           frontmatter,
+			NormalizeRelativePath: normalizeRelativePath,
+		}
+	case formatJSON:
+		g.Renderer = &jsondoc.Renderer{
+			Home:                  opts.Home,
+			Internal:              opts.Internal,
+			NormalizeRelativePath: normalizeRelativePath,
+		}
+	}
+
+	// runGenerator runs the generator and writes the -report.
+	// The report is written even if generation fails.
+	// With -watch, it's rewritten after every run,
+	// so the watcher ignores it (see ignorePaths).
+	runGenerator := func() error {
+		res, err := g.Generate(ctx, pkgRefs)
+		if opts.Report != "" {
+			report.addResult(res)
+			if reportErr := report.WriteFile(opts.Report); reportErr != nil {
+				err = errors.Join(err, fmt.Errorf("write report: %w", reportErr))
+			}
+		}
+		return errtrace.Wrap(err)
+	}
+
+	if target.Output != nil {
+		g.Output = target.Output
+		return errtrace.Wrap(runGenerator())
+	}
+
+	if isArchive {
+		return errtrace.Wrap(writeArchive(opts.OutputDir, archiveFormat, func(out output.FS) error {
+			g.Output = out
+			return errtrace.Wrap(runGenerator())
+		}))
+	}
+
+	var (
+		cache     *buildcache.Cache
+		cachePath string
+	)
+	if opts.Incremental || opts.Watch {
+		cachePath = filepath.Join(opts.OutputDir, opts.SubDir, _cacheFile)
+		cache, err = buildcache.Load(cachePath, cacheSalt(opts, frontmatterSrc, templatesSrc))
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("load build cache: %w", err))
+		}
+		g.Cache = cache
+		g.Preserve = append(g.Preserve, cachePath)
+	}
+
+	genErr := runGenerator()
+
+	// With -keep-going, pages for the packages that didn't fail
+	// are in place, so the cache must be saved for them.
+	var pkgsErr *sitegen.PackagesError
+	if genErr != nil && !errors.As(genErr, &pkgsErr) {
+		return errtrace.Wrap(genErr)
+	}
+
+	if cache != nil {
+		if err := cache.Save(cachePath); err != nil {
+			return errtrace.Wrap(fmt.Errorf("save build cache: %w", err))
+		}
+	}
+
+	return errtrace.Wrap(genErr)
+}
+
+// writeArchive calls generate to write files into an archive at path.
+//
+// The archive is replaced only if generate succeeds.
+func writeArchive(
+	path string,
+	format output.ArchiveFormat,
+	generate func(output.FS) error,
+) (err error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o1755); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			// f may already be closed. Only the removal matters.
+			_ = f.Close()
+			err = errtrace.Wrap(errors.Join(err, os.Remove(f.Name())))
+		}
+	}()
+
+	archive := output.NewArchive(f, format)
+	if err := generate(archive); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return errtrace.Wrap(fmt.Errorf("write archive: %w", err))
+	}
+	if err := f.Close(); err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	// CreateTemp creates files that only the owner can read.
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return errtrace.Wrap(err)
+	}
+	return errtrace.Wrap(os.Rename(f.Name(), path))
+}
+
+// _defaultHTTPAddr is the address that 'doc2go serve' listens on
+// if -http is not specified.
+const _defaultHTTPAddr = "localhost:6060"
+
+// serve runs an HTTP server on the given address
+// until the context is cancelled.
+func (cmd *mainCmd) serve(ctx context.Context, addr string, handler http.Handler) error {
+	if addr == "" {
+		addr = _defaultHTTPAddr
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	srv := http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = srv.Close()
+	})
+	defer stop()
+
+	cmd.log.Printf("Serving documentation at http://%v", ln.Addr())
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return errtrace.Wrap(err)
+	}
+	return nil
+}
+
+// _cacheFile is the name of the build cache file
+// written inside the site directory with -incremental.
+const _cacheFile = ".doc2go-cache.json"
+
+// _cacheVersion identifies the format of the build cache
+// and the way pages are generated from it.
+//
+// Bump this when a change to doc2go invalidates earlier caches.
+// Development builds don't have a version of their own,
+// so they rely on this to not reuse caches from incompatible builds.
+const _cacheVersion = 2
+
+// cacheSalt builds a salt for the build cache
+// from global inputs that affect every generated page.
+func cacheSalt(opts *params, frontmatter, templates []byte) string {
+	// Only parameters that affect the contents of a page.
+	// Patterns affect the set of packages,
+	// which the Generator accounts for separately.
+	key := struct {
+		Tags             string
+		Format           outputFormat
+		Basename         string
+		SubDir           string
+		PkgVersion       string
+		Home             string
+		Pagefind         pagefindMode
+		SymbolSearch     bool
+		BaseURL          string
+		Embed            bool
+		Internal         bool
+		PkgDocs          []pathTemplate
+		GoImports        []goImport
+		ExtraCSS         []filePath
+		ExtraJS          []filePath
+		RelLinkStyle     relLinkStyle
+		NoModuleVersions bool
+		Highlight        highlightParams
+	}{
+		Tags:             opts.Tags,
+		Format:           opts.Format,
+		Basename:         opts.Basename,
+		SubDir:           opts.SubDir,
+		PkgVersion:       opts.PkgVersion,
+		Home:             opts.Home,
+		Pagefind:         opts.Pagefind.Mode,
+		SymbolSearch:     opts.SymbolSearch,
+		BaseURL:          opts.BaseURL.String(),
+		Embed:            opts.Embed,
+		Internal:         opts.Internal,
+		PkgDocs:          opts.PkgDocs,
+		GoImports:        opts.GoImports,
+		ExtraCSS:         opts.ExtraCSS,
+		ExtraJS:          opts.ExtraJS,
+		RelLinkStyle:     opts.RelLinkStyle,
+		NoModuleVersions: opts.NoModuleVersions,
+		Highlight:        opts.Highlight,
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%d\n%#v\n", _version, _cacheVersion, key)
+	h.Write(frontmatter)
+	h.Write(templates)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeSinglePage generates the documentation for pkgRefs
+// into a single HTML file at path.
+func writeSinglePage(
+	ctx context.Context,
+	path string,
+	pkgRefs []*gosrc.PackageRef,
+	g *sitegen.SinglePageGenerator,
+) (err error) {
+	f, err := output.Dir(filepath.Dir(path)).Create(filepath.Base(path))
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer errdefer.Close(&err, f)
+
+	return errtrace.Wrap(g.Generate(ctx, f, pkgRefs))
+}
+
+// singlePageTitle picks a title for a single page document
+// holding the given packages.
+//
+// This is the home page if one was specified,
+// or the module that all packages belong to.
+func singlePageTitle(home string, refs []*gosrc.PackageRef) string {
+	if home != "" {
+		return home
+	}
+	if len(refs) == 0 || refs[0].Module == nil {
+		return "Documentation"
+	}
+
+	mod := refs[0].Module.Path
+	for _, ref := range refs[1:] {
+		if ref.Module == nil || ref.Module.Path != mod {
+			return "Documentation"
+		}
+	}
+	return mod
+}
+
+// moduleVersion returns the version of the module
+// that all the given packages were loaded from
+// with a module@version pattern.
+// It returns an empty string if the packages span multiple modules
+// or weren't loaded from the module cache.
+func moduleVersion(refs []*gosrc.PackageRef) string {
+	if len(refs) == 0 {
+		return ""
+	}
+
+	mod := refs[0].Module
+	if mod == nil || mod.Version == "" {
+		return ""
+	}
+	for _, ref := range refs[1:] {
+		if ref.Module == nil || *ref.Module != *mod {
+			return ""
+		}
+	}
+	return mod.Version
 }
@@ -1,17 +1,32 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
+	"encoding/json"
+	"io"
 	"io/fs"
+	"log"
+	"maps"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"braces.dev/errtrace"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.abhg.dev/doc2go/internal/gosrc"
 	"go.abhg.dev/doc2go/internal/iotest"
+	"go.abhg.dev/doc2go/internal/jsondoc"
+	"go.abhg.dev/doc2go/internal/sitegen"
+	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/packages/packagestest"
 )
 
@@ -91,6 +106,75 @@ func TestMainCmd_writeCSS(t *testing.T) {
 	assert.NotEmpty(t, buff.String())
 }
 
+func TestMainCmd_writeCSS_dark(t *testing.T) {
+	t.Parallel()
+
+	var light, both bytes.Buffer
+	for _, run := range []struct {
+		out  *bytes.Buffer
+		args []string
+	}{
+		{&light, []string{"-highlight-print-css", "-highlight=github"}},
+		{&both, []string{"-highlight-print-css", "-highlight=github,github-dark"}},
+	} {
+		exitCode := (&mainCmd{
+			Stdout: run.out,
+			Stderr: iotest.Writer(t),
+		}).Run(run.args)
+		require.Zero(t, exitCode)
+	}
+
+	got := both.String()
+	assert.True(t, strings.HasPrefix(got, light.String()),
+		"light theme must be first:\n%s", got)
+	assert.Contains(t, got, "@media (prefers-color-scheme: dark) {")
+}
+
+func TestMainCmd_writeCSS_unknownDark(t *testing.T) {
+	t.Parallel()
+
+	var buff bytes.Buffer
+	exitCode := (&mainCmd{
+		Stdout: iotest.Writer(t),
+		Stderr: &buff,
+	}).Run([]string{"-highlight-print-css", "-highlight=github,this-theme-does-not-exist"})
+	assert.NotZero(t, exitCode)
+	assert.Contains(t, buff.String(), `unknown theme "this-theme-does-not-exist"`)
+}
+
+func TestMainCmd_generate_unknownDark(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "package foo\n",
+				},
+			},
+		})
+
+	var stderr bytes.Buffer
+	outDir := t.TempDir()
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         io.MultiWriter(&stderr, iotest.Writer(t)),
+		packagesConfig: exported.Config,
+	}).Run([]string{
+		"-out", outDir,
+		"-highlight=github,this-theme-does-not-exist",
+		"./...",
+	})
+	require.Zero(t, exitCode, "unknown dark theme must only warn")
+	assert.Contains(t, stderr.String(),
+		`Unknown theme "this-theme-does-not-exist". Not using a dark theme.`)
+
+	css, err := os.ReadFile(filepath.Join(outDir, "_", "css", "highlight.css"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(css), "prefers-color-scheme: dark")
+}
+
 func TestMainCmd_writeCSS_unknown(t *testing.T) {
 	t.Parallel()
 
@@ -291,6 +375,152 @@ func TestMainCmd_frontmatter_errors(t *testing.T) {
 	})
 }
 
+func TestMainCmd_templateDir(t *testing.T) {
+	t.Parallel()
+
+	templateDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(templateDir, "layout.html"), []byte(
+		`{{ define "Page" }}<header>ACME Corp</header>{{ template "Body" $ }}{{ end }}`+
+			`{{ define "Embedded" }}{{ template "Body" $ }}{{ end }}`,
+	), 0o644))
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo\n",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		packagesConfig: exported.Config,
+	}).Run([]string{"-template-dir", templateDir, "-out", outDir, "./..."})
+	require.Zero(t, exitCode, "expected success")
+
+	bs, err := os.ReadFile(filepath.Join(outDir, "example.com", "foo", "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(bs), "<header>ACME Corp</header>")
+	assert.Contains(t, string(bs), "Package foo does things.")
+}
+
+func TestMainCmd_templateDir_errors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("bad template", func(t *testing.T) {
+		t.Parallel()
+
+		templateDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, "package.html"), []byte("{{"), 0o644))
+
+		var buff bytes.Buffer
+		exitCode := (&mainCmd{
+			Stdout: iotest.Writer(t),
+			Stderr: &buff,
+		}).Run([]string{"-template-dir", templateDir, "./..."})
+		require.NotZero(t, exitCode, "expected failure")
+		assert.Contains(t, buff.String(), "bad templates in")
+		assert.Contains(t, buff.String(), "package.html")
+	})
+
+	t.Run("directory does not exist", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		exitCode := (&mainCmd{
+			Stdout: iotest.Writer(t),
+			Stderr: &buff,
+		}).Run([]string{"-template-dir", "does-not-exist", "./..."})
+		require.NotZero(t, exitCode, "expected failure")
+		assert.Contains(t, buff.String(), "-template-dir")
+	})
+}
+
+func TestMainCmd_extraAssets(t *testing.T) {
+	t.Parallel()
+
+	assetsDir := t.TempDir()
+	for name, body := range map[string]string{
+		"brand.css":         `@font-face { src: url("fonts/inter.woff2"); }`,
+		"fonts/inter.woff2": "font",
+	} {
+		path := filepath.Join(assetsDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+	}
+
+	analyticsJS := filepath.Join(t.TempDir(), "analytics.js")
+	require.NoError(t, os.WriteFile(analyticsJS, []byte("track()"), 0o644))
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "package foo\n",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		packagesConfig: exported.Config,
+	}).Run([]string{
+		"-out", outDir,
+		"-home", "example.com/foo",
+		"-static-dir", assetsDir,
+		"-extra-css", filepath.Join(assetsDir, "brand.css"),
+		"-extra-js", analyticsJS,
+		"./...",
+	})
+	require.Zero(t, exitCode, "expected success")
+
+	for name, want := range map[string]string{
+		"_/extra/fonts/inter.woff2": "font",
+		"_/extra/analytics.js":      "track()",
+	} {
+		bs, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(name)))
+		if assert.NoError(t, err) {
+			assert.Equal(t, want, string(bs))
+		}
+	}
+	assert.FileExists(t, filepath.Join(outDir, "_", "css", "highlight.css"))
+
+	page, err := os.ReadFile(filepath.Join(outDir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(page), `<link href="_/extra/brand.css" rel="stylesheet" />`)
+	assert.Contains(t, string(page), `<script src="_/extra/analytics.js"></script>`)
+}
+
+func TestMainCmd_extraAssets_conflict(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a/style.css", "b/style.css"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
+	}
+
+	var buff bytes.Buffer
+	exitCode := (&mainCmd{
+		Stdout: iotest.Writer(t),
+		Stderr: &buff,
+	}).Run([]string{
+		"-extra-css", filepath.Join(dir, "a", "style.css"),
+		"-extra-css", filepath.Join(dir, "b", "style.css"),
+		"./...",
+	})
+	require.NotZero(t, exitCode, "expected failure")
+	assert.Contains(t, buff.String(), "another file is already named style.css")
+}
+
 func TestMainCmd_home(t *testing.T) {
 	t.Parallel()
 
@@ -323,3 +553,1129 @@ func TestMainCmd_home(t *testing.T) {
 	assertFileContains("index.html", "package bar")
 	assertFileContains("baz/index.html", "package baz")
 }
+
+func TestMainCmd_incremental(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "foo",
+				Files: map[string]any{
+					"foo.go":     "// Package foo does things.\npackage foo",
+					"bar/bar.go": "// Package bar does other things.\npackage bar",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	run := func() string {
+		var stderr bytes.Buffer
+		exitCode := (&mainCmd{
+			Stdout:         iotest.Writer(t),
+			Stderr:         io.MultiWriter(&stderr, iotest.Writer(t)),
+			packagesConfig: exported.Config,
+		}).Run([]string{"-incremental", "-debug", "-out", outDir, "./..."})
+		require.Zero(t, exitCode, "expected success")
+		return stderr.String()
+	}
+
+	out := run()
+	assert.NotContains(t, out, "Skipping unchanged package")
+	assert.FileExists(t, filepath.Join(outDir, _cacheFile))
+
+	out = run()
+	assert.Contains(t, out, "Skipping unchanged package foo/bar")
+	assert.Contains(t, out, "Skipping unchanged package foo\n")
+
+	bs, err := os.ReadFile(filepath.Join(outDir, "foo", "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(bs), "Package bar does other things",
+		"subpackage synopsis must be retained")
+}
+
+func TestMainCmd_keepGoing(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "foo",
+				Files: map[string]any{
+					"foo.go":           "// Package foo does things.\npackage foo",
+					"bar/bar.go":       "// Package bar does other things.\npackage bar",
+					"broken/broken.go": "package broken\n\nfunc {",
+				},
+			},
+		})
+
+	run := func(t *testing.T, args ...string) (exitCode int, stderr string) {
+		var buff bytes.Buffer
+		exitCode = (&mainCmd{
+			Stdout:         iotest.Writer(t),
+			Stderr:         io.MultiWriter(&buff, iotest.Writer(t)),
+			packagesConfig: exported.Config,
+		}).Run(append(args, "./..."))
+		return exitCode, buff.String()
+	}
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		outDir := t.TempDir()
+		exitCode, _ := run(t, "-out", outDir)
+		assert.NotZero(t, exitCode, "expected failure")
+		assert.NoFileExists(t, filepath.Join(outDir, "foo", "index.html"))
+	})
+
+	t.Run("keep going", func(t *testing.T) {
+		t.Parallel()
+
+		outDir := t.TempDir()
+		exitCode, stderr := run(t, "-keep-going", "-out", outDir)
+		assert.NotZero(t, exitCode, "expected failure")
+		assert.Contains(t, stderr, "1 package failed:\n  foo/broken: parse:")
+
+		assert.FileExists(t, filepath.Join(outDir, "foo", "index.html"))
+		assert.FileExists(t, filepath.Join(outDir, "foo", "bar", "index.html"))
+		assert.NoFileExists(t, filepath.Join(outDir, "foo", "broken", "index.html"))
+
+		bs, err := os.ReadFile(filepath.Join(outDir, "foo", "index.html"))
+		require.NoError(t, err)
+		assert.NotContains(t, string(bs), "broken",
+			"failed package must not be listed")
+	})
+}
+
+func TestMainCmd_markdown(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo\n\n" +
+						"// Foo does things. See [example.com/foo/bar.Bar].\nfunc Foo() {}\n",
+					"bar/bar.go": "// Package bar does other things.\npackage bar\n\n" +
+						"// Bar does other things.\nfunc Bar() {}\n",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		packagesConfig: exported.Config,
+	}).Run([]string{
+		"-out", outDir,
+		"-format", "markdown",
+		"-home", "example.com/foo",
+		"./...",
+	})
+	require.Zero(t, exitCode)
+
+	bs, err := os.ReadFile(filepath.Join(outDir, "README.md"))
+	require.NoError(t, err)
+	got := string(bs)
+	assert.Contains(t, got, "# package foo\n")
+	assert.Contains(t, got, "```go\nfunc Foo()\n```")
+	assert.Contains(t, got, "[example.com/foo/bar.Bar](bar/README.md#Bar)")
+	assert.Contains(t, got, "| [bar](bar/README.md) | Package bar does other things. |")
+
+	assert.FileExists(t, filepath.Join(outDir, "bar", "README.md"))
+	assert.NoFileExists(t, filepath.Join(outDir, "index.html"))
+	assert.NoDirExists(t, filepath.Join(outDir, "_"))
+}
+
+func TestMainCmd_json(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo\n\n" +
+						"import \"example.com/foo/bar\"\n\n" +
+						"// Foo does things.\nfunc Foo() bar.Bar { return 0 }\n",
+					"bar/bar.go": "// Package bar does other things.\npackage bar\n\n" +
+						"// Bar is a thing.\ntype Bar int\n",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		packagesConfig: exported.Config,
+	}).Run([]string{
+		"-out", outDir,
+		"-format", "json",
+		"-home", "example.com/foo",
+		"./...",
+	})
+	require.Zero(t, exitCode)
+
+	bs, err := os.ReadFile(filepath.Join(outDir, "index.json"))
+	require.NoError(t, err)
+
+	var pkg jsondoc.Package
+	require.NoError(t, json.Unmarshal(bs, &pkg), "invalid JSON:\n%s", bs)
+	assert.Equal(t, "example.com/foo", pkg.ImportPath)
+	require.Len(t, pkg.Functions, 1)
+	decl := pkg.Functions[0].Decl
+	assert.Equal(t, "func Foo() bar.Bar", decl.Text)
+	assert.Equal(t, []*jsondoc.Link{
+		{Start: 11, End: 14, Dest: "bar/index.json"},
+		{Start: 15, End: 18, Dest: "bar/index.json#Bar"},
+	}, decl.Links)
+
+	bs, err = os.ReadFile(filepath.Join(outDir, jsondoc.ManifestName))
+	require.NoError(t, err)
+
+	var manifest jsondoc.Manifest
+	require.NoError(t, json.Unmarshal(bs, &manifest), "invalid JSON:\n%s", bs)
+	assert.Equal(t, []*jsondoc.ManifestPage{
+		{
+			ImportPath: "example.com/foo",
+			Kind:       sitegen.PackagePage,
+			Path:       "index.json",
+			Synopsis:   "Package foo does things.",
+		},
+		{
+			ImportPath: "example.com/foo/bar",
+			Kind:       sitegen.PackagePage,
+			Path:       "bar/index.json",
+			Synopsis:   "Package bar does other things.",
+		},
+	}, manifest.Pages)
+	assert.NoFileExists(t, filepath.Join(outDir, "index.html"))
+}
+
+func TestMainCmd_man(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo\n",
+					"cmd/foo/main.go": "// foo does things from the command line.\n" +
+						"//\n// # Usage\n//\n//\tfoo [-v]\npackage main\n\nfunc main() {}",
+				},
+			},
+		})
+
+	t.Run("alongside site", func(t *testing.T) {
+		t.Parallel()
+
+		outDir, manDir := t.TempDir(), t.TempDir()
+		exitCode := (&mainCmd{
+			Stdout:         iotest.Writer(t),
+			Stderr:         iotest.Writer(t),
+			packagesConfig: exported.Config,
+		}).Run([]string{"-out", outDir, "-man", manDir, "./..."})
+		require.Zero(t, exitCode)
+
+		bs, err := os.ReadFile(filepath.Join(manDir, "foo.1"))
+		require.NoError(t, err)
+		assert.Contains(t, string(bs), `.TH "FOO" "1"`)
+		assert.Contains(t, string(bs), "foo \\- does things from the command line\n")
+		assert.Contains(t, string(bs), `.SH "USAGE"`)
+		assert.FileExists(t, filepath.Join(outDir, "index.html"))
+	})
+
+	t.Run("instead of site", func(t *testing.T) {
+		t.Parallel()
+
+		outDir := t.TempDir()
+		exitCode := (&mainCmd{
+			Stdout:         iotest.Writer(t),
+			Stderr:         iotest.Writer(t),
+			packagesConfig: exported.Config,
+		}).Run([]string{"-out", outDir, "-format", "man", "./..."})
+		require.Zero(t, exitCode)
+
+		ents, err := os.ReadDir(outDir)
+		require.NoError(t, err)
+		var names []string
+		for _, ent := range ents {
+			names = append(names, ent.Name())
+		}
+		assert.Equal(t, []string{"foo.1"}, names)
+	})
+}
+
+func TestMainCmd_singlePage(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo\n\n" +
+						"import \"example.com/foo/bar\"\n\n" +
+						"// Foo does things.\nfunc Foo() bar.Bar { return 0 }\n",
+					"bar/bar.go": "// Package bar does other things.\npackage bar\n\n" +
+						"// Bar is a thing.\ntype Bar int\n",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	singlePage := filepath.Join(t.TempDir(), "docs", "api.html")
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		packagesConfig: exported.Config,
+	}).Run([]string{
+		"-out", outDir,
+		"-single-page", singlePage,
+		"./...",
+	})
+	require.Zero(t, exitCode)
+
+	bs, err := os.ReadFile(singlePage)
+	require.NoError(t, err)
+	got := string(bs)
+
+	assert.Contains(t, got, "<title>example.com/foo</title>")
+	assert.Contains(t, got, `<section id="example.com/foo" class="package">`)
+	assert.Contains(t, got, `<section id="example.com/foo/bar" class="package">`)
+
+	// The declaration of Foo links to the section for Bar.
+	assert.Contains(t, got, `<a href="#example.com/foo/bar:Bar">`)
+
+	// No website is generated.
+	assert.NoDirExists(t, filepath.Join(outDir, "_"))
+	assert.NoFileExists(t, filepath.Join(outDir, "index.html"))
+}
+
+func TestMainCmd_sitemap(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go":              "// Package foo does things.\npackage foo\n",
+					"internal/bar/bar.go": "package bar\n",
+				},
+			},
+		})
+
+	var stderr bytes.Buffer
+	outDir := t.TempDir()
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         io.MultiWriter(&stderr, iotest.Writer(t)),
+		packagesConfig: exported.Config,
+	}).Run([]string{
+		"-out", outDir,
+		"-home", "example.com/foo",
+		"-base-url", "https://example.com/foo",
+		"-rel-link-style", "directory",
+		"./...",
+	})
+	require.Zero(t, exitCode)
+
+	sitemap, err := os.ReadFile(filepath.Join(outDir, "sitemap.xml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(sitemap), "<loc>https://example.com/foo/</loc>")
+	assert.NotContains(t, string(sitemap), "internal")
+
+	// The site isn't at the root of the host,
+	// so robots.txt is printed instead of written.
+	assert.NoFileExists(t, filepath.Join(outDir, "robots.txt"))
+	assert.Contains(t, stderr.String(), "Disallow: /foo/internal/\n")
+	assert.Contains(t, stderr.String(), "Sitemap: https://example.com/foo/sitemap.xml\n")
+
+	index, err := os.ReadFile(filepath.Join(outDir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), `<link rel="canonical" href="https://example.com/foo/">`)
+	assert.Contains(t, string(index), `<meta property="og:description" content="Package foo does things.">`)
+}
+
+func TestMainCmd_canonicalVersions(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go":     "// Package foo does things.\npackage foo\n",
+					"bar/bar.go": "// Package bar does other things.\npackage bar\n",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	generate := func(t *testing.T, subdir string) {
+		t.Helper()
+
+		exitCode := (&mainCmd{
+			Stdout:         iotest.Writer(t),
+			Stderr:         iotest.Writer(t),
+			packagesConfig: exported.Config,
+		}).Run([]string{
+			"-out", outDir,
+			"-subdir", subdir,
+			"-home", "example.com/foo",
+			"-base-url", "https://example.com/",
+			"-rel-link-style", "directory",
+			"./...",
+		})
+		require.Zero(t, exitCode, "generate %v", subdir)
+	}
+
+	const baseURL = "https://example.com/"
+	readPage := func(t *testing.T, pageURL string) string {
+		t.Helper()
+
+		require.True(t, strings.HasPrefix(pageURL, baseURL), "unexpected URL %q", pageURL)
+		page := strings.TrimPrefix(pageURL, baseURL)
+		bs, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(page), "index.html"))
+		require.NoError(t, err, "read %v", pageURL)
+		return string(bs)
+	}
+
+	canonicalRe := regexp.MustCompile(`<link rel="canonical" href="([^"]+)">`)
+	canonicalURL := func(t *testing.T, body string) string {
+		t.Helper()
+
+		m := canonicalRe.FindStringSubmatch(body)
+		require.NotNil(t, m, "no canonical link in:\n%s", body)
+		return m[1]
+	}
+
+	generate(t, "v1.0.0")
+	generate(t, "v1.1.0")
+
+	// Sites aren't rewritten when a newer release is published,
+	// so v1.0.0 names itself until it's generated again.
+	assert.Equal(t, baseURL+"v1.0.0/bar/", canonicalURL(t, readPage(t, baseURL+"v1.0.0/bar/")),
+		"stale version must keep its own canonical URL")
+	sitemap, err := os.ReadFile(filepath.Join(outDir, "sitemap.xml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sitemap), baseURL+"v1.0.0/bar/",
+		"stale version is left out of the sitemap")
+	generate(t, "v1.0.0")
+
+	tests := []struct {
+		page string
+		want string
+	}{
+		{
+			page: "v1.0.0/bar/",
+			want: "https://example.com/v1.1.0/bar/",
+		},
+		{
+			page: "v1.1.0/bar/",
+			want: "https://example.com/v1.1.0/bar/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			got := canonicalURL(t, readPage(t, baseURL+tt.page))
+			assert.Equal(t, tt.want, got)
+
+			// The canonical page must be a real page,
+			// not a redirect, and must name itself.
+			target := readPage(t, got)
+			assert.NotContains(t, target, `http-equiv="refresh"`)
+			assert.Equal(t, got, canonicalURL(t, target))
+		})
+	}
+
+	t.Run("sitemap", func(t *testing.T) {
+		bs, err := os.ReadFile(filepath.Join(outDir, "sitemap.xml"))
+		require.NoError(t, err)
+
+		locs := regexp.MustCompile(`<loc>([^<]+)</loc>`).FindAllStringSubmatch(string(bs), -1)
+		require.NotEmpty(t, locs, "empty sitemap:\n%s", bs)
+		for _, loc := range locs {
+			page := readPage(t, loc[1])
+			assert.Equal(t, loc[1], canonicalURL(t, page),
+				"sitemap must only list canonical URLs")
+		}
+	})
+}
+
+func TestMainCmd_symbolSearchEmbedded(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo\n\n" +
+						"// Foo does things.\nfunc Foo() {}\n",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		packagesConfig: exported.Config,
+	}).Run([]string{
+		"-out", outDir,
+		"-home", "example.com/foo",
+		"-embed",
+		"-symbol-search",
+		"./...",
+	})
+	require.Zero(t, exitCode)
+
+	index, err := os.ReadFile(filepath.Join(outDir, "_", "symbols.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), `{"name":"Foo","kind":"func","id":"Foo","synopsis":"Foo does things."}`)
+
+	for _, name := range []string{"js/search.js", "css/search.css"} {
+		assert.FileExists(t, filepath.Join(outDir, "_", filepath.FromSlash(name)))
+	}
+
+	page, err := os.ReadFile(filepath.Join(outDir, "index.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(page), `id="symbol-search"`)
+}
+
+func TestMainCmd_report(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo\n\n" +
+						"// Foo does things.\nfunc Foo() {}\n\nfunc Bar() {}\n",
+					"cmd/foo/main.go":  "// foo is a program.\npackage main\n\nfunc main() {}",
+					"tests/x_test.go":  "package tests",
+					"broken/broken.go": "package broken\n\nfunc {",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		packagesConfig: exported.Config,
+	}).Run([]string{
+		"-out", outDir,
+		"-report", reportPath,
+		"-keep-going",
+		"./...",
+	})
+	assert.NotZero(t, exitCode, "expected failure from broken package")
+
+	bs, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+
+	var report struct {
+		Pages []struct {
+			ImportPath string `json:"importPath"`
+			Path       string `json:"path"`
+			Kind       string `json:"kind"`
+		} `json:"pages"`
+		Packages []struct {
+			ImportPath string `json:"importPath"`
+			Exported   int    `json:"exported"`
+			Documented int    `json:"documented"`
+			Error      string `json:"error"`
+		} `json:"packages"`
+		Skipped []struct {
+			ImportPath string `json:"importPath"`
+			Reason     string `json:"reason"`
+		} `json:"skipped"`
+		Symbols struct {
+			Exported   int `json:"exported"`
+			Documented int `json:"documented"`
+		} `json:"symbols"`
+		Timings map[string]float64 `json:"timings"`
+	}
+	require.NoError(t, json.Unmarshal(bs, &report), "invalid JSON:\n%s", bs)
+
+	pages := make(map[string]string) // import path => kind
+	for _, page := range report.Pages {
+		pages[page.ImportPath] = page.Kind
+		assert.FileExists(t, filepath.Join(outDir, filepath.FromSlash(page.Path)))
+	}
+	assert.Equal(t, "package", pages["example.com/foo"])
+	assert.Equal(t, "command", pages["example.com/foo/cmd/foo"])
+	assert.Equal(t, "directory", pages["example.com/foo/cmd"])
+	assert.NotContains(t, pages, "example.com/foo/broken")
+
+	var brokenErr string
+	for _, pkg := range report.Packages {
+		switch pkg.ImportPath {
+		case "example.com/foo":
+			assert.Equal(t, 2, pkg.Exported)
+			assert.Equal(t, 1, pkg.Documented)
+		case "example.com/foo/broken":
+			brokenErr = pkg.Error
+		}
+	}
+	assert.Contains(t, brokenErr, "parse:")
+	assert.Equal(t, 2, report.Symbols.Exported)
+	assert.Equal(t, 1, report.Symbols.Documented)
+
+	if assert.Len(t, report.Skipped, 1) {
+		assert.Equal(t, "example.com/foo/tests", report.Skipped[0].ImportPath)
+		assert.Equal(t, "no non-test files", report.Skipped[0].Reason)
+	}
+
+	assert.ElementsMatch(t,
+		[]string{"find", "parse", "assemble", "render", "pagefind"},
+		slices.Collect(maps.Keys(report.Timings)))
+}
+
+func TestMainCmd_report_cached(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "example.com/foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo\n\n" +
+						"// Foo does things.\nfunc Foo() {}\n\nfunc Bar() {}\n",
+				},
+			},
+		})
+
+	type report struct {
+		Packages []struct {
+			ImportPath string `json:"importPath"`
+			Exported   int    `json:"exported"`
+			Documented int    `json:"documented"`
+			Cached     bool   `json:"cached"`
+		} `json:"packages"`
+		Symbols struct {
+			Exported   int `json:"exported"`
+			Documented int `json:"documented"`
+		} `json:"symbols"`
+	}
+
+	outDir := t.TempDir()
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	run := func(t *testing.T) *report {
+		t.Helper()
+
+		exitCode := (&mainCmd{
+			Stdout:         iotest.Writer(t),
+			Stderr:         iotest.Writer(t),
+			packagesConfig: exported.Config,
+		}).Run([]string{"-out", outDir, "-report", reportPath, "-incremental", "./..."})
+		require.Zero(t, exitCode)
+
+		bs, err := os.ReadFile(reportPath)
+		require.NoError(t, err)
+
+		var r report
+		require.NoError(t, json.Unmarshal(bs, &r), "invalid JSON:\n%s", bs)
+		return &r
+	}
+
+	first := run(t)
+	require.Len(t, first.Packages, 1)
+	assert.False(t, first.Packages[0].Cached)
+
+	// The second run reuses the page from the first,
+	// but still reports its symbols.
+	second := run(t)
+	require.Len(t, second.Packages, 1)
+	assert.True(t, second.Packages[0].Cached, "package must be cached")
+	assert.Equal(t, 2, second.Packages[0].Exported)
+	assert.Equal(t, 1, second.Packages[0].Documented)
+	assert.Equal(t, first.Symbols, second.Symbols)
+}
+
+func TestMainCmd_archive(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "foo",
+				Files: map[string]any{
+					"foo.go":     "// Package foo does things.\npackage foo",
+					"bar/bar.go": "// Package bar does other things.\npackage bar",
+				},
+			},
+		})
+
+	outDir := t.TempDir()
+	archivePath := filepath.Join(outDir, "site.zip")
+	exitCode := (&mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		packagesConfig: exported.Config,
+	}).Run([]string{"-out", archivePath, "./..."})
+	require.Zero(t, exitCode, "expected success")
+
+	zr, err := zip.OpenReader(archivePath)
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, zr.Close())
+	}()
+
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Subset(t, names, []string{
+		"index.html",
+		"_/css/main.css",
+		"foo/index.html",
+		"foo/bar/index.html",
+	})
+
+	ents, err := os.ReadDir(outDir)
+	require.NoError(t, err)
+	assert.Len(t, ents, 1, "only the archive must be written")
+}
+
+func TestMainCmd_refs(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found:", err)
+	}
+
+	// The module lives in a subdirectory of the repository
+	// to verify that packages are loaded from the same place
+	// inside each worktree.
+	repoDir := t.TempDir()
+	modDir := filepath.Join(repoDir, "mod")
+	writeFile := func(name, body string) {
+		path := filepath.Join(modDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+	}
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test",
+			"GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test",
+			"GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, "git %v:\n%s", args, out)
+	}
+
+	writeFile("go.mod", "module example.com/foo\n\ngo 1.21\n")
+	writeFile("foo.go", "// Package foo is version one.\npackage foo\n")
+	git("init", "--quiet")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1.0.0")
+	git("branch", "release/v1")
+
+	writeFile("foo.go", "// Package foo is version two.\npackage foo\n")
+	writeFile("bar/bar.go", "// Package bar is new.\npackage bar\n")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "v2")
+	git("tag", "v1.1.0")
+
+	// Uncommitted changes must not affect the output.
+	writeFile("foo.go", "// Package foo is a work in progress.\npackage foo\n")
+
+	run := func(t *testing.T, out string) {
+		exitCode := (&mainCmd{
+			Stdout:         iotest.Writer(t),
+			Stderr:         iotest.Writer(t),
+			packagesConfig: &packages.Config{Dir: modDir},
+		}).Run([]string{
+			"-out", out,
+			"-refs", "v1.*,release/v1",
+			"-base-url", "https://example.com/",
+			"-debug",
+			"./...",
+		})
+		require.Zero(t, exitCode, "expected success")
+	}
+
+	// Subtests share a repository, so they can't run in parallel.
+	t.Run("directory", func(t *testing.T) {
+		outDir := t.TempDir()
+		run(t, outDir)
+
+		readFile := func(path string) string {
+			bs, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(path)))
+			require.NoError(t, err)
+			return string(bs)
+		}
+
+		assert.Contains(t, readFile("v1.0.0/example.com/foo/index.html"), "Package foo is version one.")
+		assert.Contains(t, readFile("v1.0.0/example.com/foo/index.html"), "v1.0.0")
+		assert.NoFileExists(t, filepath.Join(outDir, "v1.0.0", "example.com", "foo", "bar", "index.html"))
+
+		assert.Contains(t, readFile("v1.1.0/example.com/foo/index.html"), "Package foo is version two.")
+		assert.Contains(t, readFile("v1.1.0/example.com/foo/bar/index.html"), "Package bar is new.")
+
+		assert.Contains(t, readFile("release-v1/example.com/foo/index.html"), "Package foo is version one.")
+
+		// All refs prefer the newest release,
+		// even the ones listed before it.
+		for _, subdir := range []string{"v1.0.0", "v1.1.0", "release-v1"} {
+			assert.Contains(t, readFile(subdir+"/example.com/foo/index.html"),
+				`<link rel="canonical" href="https://example.com/v1.1.0/example.com/foo">`,
+				"canonical link of %v", subdir)
+		}
+		assert.FileExists(t, filepath.Join(outDir, "_", "css", "main.css"))
+
+		index := readFile("index.html")
+		for _, subdir := range []string{"v1.0.0", "v1.1.0", "release-v1"} {
+			assert.Contains(t, index, subdir)
+		}
+
+		// Worktrees must be cleaned up.
+		cmd := exec.Command("git", "worktree", "list", "--porcelain")
+		cmd.Dir = repoDir
+		out, err := cmd.Output()
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(out), "worktree "), "worktrees:\n%s", out)
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "site.zip")
+		run(t, archivePath)
+
+		zr, err := zip.OpenReader(archivePath)
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, zr.Close())
+		}()
+
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.Subset(t, names, []string{
+			"index.html",
+			"_/css/main.css",
+			"v1.0.0/example.com/foo/index.html",
+			"v1.1.0/example.com/foo/bar/index.html",
+			"release-v1/example.com/foo/index.html",
+		})
+
+		idx, err := zr.Open("index.html")
+		require.NoError(t, err)
+		defer func() {
+			assert.NoError(t, idx.Close())
+		}()
+		bs, err := io.ReadAll(idx)
+		require.NoError(t, err)
+		for _, subdir := range []string{"v1.0.0", "v1.1.0", "release-v1"} {
+			assert.Contains(t, string(bs), subdir)
+		}
+	})
+}
+
+func TestMainCmd_watch(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo",
+				},
+			},
+		})
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	outDir := t.TempDir()
+	args := []string{"-watch", "-out", outDir, "./..."}
+	opts, err := (&cliParser{Stderr: iotest.Writer(t)}).Parse(args)
+	require.NoError(t, err)
+
+	cmd := mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		log:            log.New(iotest.Writer(t), "", 0),
+		debugLog:       log.New(iotest.Writer(t), "", 0),
+		packagesConfig: exported.Config,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.watch(ctx, wd, args, opts)
+	}()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+	}()
+
+	pagePath := filepath.Join(outDir, "foo", "index.html")
+	pageContains := func(s string) func() bool {
+		return func() bool {
+			bs, err := os.ReadFile(pagePath)
+			return err == nil && strings.Contains(string(bs), s)
+		}
+	}
+	require.Eventually(t, pageContains("Package foo does things."),
+		10*time.Second, 50*time.Millisecond, "initial generation")
+
+	outInfo, err := os.Stat(outDir)
+	require.NoError(t, err)
+
+	srcPath := exported.File("foo", "foo.go")
+	require.NoError(t, os.WriteFile(srcPath,
+		[]byte("// Package foo does new things.\npackage foo"), 0o644))
+
+	require.Eventually(t, pageContains("Package foo does new things."),
+		10*time.Second, 50*time.Millisecond, "regeneration")
+
+	// Tools watching the output directory
+	// must not lose track of it.
+	newInfo, err := os.Stat(outDir)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(outInfo, newInfo),
+		"output directory must be updated in place")
+}
+
+func TestMainCmd_watchOwnOutputs(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo",
+				},
+			},
+		})
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	// The report and debug log are written
+	// into the directory of a watched package.
+	pkgDir := filepath.Dir(exported.File("foo", "foo.go"))
+	reportPath := filepath.Join(pkgDir, "report.json")
+	debugPath := filepath.Join(pkgDir, "debug.log")
+
+	outDir := t.TempDir()
+	args := []string{
+		"-watch",
+		"-out", outDir,
+		"-report", reportPath,
+		"-debug=" + debugPath,
+		"./...",
+	}
+	opts, err := (&cliParser{Stderr: iotest.Writer(t)}).Parse(args)
+	require.NoError(t, err)
+
+	var logs bytes.Buffer
+	cmd := mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		log:            log.New(io.MultiWriter(&logs, iotest.Writer(t)), "", 0),
+		debugLog:       log.New(iotest.Writer(t), "", 0),
+		packagesConfig: exported.Config,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.watch(ctx, wd, args, opts)
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(reportPath)
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond, "initial generation")
+
+	// cmd.debugLog isn't opened from -debug here,
+	// so write to the debug log ourselves.
+	require.NoError(t, os.WriteFile(debugPath, []byte("debug output"), 0o644))
+
+	// Wait for a few polling intervals
+	// to give the watcher time to notice changes.
+	time.Sleep(2 * time.Second)
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.NotContains(t, logs.String(), "Regenerating",
+		"files written by doc2go must not trigger a rebuild")
+}
+
+func TestMainCmd_watchStaticDir(t *testing.T) {
+	t.Parallel()
+
+	exported := packagestest.Export(t,
+		packagestest.Modules, []packagestest.Module{
+			{
+				Name: "foo",
+				Files: map[string]any{
+					"foo.go": "// Package foo does things.\npackage foo",
+				},
+			},
+		})
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	staticDir := t.TempDir()
+	nestedPath := filepath.Join(staticDir, "img", "icons", "logo.svg")
+	require.NoError(t, os.MkdirAll(filepath.Dir(nestedPath), 0o1755))
+	require.NoError(t, os.WriteFile(nestedPath, []byte("old logo"), 0o644))
+
+	outDir := t.TempDir()
+	args := []string{"-watch", "-out", outDir, "-static-dir", staticDir, "./..."}
+	opts, err := (&cliParser{Stderr: iotest.Writer(t)}).Parse(args)
+	require.NoError(t, err)
+
+	cmd := mainCmd{
+		Stdout:         iotest.Writer(t),
+		Stderr:         iotest.Writer(t),
+		log:            log.New(iotest.Writer(t), "", 0),
+		debugLog:       log.New(iotest.Writer(t), "", 0),
+		packagesConfig: exported.Config,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.watch(ctx, wd, args, opts)
+	}()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+	}()
+
+	outPath := filepath.Join(outDir, "_", "extra", "img", "icons", "logo.svg")
+	outContains := func(s string) func() bool {
+		return func() bool {
+			bs, err := os.ReadFile(outPath)
+			return err == nil && string(bs) == s
+		}
+	}
+	require.Eventually(t, outContains("old logo"),
+		10*time.Second, 50*time.Millisecond, "initial generation")
+
+	// Different size so that the change is seen
+	// even if the modification time doesn't change.
+	require.NoError(t, os.WriteFile(nestedPath, []byte("new bigger logo"), 0o644))
+
+	require.Eventually(t, outContains("new bigger logo"),
+		10*time.Second, 50*time.Millisecond, "regeneration")
+}
+
+func TestModuleVersion(t *testing.T) {
+	t.Parallel()
+
+	foo1 := &gosrc.ModuleRef{Path: "example.com/foo", Version: "v1.0.0"}
+	foo2 := &gosrc.ModuleRef{Path: "example.com/foo", Version: "v2.0.0"}
+	local := &gosrc.ModuleRef{Path: "example.com/foo"}
+
+	tests := []struct {
+		desc string
+		refs []*gosrc.PackageRef
+		want string
+	}{
+		{desc: "empty"},
+		{
+			desc: "single module",
+			refs: []*gosrc.PackageRef{
+				{ImportPath: "example.com/foo", Module: foo1},
+				{ImportPath: "example.com/foo/bar", Module: foo1},
+			},
+			want: "v1.0.0",
+		},
+		{
+			desc: "different versions",
+			refs: []*gosrc.PackageRef{
+				{ImportPath: "example.com/foo", Module: foo1},
+				{ImportPath: "example.com/foo", Module: foo2},
+			},
+		},
+		{
+			desc: "local module",
+			refs: []*gosrc.PackageRef{
+				{ImportPath: "example.com/foo", Module: local},
+			},
+		},
+		{
+			desc: "no module",
+			refs: []*gosrc.PackageRef{
+				{ImportPath: "example.com/foo", Module: foo1},
+				{ImportPath: "example.com/bar"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, moduleVersion(tt.refs))
+		})
+	}
+}
+
+func TestCacheSalt(t *testing.T) {
+	t.Parallel()
+
+	want := cacheSalt(&params{Basename: "index.html"}, nil, nil)
+
+	t.Run("ignored", func(t *testing.T) {
+		t.Parallel()
+
+		opts := params{
+			Basename:    "index.html",
+			OutputDir:   "out",
+			Jobs:        4,
+			Watch:       true,
+			Incremental: true,
+			Report:      "report.json",
+			Clean:       cleanEnabled,
+			KeepGoing:   true,
+			Patterns:    []string{"./..."},
+		}
+		assert.Equal(t, want, cacheSalt(&opts, nil, nil))
+	})
+
+	t.Run("embed", func(t *testing.T) {
+		t.Parallel()
+
+		opts := params{Basename: "index.html", Embed: true}
+		assert.NotEqual(t, want, cacheSalt(&opts, nil, nil))
+	})
+
+	t.Run("base URL", func(t *testing.T) {
+		t.Parallel()
+
+		u, err := url.Parse("https://example.com/docs/")
+		require.NoError(t, err)
+		opts := params{Basename: "index.html", BaseURL: baseURL{u}}
+		assert.NotEqual(t, want, cacheSalt(&opts, nil, nil))
+	})
+
+	t.Run("templates", func(t *testing.T) {
+		t.Parallel()
+
+		opts := params{Basename: "index.html"}
+		assert.NotEqual(t, want, cacheSalt(&opts, nil, []byte("tmpl")))
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/errdefer"
+	"go.abhg.dev/doc2go/internal/godoc"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/html"
+	"go.abhg.dev/doc2go/internal/man"
+	"go.abhg.dev/doc2go/internal/output"
+)
+
+// manPageWriter writes man pages for the commands among a set of packages.
+// It's used for -man and '-format man'.
+type manPageWriter struct {
+	Parser     *gosrc.Parser
+	Assembler  *godoc.Assembler
+	PkgVersion string
+	DebugLog   *log.Logger
+}
+
+// WriteAll writes a man page for each command in pkgRefs
+// into the root of out.
+// Other packages are ignored.
+func (w *manPageWriter) WriteAll(ctx context.Context, out output.FS, pkgRefs []*gosrc.PackageRef) error {
+	written := make(map[string]string) // file name => import path
+	for _, ref := range pkgRefs {
+		if ref.Name != "main" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return errtrace.Wrap(err)
+		}
+
+		bpkg, err := w.Parser.ParsePackage(ref)
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("%v: parse: %w", ref.ImportPath, err))
+		}
+
+		dpkg, err := w.Assembler.Assemble(bpkg)
+		if err != nil {
+			return errtrace.Wrap(fmt.Errorf("%v: assemble: %w", ref.ImportPath, err))
+		}
+
+		name := man.FileName(dpkg.BinName)
+		if other, ok := written[name]; ok {
+			return errtrace.Wrap(fmt.Errorf("%v: man page %v was already written for %v", ref.ImportPath, name, other))
+		}
+		written[name] = ref.ImportPath
+
+		w.DebugLog.Printf("Writing man page %v for %v", name, ref.ImportPath)
+		if err := w.write(out, name, dpkg); err != nil {
+			return errtrace.Wrap(fmt.Errorf("%v: %w", ref.ImportPath, err))
+		}
+	}
+	return nil
+}
+
+func (w *manPageWriter) write(out output.FS, name string, dpkg *godoc.Package) (err error) {
+	f, err := out.Create(name)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer errdefer.Close(&err, f)
+
+	return errtrace.Wrap(new(man.Renderer).RenderPackage(f, &html.PackageInfo{
+		Package:    dpkg,
+		PkgVersion: w.PkgVersion,
+	}))
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/gitrepo"
+	"go.abhg.dev/doc2go/internal/output"
+	"go.abhg.dev/doc2go/internal/sitegen"
+)
+
+// runRefs generates documentation for each of the git refs in -refs.
+//
+// Each ref is checked out into a temporary worktree,
+// and its documentation is generated into a subdirectory
+// named after the ref, as if by '-subdir REF -pkg-version REF'.
+// The index of these subdirectories is generated once, after all refs.
+//
+// The newest stable release is generated first
+// so that pages of the other refs can name it as their canonical version.
+func (cmd *mainCmd) runRefs(ctx context.Context, opts *params) (err error) {
+	repo := gitrepo.Repo{}
+	if cmd.packagesConfig != nil {
+		repo.Dir = cmd.packagesConfig.Dir
+	}
+	if cmd.debug {
+		repo.DebugLog = cmd.debugLog
+	}
+
+	refs, err := repo.ResolveRefs(ctx, strings.Split(opts.Refs, ","))
+	if err != nil {
+		return errtrace.Wrap(fmt.Errorf("resolve refs: %w", err))
+	}
+
+	// Packages in the worktree are loaded from the same
+	// relative directory as the working directory in the repository.
+	prefix, err := repo.Prefix(ctx)
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+
+	subdirs := make([]string, len(refs))
+	refBySubdir := make(map[string]string, len(refs)) // subdir => ref
+	for i, ref := range refs {
+		subdir := refSubDir(ref)
+		if other, ok := refBySubdir[subdir]; ok {
+			return errtrace.Wrap(fmt.Errorf("refs %q and %q would both be generated into %q", other, ref, subdir))
+		}
+		refBySubdir[subdir] = ref
+		subdirs[i] = subdir
+	}
+	if i := slices.Index(subdirs, sitegen.LatestRelease(subdirs)); i > 0 {
+		refs[0], refs[i] = refs[i], refs[0]
+		subdirs[0], subdirs[i] = subdirs[i], subdirs[0]
+	}
+
+	tmpDir, err := os.MkdirTemp("", "doc2go-refs-*")
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer func() {
+		err = errtrace.Wrap(errors.Join(err, os.RemoveAll(tmpDir)))
+	}()
+
+	generateAll := func(out output.FS) error {
+		for i, ref := range refs {
+			refOpts := *opts
+			refOpts.SubDir = subdirs[i]
+			refOpts.PkgVersion = ref
+
+			worktree := filepath.Join(tmpDir, subdirs[i])
+			target := siteTarget{
+				Dir:    filepath.Join(worktree, filepath.FromSlash(prefix)),
+				Output: out,
+				// Only the last ref writes the index of subdirectories
+				// so that it includes all of them.
+				SkipSiblingIndex: i < len(refs)-1,
+				Siblings:         subdirs[:i],
+			}
+			if err := cmd.generateRef(ctx, &repo, ref, worktree, &refOpts, target); err != nil {
+				return errtrace.Wrap(fmt.Errorf("%v: %w", ref, err))
+			}
+		}
+		return nil
+	}
+
+	if format, ok := output.ArchiveFormatOf(opts.OutputDir); ok {
+		return errtrace.Wrap(writeArchive(opts.OutputDir, format, generateAll))
+	}
+	return errtrace.Wrap(generateAll(nil))
+}
+
+// generateRef checks out ref into a worktree at dir,
+// and generates documentation for it.
+func (cmd *mainCmd) generateRef(
+	ctx context.Context,
+	repo *gitrepo.Repo,
+	ref, dir string,
+	opts *params,
+	target siteTarget,
+) (err error) {
+	cmd.debugLog.Printf("[%v] Checking out into %v", ref, dir)
+	if err := repo.AddWorktree(ctx, ref, dir); err != nil {
+		return errtrace.Wrap(err)
+	}
+	defer func() {
+		// Clean up even if we were interrupted.
+		rmErr := repo.RemoveWorktree(context.WithoutCancel(ctx), dir)
+		err = errtrace.Wrap(errors.Join(err, rmErr))
+	}()
+
+	return errtrace.Wrap(cmd.generate(ctx, opts, target))
+}
+
+// refSubDir returns the name of the subdirectory
+// that documentation for a ref is generated into.
+func refSubDir(ref string) string {
+	return strings.ReplaceAll(ref, "/", "-")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"os"
+	"slices"
+	"time"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/sitegen"
+)
+
+// _skipOutsideHome marks packages that were left out
+// because they're not rooted under -home.
+const _skipOutsideHome gosrc.SkipReason = "outside home"
+
+// buildReport is the summary of a run written by -report.
+//
+// The JSON form of this is part of doc2go's public interface.
+// Add to it, but don't change or remove existing fields.
+type buildReport struct {
+	Pages    []reportPage    `json:"pages"`
+	Packages []reportPackage `json:"packages"`
+	Skipped  []reportSkipped `json:"skipped"`
+	Warnings []reportWarning `json:"warnings"`
+	Symbols  reportSymbols   `json:"symbols"`
+	Timings  reportTimings   `json:"timings"`
+}
+
+type reportPage struct {
+	ImportPath string `json:"importPath"`
+	Path       string `json:"path"` // relative to the output
+	Kind       string `json:"kind"` // package, command, or directory
+}
+
+type reportPackage struct {
+	ImportPath string `json:"importPath"`
+	Exported   int    `json:"exported"`
+	Documented int    `json:"documented"`
+
+	// Cached packages weren't regenerated with -incremental.
+	// Their symbol counts are the ones recorded in the build cache.
+	Cached bool `json:"cached,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+type reportSkipped struct {
+	ImportPath string `json:"importPath"`
+	Reason     string `json:"reason"`
+}
+
+type reportWarning struct {
+	ImportPath string `json:"importPath"`
+	Message    string `json:"message"`
+}
+
+type reportSymbols struct {
+	Exported   int `json:"exported"`
+	Documented int `json:"documented"`
+}
+
+// reportTimings holds the time spent in each stage in seconds.
+//
+// Packages are processed concurrently,
+// so these may add up to more than the duration of the run.
+type reportTimings struct {
+	Find     float64 `json:"find"`
+	Parse    float64 `json:"parse"`
+	Assemble float64 `json:"assemble"`
+	Render   float64 `json:"render"`
+	Pagefind float64 `json:"pagefind"`
+}
+
+func newBuildReport() *buildReport {
+	// Empty lists are reported as [], not null.
+	return &buildReport{
+		Pages:    []reportPage{},
+		Packages: []reportPackage{},
+		Skipped:  []reportSkipped{},
+		Warnings: []reportWarning{},
+	}
+}
+
+// skip records a package that won't be documented.
+func (r *buildReport) skip(pkg gosrc.SkippedPackage) {
+	r.Skipped = append(r.Skipped, reportSkipped{
+		ImportPath: pkg.ImportPath,
+		Reason:     string(pkg.Reason),
+	})
+}
+
+// warn records a problem with a package.
+func (r *buildReport) warn(w gosrc.Warning) {
+	r.Warnings = append(r.Warnings, reportWarning{
+		ImportPath: w.ImportPath,
+		Message:    w.Message,
+	})
+}
+
+// addResult records the outcome of generating documentation.
+func (r *buildReport) addResult(res *sitegen.Result) {
+	for _, page := range res.Pages {
+		r.Pages = append(r.Pages, reportPage{
+			ImportPath: page.ImportPath,
+			Path:       page.File,
+			Kind:       string(page.Kind),
+		})
+	}
+
+	for _, pkg := range res.Packages {
+		rpkg := reportPackage{
+			ImportPath: pkg.ImportPath,
+			Exported:   pkg.Stats.Exported,
+			Documented: pkg.Stats.Documented,
+			Cached:     pkg.Cached,
+		}
+		if pkg.Err != nil {
+			rpkg.Error = pkg.Err.Error()
+		}
+		r.Packages = append(r.Packages, rpkg)
+
+		r.Symbols.Exported += pkg.Stats.Exported
+		r.Symbols.Documented += pkg.Stats.Documented
+	}
+
+	r.Timings.Parse = res.Timings.Parse.Seconds()
+	r.Timings.Assemble = res.Timings.Assemble.Seconds()
+	r.Timings.Render = res.Timings.Render.Seconds()
+	r.Timings.Pagefind = res.Timings.Pagefind.Seconds()
+}
+
+// setFindTime records the time spent searching for packages.
+func (r *buildReport) setFindTime(d time.Duration) {
+	r.Timings.Find = d.Seconds()
+}
+
+// WriteFile writes the report to the given file as JSON.
+func (r *buildReport) WriteFile(path string) error {
+	slices.SortStableFunc(r.Skipped, func(a, b reportSkipped) int {
+		return cmp.Compare(a.ImportPath, b.ImportPath)
+	})
+	slices.SortStableFunc(r.Warnings, func(a, b reportWarning) int {
+		return cmp.Compare(a.ImportPath, b.ImportPath)
+	})
+
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errtrace.Wrap(err)
+	}
+	return errtrace.Wrap(os.WriteFile(path, append(bs, '\n'), 0o644))
+}
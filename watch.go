@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"braces.dev/errtrace"
+	"go.abhg.dev/doc2go/internal/gosrc"
+	"go.abhg.dev/doc2go/internal/watch"
+)
+
+// watch generates documentation,
+// and then regenerates it every time the inputs change
+// until the context is cancelled.
+//
+// wd is the working directory before -C was applied,
+// and args are the original command line arguments.
+// These are used to reload the configuration if it changes.
+//
+// Every run loads all packages again:
+// a change to one package can add, remove, or rename packages,
+// or change what links between them resolve to,
+// so the previous package list can't be trusted.
+// The build cache keeps this cheap:
+// only packages whose inputs changed are parsed and rendered again,
+// along with the directory listings.
+func (cmd *mainCmd) watch(ctx context.Context, wd string, args []string, opts *params) error {
+	var (
+		poller watch.Poller
+		paths  []string
+	)
+	for {
+		// The configuration file is resolved relative to
+		// the directory before -C.
+		configPath := opts.Config
+		if !filepath.IsAbs(configPath) {
+			configPath = filepath.Join(wd, configPath)
+		}
+		if !slices.Contains(paths, configPath) {
+			paths = append(paths, configPath)
+		}
+
+		// Changes made while we're generating
+		// must trigger another run.
+		// run adds paths that we didn't know about yet.
+		cmd.watchSnapshot = watch.NewSnapshot(paths)
+		if err := cmd.run(ctx, opts); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			// If we didn't get far enough to know what to watch,
+			// there's nothing we can do.
+			if len(cmd.watchPaths) == 0 {
+				return errtrace.Wrap(err)
+			}
+
+			cmd.logError(err)
+		}
+
+		// Never stop watching a path once we've started.
+		// If a package failed to load, its directory will be
+		// absent from watchPaths, but we want to see it get fixed.
+		for _, p := range cmd.watchPaths {
+			if !slices.Contains(paths, p) {
+				paths = append(paths, p)
+			}
+		}
+
+		// Don't regenerate because of files we wrote.
+		poller.Ignore = ignorePaths(wd, opts)
+
+		cmd.log.Printf("Watching %d paths for changes.", len(paths))
+		changed, err := poller.Wait(ctx, paths, cmd.watchSnapshot)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return errtrace.Wrap(err)
+		}
+		cmd.log.Printf("Changed: %v. Regenerating.", changed[0])
+		for _, c := range changed[1:] {
+			cmd.debugLog.Printf("Changed: %v", c)
+		}
+
+		if slices.Contains(changed, configPath) {
+			newOpts, err := cmd.reparse(wd, args)
+			if err != nil {
+				cmd.log.Printf("Unable to reload configuration: %+v", err)
+			} else {
+				opts = newOpts
+			}
+		}
+	}
+}
+
+// reparse parses the command line arguments again,
+// resolving relative paths from wd.
+func (cmd *mainCmd) reparse(wd string, args []string) (_ *params, err error) {
+	cur, err := os.Getwd()
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	if cur != wd {
+		if err := os.Chdir(wd); err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		defer func() {
+			err = errtrace.Wrap(errors.Join(err, os.Chdir(cur)))
+		}()
+	}
+
+	return errtrace.Wrap2((&cliParser{
+		Stdout: cmd.Stdout,
+		Stderr: cmd.Stderr,
+	}).Parse(args))
+}
+
+// watchPaths returns the paths that -watch should check for changes.
+func watchPaths(opts *params, refs []*gosrc.PackageRef) []string {
+	var paths []string
+	if opts.FrontMatter != "" {
+		paths = append(paths, opts.FrontMatter)
+	}
+	for _, dir := range []string{opts.TemplateDir, opts.StaticDir} {
+		if dir != "" {
+			paths = append(paths, dirTree(dir)...)
+		}
+	}
+	for _, files := range [][]filePath{opts.ExtraCSS, opts.ExtraJS} {
+		for _, f := range files {
+			paths = append(paths, string(f))
+		}
+	}
+
+	for _, ref := range refs {
+		// Watch the directory rather than the files
+		// so that we see new files.
+		for _, files := range [][]string{ref.Files, ref.TestFiles} {
+			for _, f := range files {
+				paths = append(paths, filepath.Dir(f))
+			}
+		}
+
+		// Dependency versions affect links.
+		if ref.Module != nil && ref.Module.GoMod != "" {
+			paths = append(paths, ref.Module.GoMod)
+		}
+	}
+
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// ignorePaths returns the files and directories written by doc2go
+// that -watch must not treat as changed inputs
+// even if they're inside watched directories.
+//
+// wd is the working directory before -C was applied.
+func ignorePaths(wd string, opts *params) []string {
+	var paths []string
+	for _, p := range []string{opts.OutputDir, opts.Report, opts.Man, opts.SinglePage} {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+
+	// The debug log is opened before -C is applied.
+	if debug := string(opts.Debug); debug != "" && debug != "-" {
+		if !filepath.IsAbs(debug) {
+			debug = filepath.Join(wd, debug)
+		}
+		paths = append(paths, debug)
+	}
+	return paths
+}
+
+// dirTree returns dir and all directories inside it.
+//
+// The Poller doesn't look inside subdirectories,
+// so each of them must be watched for changes to nested files.
+// New subdirectories are picked up on the next run.
+func dirTree(dir string) []string {
+	dirs := []string{dir}
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		// Unreadable directories are watched for becoming readable.
+		if err == nil && d.IsDir() && path != dir {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs
+}